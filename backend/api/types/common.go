@@ -1,6 +1,9 @@
 package types
 
-import "mime/multipart"
+import (
+	"mime/multipart"
+	"time"
+)
 
 // AuthCodeApplyParam
 type AuthCodeApplyParam struct {
@@ -34,20 +37,452 @@ type CreateImageParam struct {
 }
 
 type GetRouteDetailParam struct {
+	RouteId         uint `form:"routeId" binding:"required"`
+	IncludeRejected bool `form:"includeRejected"`
+	// Tolerance douglas-peucker tolerance in metres, 0 returns every point
+	Tolerance float64 `form:"tolerance" binding:"min=0,max=1000"`
+	// Format points (default) or polyline for a google encoded polyline string
+	Format string `form:"format" binding:"omitempty,oneof=points polyline"`
+}
+
+// UploadRoutePointsParam
+type UploadRoutePointsParam struct {
+	RouteId uint               `json:"routeId" binding:"required"`
+	Points  []UploadRoutePoint `json:"points" binding:"required,min=1,max=2000,dive"`
+}
+
+// UploadRoutePoint
+type UploadRoutePoint struct {
+	Seq         *uint64   `json:"seq" binding:"required"`
+	Longitude   float64   `json:"longitude" binding:"min=-180,max=180"`
+	Latitude    float64   `json:"latitude" binding:"min=-90,max=90"`
+	CreatedTime time.Time `json:"createdTime" binding:"required"`
+}
+
+// ExportRouteParam either a single route or a date range of the user's routes
+type ExportRouteParam struct {
+	RouteId uint      `form:"routeId"`
+	From    time.Time `form:"from" time_format:"2006-01-02"`
+	To      time.Time `form:"to" time_format:"2006-01-02"`
+	Format  string    `form:"format" binding:"required,oneof=gpx geojson kml"`
+}
+
+// DogStatsParam date range of walks, to is exclusive, defaults to the last 30 days
+type DogStatsParam struct {
+	From time.Time `form:"from" time_format:"2006-01-02"`
+	To   time.Time `form:"to" time_format:"2006-01-02"`
+}
+
+// ImportRouteParam
+type ImportRouteParam struct {
+	DogId uint                    `form:"dogId" binding:"required"`
+	Files []*multipart.FileHeader `form:"files" binding:"required,min=1,max=20"`
+}
+
+// ImportRouteResult per file outcome of an import
+type ImportRouteResult struct {
+	FileName   string `json:"fileName"`
+	RouteIds   []uint `json:"routeIds"`
+	PointCount int    `json:"pointCount"`
+	Error      string `json:"error,omitempty"`
+}
+
+// RouteEditParam
+type RouteEditParam struct {
+	RouteId uint `json:"routeId" binding:"required"`
+}
+
+// PrivacyUpdateParam
+type PrivacyUpdateParam struct {
+	Visibility string `json:"visibility" binding:"required,oneof=everyone friends nobody"`
+	FuzzMetres uint   `json:"fuzzMetres" binding:"max=5000"`
+}
+
+// HomeZoneCreateParam
+type HomeZoneCreateParam struct {
+	Name      string   `json:"name" binding:"required,max=64"`
+	Latitude  *float64 `json:"latitude" binding:"required,min=-90,max=90"`
+	Longitude *float64 `json:"longitude" binding:"required,min=-180,max=180"`
+	Radius    float64  `json:"radius" binding:"required,min=10,max=5000"`
+}
+
+// HomeZoneEditParam
+type HomeZoneEditParam struct {
+	ZoneId uint `json:"zoneId" binding:"required"`
+}
+
+// FriendEditParam
+type FriendEditParam struct {
+	FriendId uint `json:"friendId" binding:"required"`
+}
+
+// RouteShareCreateParam TTLMinutes defaults to 4 hours
+type RouteShareCreateParam struct {
+	RouteId    uint `json:"routeId" binding:"required"`
+	TTLMinutes uint `json:"ttlMinutes" binding:"omitempty,min=5,max=1440"`
+}
+
+// RouteShareEditParam
+type RouteShareEditParam struct {
+	ShareId uint `json:"shareId" binding:"required"`
+}
+
+// RouteShareListParam
+type RouteShareListParam struct {
 	RouteId uint `form:"routeId" binding:"required"`
 }
 
+// SharedRouteParam After only returns the points recorded later, for polling
+type SharedRouteParam struct {
+	Token string     `form:"token" binding:"required,max=64"`
+	After *time.Time `form:"after" time_format:"2006-01-02T15:04:05Z07:00"`
+}
+
+// GeofenceVertex
+type GeofenceVertex struct {
+	Latitude  float64 `json:"latitude" binding:"min=-90,max=90"`
+	Longitude float64 `json:"longitude" binding:"min=-180,max=180"`
+}
+
+// GeofenceParam circle uses latitude, longitude and radius, polygon its vertices
+// GeofenceId only on update, DogId nil applies to every dog
+type GeofenceParam struct {
+	GeofenceId uint             `json:"geofenceId"`
+	DogId      *uint            `json:"dogId"`
+	Name       string           `json:"name" binding:"required,max=64"`
+	Kind       string           `json:"kind" binding:"required,oneof=circle polygon"`
+	Latitude   float64          `json:"latitude" binding:"min=-90,max=90"`
+	Longitude  float64          `json:"longitude" binding:"min=-180,max=180"`
+	Radius     float64          `json:"radius" binding:"min=0,max=50000"`
+	Polygon    []GeofenceVertex `json:"polygon" binding:"max=100,dive"`
+}
+
+// GeofenceEditParam
+type GeofenceEditParam struct {
+	GeofenceId uint `json:"geofenceId" binding:"required"`
+}
+
+// GeofenceEventListParam
+type GeofenceEventListParam struct {
+	DogId      uint `form:"dogId"`
+	GeofenceId uint `form:"geofenceId"`
+	Offset     uint `form:"offset"`
+	Limit      uint `form:"limit" binding:"max=200"`
+}
+
+// LostReportParam the last seen location defaults to the dog's last tracked one
+type LostReportParam struct {
+	DogId        uint       `json:"dogId" binding:"required"`
+	Description  string     `json:"description" binding:"max=1000"`
+	PhotoUrl     string     `json:"photoUrl" binding:"omitempty,url,max=255"`
+	Latitude     *float64   `json:"latitude" binding:"omitempty,min=-90,max=90"`
+	Longitude    *float64   `json:"longitude" binding:"omitempty,min=-180,max=180"`
+	LastSeenTime *time.Time `json:"lastSeenTime"`
+}
+
+// LostAlertEditParam
+type LostAlertEditParam struct {
+	AlertId uint `json:"alertId" binding:"required"`
+}
+
+// NearbyLostAlertParam
+type NearbyLostAlertParam struct {
+	Lat    *float64 `form:"lat" binding:"required,min=-90,max=90"`
+	Lon    *float64 `form:"lon" binding:"required,min=-180,max=180"`
+	Radius float64  `form:"radius" binding:"min=0,max=50000"`
+}
+
+// LostSightingParam
+type LostSightingParam struct {
+	AlertId   uint       `json:"alertId" binding:"required"`
+	Latitude  *float64   `json:"latitude" binding:"required,min=-90,max=90"`
+	Longitude *float64   `json:"longitude" binding:"required,min=-180,max=180"`
+	Note      string     `json:"note" binding:"max=500"`
+	PhotoUrl  string     `json:"photoUrl" binding:"omitempty,url,max=255"`
+	SeenTime  *time.Time `json:"seenTime"`
+}
+
+// LostSightingListParam
+type LostSightingListParam struct {
+	AlertId uint `form:"alertId" binding:"required"`
+}
+
+// NotificationListParam
+type NotificationListParam struct {
+	UnreadOnly bool `form:"unreadOnly"`
+	Offset     uint `form:"offset"`
+	Limit      uint `form:"limit" binding:"max=200"`
+}
+
+// NotificationReadParam empty Ids marks everything read
+type NotificationReadParam struct {
+	Ids []uint `json:"ids" binding:"max=500"`
+}
+
+// NotificationPreferenceParam Category one of schema.NotificationCategories
+type NotificationPreferenceParam struct {
+	Category string `json:"category" binding:"required"`
+	InApp    bool   `json:"inApp"`
+	Email    bool   `json:"email"`
+	Push     bool   `json:"push"`
+}
+
+// PushDeviceParam
+type PushDeviceParam struct {
+	Token    string `json:"token" binding:"required,max=255"`
+	Platform string `json:"platform" binding:"omitempty,oneof=ios android web"`
+}
+
 // DogEditParam
 type DogEditParam struct {
 	DogId uint `json:"dogId" binding:"required"`
 }
 
+// DogUpdateParam nil fields are left unchanged, weight goes through the weight log and img through the gallery
+type DogUpdateParam struct {
+	DogId           uint      `json:"dogId" binding:"required"`
+	Name            *string   `json:"name" binding:"omitempty,min=1,max=64"`
+	Breed           *string   `json:"breed" binding:"omitempty,max=64"`
+	CustomizedBreed *string   `json:"customizedBreed" binding:"omitempty,max=64"`
+	Gender          *string   `json:"gender" binding:"omitempty,max=16"`
+	HealthCondition *string   `json:"healthCondition" binding:"omitempty,max=255"`
+	Age             *string   `json:"age" binding:"omitempty,max=16"`
+	Personality     *[]string `json:"personality" binding:"omitempty,max=20,dive,max=32"`
+}
+
+// DogChangeListParam
+type DogChangeListParam struct {
+	DogId uint `form:"dogId" binding:"required"`
+	Limit int  `form:"limit" binding:"min=0,max=200"`
+}
+
+// DogPhotoParam Url as returned by createImage
+type DogPhotoParam struct {
+	DogId     uint   `json:"dogId" binding:"required"`
+	Url       string `json:"url" binding:"required,max=255"`
+	IsPrimary bool   `json:"isPrimary"`
+}
+
+// DogPhotoEditParam
+type DogPhotoEditParam struct {
+	PhotoId uint `json:"id" binding:"required"`
+}
+
+// DogPhotoListParam
+type DogPhotoListParam struct {
+	DogId uint `form:"dogId" binding:"required"`
+}
+
+// DogPhotoOrderParam every photo of the dog in the new order
+type DogPhotoOrderParam struct {
+	DogId    uint   `json:"dogId" binding:"required"`
+	PhotoIds []uint `json:"photoIds" binding:"required,min=1"`
+}
+
+// DogGoalParam empty GoalType clears the goal, GoalReminder "HH:MM" or empty, GoalTimezone an IANA name
+type DogGoalParam struct {
+	DogId        uint    `json:"dogId" binding:"required"`
+	GoalType     string  `json:"goalType" binding:"omitempty,oneof=minutes km"`
+	GoalValue    float64 `json:"goalValue" binding:"min=0,max=1000"`
+	GoalReminder string  `json:"goalReminder" binding:"omitempty,len=5"`
+	GoalTimezone string  `json:"goalTimezone" binding:"max=64"`
+}
+
+// DogGoalProgressParam Days of history including today
+type DogGoalProgressParam struct {
+	DogId uint `form:"dogId" binding:"required"`
+	Days  int  `form:"days" binding:"min=0,max=90"`
+}
+
+// DogRecommendationParam weather is looked up at the given location, or the dog's last one
+type DogRecommendationParam struct {
+	DogId     uint     `form:"dogId" binding:"required"`
+	Latitude  *float64 `form:"latitude" binding:"omitempty,min=-90,max=90"`
+	Longitude *float64 `form:"longitude" binding:"omitempty,min=-180,max=180"`
+}
+
+// PlaymateParam candidates are dogs active within Radius metres of the given location, or the dog's last one
+type PlaymateParam struct {
+	DogId         uint     `form:"dogId" binding:"required"`
+	Latitude      *float64 `form:"latitude" binding:"omitempty,min=-90,max=90"`
+	Longitude     *float64 `form:"longitude" binding:"omitempty,min=-180,max=180"`
+	Radius        float64  `form:"radius" binding:"min=0,max=20000"`
+	WithinMinutes int      `form:"withinMinutes" binding:"min=0"`
+	Limit         int      `form:"limit" binding:"min=0,max=50"`
+}
+
+// OneCallWeather the part of the openweathermap one call response the backend reads, temperatures in kelvin
+type OneCallWeather struct {
+	Current struct {
+		Temp      float64 `json:"temp"`
+		Humidity  float64 `json:"humidity"`
+		WindSpeed float64 `json:"wind_speed"`
+		Uvi       float64 `json:"uvi"`
+		Weather   []struct {
+			Main string `json:"main"`
+		} `json:"weather"`
+	} `json:"current"`
+}
+
+// VaccinationParam VaccinationId only on update
+type VaccinationParam struct {
+	VaccinationId    uint       `json:"vaccinationId"`
+	DogId            uint       `json:"dogId" binding:"required"`
+	Name             string     `json:"name" binding:"required,max=128"`
+	AdministeredDate *time.Time `json:"administeredDate"`
+	DueDate          *time.Time `json:"dueDate"`
+	VetName          string     `json:"vetName" binding:"max=128"`
+	Notes            string     `json:"notes" binding:"max=2000"`
+}
+
+// MedicationParam MedicationId only on update, one dose every IntervalHours from StartTime
+type MedicationParam struct {
+	MedicationId  uint       `json:"medicationId"`
+	DogId         uint       `json:"dogId" binding:"required"`
+	Name          string     `json:"name" binding:"required,max=128"`
+	Dosage        string     `json:"dosage" binding:"max=128"`
+	IntervalHours int        `json:"intervalHours" binding:"required,min=1,max=8760"`
+	StartTime     *time.Time `json:"startTime" binding:"required"`
+	EndTime       *time.Time `json:"endTime"`
+	Notes         string     `json:"notes" binding:"max=2000"`
+}
+
+// VetVisitParam VetVisitId only on update, Attachments are urls from createImage
+type VetVisitParam struct {
+	VetVisitId   uint       `json:"vetVisitId"`
+	DogId        uint       `json:"dogId" binding:"required"`
+	VisitTime    *time.Time `json:"visitTime" binding:"required"`
+	Clinic       string     `json:"clinic" binding:"max=128"`
+	Reason       string     `json:"reason" binding:"max=255"`
+	Notes        string     `json:"notes" binding:"max=2000"`
+	Attachments  []string   `json:"attachments" binding:"max=20,dive,url,max=255"`
+	FollowUpDate *time.Time `json:"followUpDate"`
+}
+
+// HealthRecordEditParam
+type HealthRecordEditParam struct {
+	Id uint `json:"id" binding:"required"`
+}
+
+// HealthRecordListParam
+type HealthRecordListParam struct {
+	DogId uint `form:"dogId" binding:"required"`
+}
+
+// HealthDueParam Days ahead, overdue items are always included
+type HealthDueParam struct {
+	Days int `form:"days" binding:"min=0,max=365"`
+}
+
+// WeightLogParam weight in kg, MeasuredTime defaults to now
+type WeightLogParam struct {
+	DogId        uint       `json:"dogId" binding:"required"`
+	Weight       float64    `json:"weight" binding:"required,gt=0,max=150"`
+	MeasuredTime *time.Time `json:"measuredTime"`
+	Notes        string     `json:"notes" binding:"max=255"`
+}
+
+// WeightDeleteParam
+type WeightDeleteParam struct {
+	WeightId uint `json:"id" binding:"required"`
+}
+
+// WeightTrendParam Days back from today
+type WeightTrendParam struct {
+	DogId uint `form:"dogId" binding:"required"`
+	Days  int  `form:"days" binding:"min=0,max=730"`
+}
+
 // BinDeleteParam
 type BinDeleteParam struct {
 	BinId uint `json:"id" binding:"required"`
 }
 
+// NearbyBinParam radius in metres
+type NearbyBinParam struct {
+	Lat    *float64 `form:"lat" binding:"required,min=-90,max=90"`
+	Lon    *float64 `form:"lon" binding:"required,min=-180,max=180"`
+	Radius float64  `form:"radius" binding:"required,gt=0,max=50000"`
+	Limit  int      `form:"limit" binding:"min=0,max=500"`
+}
+
+// BoundsBinParam distances are measured from the box centre
+type BoundsBinParam struct {
+	MinLat *float64 `form:"minLat" binding:"required,min=-90,max=90"`
+	MinLon *float64 `form:"minLon" binding:"required,min=-180,max=180"`
+	MaxLat *float64 `form:"maxLat" binding:"required,min=-90,max=90"`
+	MaxLon *float64 `form:"maxLon" binding:"required,min=-180,max=180"`
+	Limit  int      `form:"limit" binding:"min=0,max=500"`
+}
+
+// NearbyDogParam centred on the caller, either a radius in metres or a bounding box
+// Offset is the page number as in schema.PaginationParam
+type NearbyDogParam struct {
+	Lat           *float64 `form:"lat" binding:"required,min=-90,max=90"`
+	Lon           *float64 `form:"lon" binding:"required,min=-180,max=180"`
+	Radius        float64  `form:"radius" binding:"min=0,max=50000"`
+	MinLat        *float64 `form:"minLat" binding:"omitempty,min=-90,max=90"`
+	MinLon        *float64 `form:"minLon" binding:"omitempty,min=-180,max=180"`
+	MaxLat        *float64 `form:"maxLat" binding:"omitempty,min=-90,max=90"`
+	MaxLon        *float64 `form:"maxLon" binding:"omitempty,min=-180,max=180"`
+	WithinMinutes int      `form:"withinMinutes" binding:"min=0"`
+	Offset        uint     `form:"offset"`
+	Limit         uint     `form:"limit" binding:"max=200"`
+}
+
+// StreamDogLocationParam area of the map the client is showing
+type StreamDogLocationParam struct {
+	MinLat *float64 `form:"minLat" binding:"required,min=-90,max=90"`
+	MinLon *float64 `form:"minLon" binding:"required,min=-180,max=180"`
+	MaxLat *float64 `form:"maxLat" binding:"required,min=-90,max=90"`
+	MaxLon *float64 `form:"maxLon" binding:"required,min=-180,max=180"`
+}
+
 type WeatherSearchParam struct {
 	Lon float64 `form:"longitude" binding:"required"`
 	Lat float64 `form:"latitude" binding:"required"`
 }
+
+// HouseholdCreateParam
+type HouseholdCreateParam struct {
+	Name string `json:"name" binding:"required,max=64"`
+}
+
+// HouseholdInviteParam TTLHours defaults to 3 days
+type HouseholdInviteParam struct {
+	HouseholdId uint   `json:"householdId" binding:"required"`
+	Role        string `json:"role" binding:"required,oneof=owner walker viewer"`
+	TTLHours    uint   `json:"ttlHours" binding:"omitempty,min=1,max=720"`
+}
+
+// HouseholdJoinParam
+type HouseholdJoinParam struct {
+	Token string `json:"token" binding:"required,max=64"`
+}
+
+// HouseholdMemberParam Role is only used when updating a member
+type HouseholdMemberParam struct {
+	HouseholdId uint   `json:"householdId" binding:"required"`
+	UserId      uint   `json:"userId" binding:"required"`
+	Role        string `json:"role" binding:"omitempty,oneof=owner walker viewer"`
+}
+
+// HouseholdDogParam HouseholdId is ignored when removing the dog
+type HouseholdDogParam struct {
+	HouseholdId uint `json:"householdId"`
+	DogId       uint `json:"dogId" binding:"required"`
+}
+
+// DogGrantCreateParam StartTime defaults to now
+type DogGrantCreateParam struct {
+	DogId     uint       `json:"dogId" binding:"required"`
+	GranteeId uint       `json:"granteeId" binding:"required"`
+	Role      string     `json:"role" binding:"required,oneof=walker viewer"`
+	StartTime *time.Time `json:"startTime"`
+	EndTime   time.Time  `json:"endTime" binding:"required"`
+}
+
+// DogGrantEditParam
+type DogGrantEditParam struct {
+	GrantId uint `json:"id" binding:"required"`
+}
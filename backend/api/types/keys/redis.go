@@ -1,10 +1,16 @@
 package keys
 
+import "fmt"
+
 const (
 	UserOperationKey = "user_operation:" //rule: key:userId
 
 	//Ban & Auth
 	UserBanInfoKey = "user_ban_info:"
+
+	NotificationQueueKey = "notification_queue"
+	// NotificationDeadKey jobs which failed every delivery attempt, kept for inspection
+	NotificationDeadKey = "notification_dead"
 )
 
 func GetLoginUserKey(username string) string {
@@ -21,3 +27,28 @@ func SetForgetPasswordCDKey(email string) string {
 func GetUserAccountCreateKey(username string) string {
 	return "account_create:" + username
 }
+
+// GetRouteSimplifiedKey point count is part of the key so new points invalidate the cache
+func GetRouteSimplifiedKey(routeId uint, tolerance float64, pointCount uint) string {
+	return fmt.Sprintf("route_simplified:%d:%g:%d", routeId, tolerance, pointCount)
+}
+
+// GetDogLocationChannel pub/sub channel of one grid cell of the map
+func GetDogLocationChannel(latCell int, lonCell int) string {
+	return fmt.Sprintf("dog_location:%d:%d", latCell, lonCell)
+}
+
+// GetJobLockKey one key per job and scheduled slot, whoever sets it runs the slot
+func GetJobLockKey(job string, slot int64) string {
+	return fmt.Sprintf("job_lock:%s:%d", job, slot)
+}
+
+// GetGoalReminderKey at most one walking goal reminder per dog and local day
+func GetGoalReminderKey(dogId uint, date string) string {
+	return fmt.Sprintf("goal_reminder:%d:%s", dogId, date)
+}
+
+// GetHealthReminderKey at most one reminder per health record and due time
+func GetHealthReminderKey(kind string, recordId uint, due int64) string {
+	return fmt.Sprintf("health_reminder:%s:%d:%d", kind, recordId, due)
+}
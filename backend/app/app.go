@@ -19,6 +19,7 @@ import (
 	"github.com/yiff028/comp90018-mobile-project/backend/app/controller"
 	"github.com/yiff028/comp90018-mobile-project/backend/app/mixin"
 	"github.com/yiff028/comp90018-mobile-project/backend/app/model"
+	"github.com/yiff028/comp90018-mobile-project/backend/app/model/redismodel"
 	"github.com/yiff028/comp90018-mobile-project/backend/app/router"
 	"github.com/yiff028/comp90018-mobile-project/backend/app/service"
 	"github.com/yiff028/comp90018-mobile-project/backend/pkg/driver"
@@ -84,19 +85,51 @@ func Init(ctx context.Context, opts ...Option) (func(), error) {
 	userModel := model.User{DB: (*dbInstance).Db}
 	tranModel := model.Transaction{DB: (*dbInstance).Db}
 	dogModel := model.Dog{DB: (*dbInstance).Db}
+	dogPhotoModel := model.DogPhoto{DB: (*dbInstance).Db}
+	dogChangeModel := model.DogChange{DB: (*dbInstance).Db}
 	routeModel := model.Route{DB: (*dbInstance).Db}
 	routePointModel := model.RoutePoint{DB: (*dbInstance).Db}
+	routeDogModel := model.RouteDog{DB: (*dbInstance).Db}
+	routePauseModel := model.RoutePause{DB: (*dbInstance).Db}
 	binModel := model.Bin{DB: (*dbInstance).Db}
+	privacyModel := model.Privacy{DB: (*dbInstance).Db}
+	friendModel := model.Friend{DB: (*dbInstance).Db}
+	routeShareModel := model.RouteShare{DB: (*dbInstance).Db}
+	geofenceModel := model.Geofence{DB: (*dbInstance).Db}
+	lostAlertModel := model.LostAlert{DB: (*dbInstance).Db}
+	notificationModel := model.Notification{DB: (*dbInstance).Db}
+	jobRunModel := model.JobRun{DB: (*dbInstance).Db}
+	healthModel := model.Health{DB: (*dbInstance).Db}
+	weightModel := model.Weight{DB: (*dbInstance).Db}
+	householdModel := model.Household{DB: (*dbInstance).Db}
+	dogGrantModel := model.DogGrant{DB: (*dbInstance).Db}
 
 	dep := mixin.StoreDepency{
-		RedisClient:     redis,
-		DBClient:        *dbInstance,
-		UserModel:       userModel,
-		TranModel:       tranModel,
-		RouteModel:      routeModel,
-		RoutePointModel: routePointModel,
-		DogModel:        dogModel,
-		BinModel:        binModel,
+		RedisClient:       redis,
+		DBClient:          *dbInstance,
+		UserModel:         userModel,
+		TranModel:         tranModel,
+		RouteModel:        routeModel,
+		RoutePointModel:   routePointModel,
+		RouteDogModel:     routeDogModel,
+		RoutePauseModel:   routePauseModel,
+		DogModel:          dogModel,
+		DogPhotoModel:     dogPhotoModel,
+		DogChangeModel:    dogChangeModel,
+		BinModel:          binModel,
+		PrivacyModel:      privacyModel,
+		FriendModel:       friendModel,
+		RouteShareModel:   routeShareModel,
+		GeofenceModel:     geofenceModel,
+		LostAlertModel:    lostAlertModel,
+		NotificationModel: notificationModel,
+		JobRunModel:       jobRunModel,
+		HealthModel:       healthModel,
+		WeightModel:       weightModel,
+		HouseholdModel:    householdModel,
+		DogGrantModel:     dogGrantModel,
+		LocationChannel:   redismodel.LocationChannel{RedisInstance: redis},
+		NotificationQueue: redismodel.NotificationQueue{RedisInstance: redis},
 	}
 	//init web service
 	ws := service.InitWInstance(ctx, "")
@@ -122,6 +155,38 @@ func Init(ctx context.Context, opts ...Option) (func(), error) {
 			Dep: dep,
 			WS:  ws,
 		},
+		PrivacyController: &controller.PrivacyController{
+			Dep: dep,
+			WS:  ws,
+		},
+		ShareController: &controller.ShareController{
+			Dep: dep,
+			WS:  ws,
+		},
+		GeofenceController: &controller.GeofenceController{
+			Dep: dep,
+			WS:  ws,
+		},
+		HealthController: &controller.HealthController{
+			Dep: dep,
+			WS:  ws,
+		},
+		LostController: &controller.LostController{
+			Dep: dep,
+			WS:  ws,
+		},
+		NotificationController: &controller.NotificationController{
+			Dep: dep,
+			WS:  ws,
+		},
+		HouseholdController: &controller.HouseholdController{
+			Dep: dep,
+			WS:  ws,
+		},
+		GrantController: &controller.GrantController{
+			Dep: dep,
+			WS:  ws,
+		},
 
 		//todo
 	}
@@ -129,9 +194,15 @@ func Init(ctx context.Context, opts ...Option) (func(), error) {
 	engine := InitGinEngine(mrouter, dep, multipleWriter)
 	//
 	httpServerCleanFunc := InitHTTPServer(ctx, engine)
+	//
+	schedulerCleanFunc := InitScheduler(ctx, dep)
+	//
+	notificationCleanFunc := InitNotificationWorker(ctx, dep)
 
 	return func() {
 		httpServerCleanFunc()
+		schedulerCleanFunc()
+		notificationCleanFunc()
 		dbCleanFunc()
 		loggerCleanFunc()
 	}, nil
@@ -23,6 +23,11 @@ type Config struct {
 	CORS     CORS     `yaml:"CORS"`
 	Services Services `yaml:"Services"`
 	UserInfo UserInfo `yaml:"UserInfo"`
+	Route    Route    `yaml:"Route"`
+	Dog      Dog      `yaml:"Dog"`
+
+	Notification Notification `yaml:"Notification"`
+	Scheduler    Scheduler    `yaml:"Scheduler"`
 }
 
 type EmailTemplate struct {
@@ -30,6 +35,31 @@ type EmailTemplate struct {
 	Order        string
 	Subscribe    string
 	Conversation string
+	// Notification text/template of notification emails, fields Title and Body
+	Notification string `yaml:"Notification"`
+}
+
+// Notification Workers queue consumers per instance
+type Notification struct {
+	Workers       int           `yaml:"Workers"`
+	SMTP          SMTP          `yaml:"SMTP"`
+	Push          Push          `yaml:"Push"`
+	EmailTemplate EmailTemplate `yaml:"EmailTemplate"`
+}
+
+// SMTP empty Host disables email
+type SMTP struct {
+	Host     string `yaml:"Host"`
+	Port     int    `yaml:"Port"`
+	Username string `yaml:"Username"`
+	Password string `yaml:"Password"`
+	From     string `yaml:"From"`
+}
+
+// Push empty Endpoint logs the messages instead of sending them
+type Push struct {
+	Endpoint  string `yaml:"Endpoint"`
+	ServerKey string `yaml:"ServerKey"`
 }
 
 // Timer use minutes
@@ -101,6 +131,26 @@ type UserInfo struct {
 	ExpireTime           int     `yaml:"ExpireTime"`
 }
 
+// Route StaleTimeout in minutes, SweepInterval in seconds
+type Route struct {
+	StaleTimeout  int `yaml:"StaleTimeout"`
+	SweepInterval int `yaml:"SweepInterval"`
+}
+
+// Scheduler HistoryDays of job runs to keep, ImageGracePeriod in hours before an unused upload is removed
+type Scheduler struct {
+	HistoryDays      int `yaml:"HistoryDays"`
+	ImageGracePeriod int `yaml:"ImageGracePeriod"`
+}
+
+// Dog ActiveWindow and MaxActiveWindow in minutes, how recent a location must be to show on the map
+// GoalTimezone counts the days of walking goals for dogs without their own timezone
+type Dog struct {
+	ActiveWindow    int    `yaml:"ActiveWindow"`
+	MaxActiveWindow int    `yaml:"MaxActiveWindow"`
+	GoalTimezone    string `yaml:"GoalTimezone"`
+}
+
 // Services
 type Services struct {
 	MainService    string `yaml:"MainService"`
@@ -109,6 +159,7 @@ type Services struct {
 	RedirectPage   string `yaml:"RedirectPage"`
 	S3BucketDomain string `yaml:"S3BucketDomain"`
 	CronicleApiKey string `yaml:"CronicleApiKey"`
+	WeatherApiKey  string `yaml:"WeatherApiKey"`
 }
 
 // HTTP
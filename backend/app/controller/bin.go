@@ -8,6 +8,7 @@ import (
 	"github.com/yiff028/comp90018-mobile-project/backend/app/service"
 	"github.com/yiff028/comp90018-mobile-project/backend/pkg/errors"
 	"github.com/yiff028/comp90018-mobile-project/backend/pkg/logger"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/util"
 )
 
 // BinController
@@ -36,6 +37,7 @@ func (r *BinController) CreateBin(c *gin.Context) {
 	}
 
 	param.UserId = userId
+	param.Location = schema.MysqlPoint{Longitude: param.Longitude, Latitude: param.Latitude}
 
 	err := r.Dep.BinModel.Create(ctx, &param)
 	if err != nil {
@@ -83,7 +85,7 @@ func (r *BinController) DeleteBin(c *gin.Context) {
 	})
 }
 
-//ListMyBin
+// ListMyBin
 func (r *BinController) ListMyBin(c *gin.Context) {
 	ctx := c.Request.Context()
 
@@ -134,3 +136,65 @@ func (r *BinController) ListAllBin(c *gin.Context) {
 		"data":    binList,
 	})
 }
+
+// ListNearbyBin
+func (r *BinController) ListNearbyBin(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var param types.NearbyBinParam
+	if err := c.ShouldBindQuery(&param); err != nil {
+		logger.Errorf(ctx, "parse query params failed %+v", err)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+
+	binList, err := r.Dep.BinModel.ListNearby(ctx, *param.Lat, *param.Lon, param.Radius, param.Limit)
+	if err != nil {
+		logger.Errorf(ctx, "list nearby bin fail %+v", err)
+		mixin.ResError(c, errors.ListNearbyBinFail)
+		return
+	}
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+		"data":    binList,
+	})
+}
+
+// ListBinWithinBounds
+func (r *BinController) ListBinWithinBounds(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var param types.BoundsBinParam
+	if err := c.ShouldBindQuery(&param); err != nil {
+		logger.Errorf(ctx, "parse query params failed %+v", err)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+
+	box := util.BoundingBox{
+		MinLongitude: *param.MinLon,
+		MinLatitude:  *param.MinLat,
+		MaxLongitude: *param.MaxLon,
+		MaxLatitude:  *param.MaxLat,
+	}
+	if !box.Valid() {
+		mixin.ResError(c, errors.BoundsIllegal)
+		return
+	}
+
+	center := box.Center()
+	binList, err := r.Dep.BinModel.ListWithinBounds(ctx, box, center.Latitude, center.Longitude, param.Limit)
+	if err != nil {
+		logger.Errorf(ctx, "list bin within bounds fail %+v", err)
+		mixin.ResError(c, errors.ListNearbyBinFail)
+		return
+	}
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+		"data":    binList,
+	})
+}
@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"context"
 	"fmt"
 	"image"
 	"image/jpeg"
@@ -19,6 +20,12 @@ import (
 	"github.com/yiff028/comp90018-mobile-project/backend/pkg/util"
 )
 
+// uploads are saved as ImageDir/<id>.jpg and served as ImageUrlPrefix<id>.jpg
+const (
+	ImageDir       = "/data/img/"
+	ImageUrlPrefix = "https://pawtrack.xyz/image/"
+)
+
 // CommonController
 type CommonController struct {
 	Dep mixin.StoreDepency
@@ -73,8 +80,8 @@ func (common *CommonController) CreateImage(c *gin.Context) {
 		}
 
 		fileId := uuid.New().String()
-		filePath := "/data/img/" + fileId + ".jpg"
-		returnUrl := "https://pawtrack.xyz/image/" + fileId + ".jpg"
+		filePath := ImageDir + fileId + ".jpg"
+		returnUrl := ImageUrlPrefix + fileId + ".jpg"
 
 		// create file
 		out, err := os.Create(filePath)
@@ -123,44 +130,17 @@ func (common *CommonController) GetWeather(c *gin.Context) {
 		return
 	}
 
-	//redis-cache
-	cacheKey := fmt.Sprintf("weather:%d", userId)
-	var locationInfo interface{}
-
-	cachedInfo, err := common.Dep.RedisClient.Get(cacheKey)
+	weatherBytes, err := loadWeather(ctx, common.Dep, common.WS, param.Lon, param.Lat)
 	if err != nil {
+		logger.Errorf(ctx, "load weather fail %+v", err)
 		mixin.ResError(c, errors.GetWeatherUsingApiFail)
 		return
 	}
-	if cachedInfo == "" {
-		locationInfo, err = common.WS.GetByLocation(param.Lon, param.Lat)
-		if err != nil {
-			logger.Errorf(ctx, "get weather by location using third api fail %+v", err)
-			mixin.ResError(c, errors.GetWeatherUsingApiFail)
-			return
-		}
-
-		marshalStr, err := jsoniter.Marshal(locationInfo)
-		if err != nil {
-			logger.Errorf(ctx, "json marshal fail %+v", err)
-			mixin.ResError(c, errors.GetWeatherUsingApiFail)
-			return
-		}
-		_, err = common.Dep.RedisClient.Set(cacheKey, marshalStr, 30*time.Minute)
-		if err != nil {
-			logger.Errorf(ctx, "set to redis fail %+v", err)
-			mixin.ResError(c, errors.GetWeatherUsingApiFail)
-			return
-		}
-	} else {
-		var res interface{}
-		marshalErr := util.JSONUnmarshal([]byte(cachedInfo), &res)
-		if marshalErr != nil {
-			logger.Errorf(ctx, "json unmarshal fail %+v", err)
-			mixin.ResError(c, errors.GetWeatherUsingApiFail)
-			return
-		}
-		locationInfo = res
+	var locationInfo interface{}
+	if err := util.JSONUnmarshal(weatherBytes, &locationInfo); err != nil {
+		logger.Errorf(ctx, "json unmarshal fail %+v", err)
+		mixin.ResError(c, errors.GetWeatherUsingApiFail)
+		return
 	}
 
 	mixin.ResSuccess(c, gin.H{
@@ -169,3 +149,29 @@ func (common *CommonController) GetWeather(c *gin.Context) {
 		"data":    locationInfo,
 	})
 }
+
+// loadWeather raw one call response, cached for 30 minutes per location rounded to about 1 km
+func loadWeather(ctx context.Context, dep mixin.StoreDepency, ws service.WebService, lon float64, lat float64) ([]byte, error) {
+	cacheKey := fmt.Sprintf("weather:%.2f:%.2f", lat, lon)
+	cachedInfo, err := dep.RedisClient.Get(cacheKey)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if cachedInfo != "" {
+		return []byte(cachedInfo), nil
+	}
+
+	locationInfo, err := ws.GetByLocation(lon, lat)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	marshalStr, err := jsoniter.Marshal(locationInfo)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	_, err = dep.RedisClient.Set(cacheKey, marshalStr, 30*time.Minute)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return marshalStr, nil
+}
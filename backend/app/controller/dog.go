@@ -1,14 +1,25 @@
 package controller
 
 import (
+	"context"
+	"time"
+
 	"github.com/gin-gonic/gin"
 
 	"github.com/yiff028/comp90018-mobile-project/backend/api/types"
+	configs "github.com/yiff028/comp90018-mobile-project/backend/app/config"
 	"github.com/yiff028/comp90018-mobile-project/backend/app/mixin"
 	"github.com/yiff028/comp90018-mobile-project/backend/app/model/schema"
 	"github.com/yiff028/comp90018-mobile-project/backend/app/service"
 	"github.com/yiff028/comp90018-mobile-project/backend/pkg/errors"
 	"github.com/yiff028/comp90018-mobile-project/backend/pkg/logger"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/util"
+)
+
+const (
+	defaultDogActiveWindow    = 300
+	defaultDogMaxActiveWindow = 1440
+	defaultNearbyDogLimit     = 50
 )
 
 // DogController
@@ -37,7 +48,32 @@ func (d *DogController) CreateDog(c *gin.Context) {
 	}
 
 	param.UserId = userId
-	err := d.Dep.DogModel.Create(ctx, &param)
+	// only set through a lost alert
+	param.IsLost = false
+	param.LostSince = nil
+	// only set through setGoal, which validates them
+	param.GoalType = ""
+	param.GoalValue = 0
+	param.GoalReminder = ""
+	param.GoalTimezone = ""
+	// only set through a household
+	param.HouseholdId = 0
+	param.Role = ""
+	// a new dog's image starts its gallery
+	err := d.Dep.TranModel.ExecTrans(ctx, d.Dep.DBClient.Db, func(ctx context.Context) error {
+		if err := d.Dep.DogModel.Create(ctx, &param); err != nil {
+			return err
+		}
+		if param.Img == "" {
+			return nil
+		}
+		return d.Dep.DogPhotoModel.Create(ctx, &schema.DogPhoto{
+			DogId:     param.ID,
+			UserId:    userId,
+			Url:       param.Img,
+			IsPrimary: true,
+		})
+	})
 	if err != nil {
 		logger.Errorf(ctx, "create dog fail %+v", err)
 		mixin.ResError(c, errors.CreateDogFail)
@@ -69,6 +105,26 @@ func (d *DogController) ListDog(c *gin.Context) {
 		mixin.ResError(c, errors.ListDogFail)
 		return
 	}
+	roleMap, err := d.Dep.HouseholdModel.ListRoleByUserId(ctx, userId)
+	if err != nil {
+		logger.Errorf(ctx, "list household role fail %+v", err)
+		mixin.ResError(c, errors.ListDogFail)
+		return
+	}
+	grantList, err := d.Dep.DogGrantModel.ListActive(ctx, userId, nil, time.Now())
+	if err != nil {
+		logger.Errorf(ctx, "list dog grant fail %+v", err)
+		mixin.ResError(c, errors.ListDogFail)
+		return
+	}
+	grantRole := map[uint]string{}
+	for _, grant := range grantList {
+		grantRole[grant.DogId] = schema.MaxRole(grantRole[grant.DogId], grant.Role)
+	}
+	for i := range dogList {
+		role := dogList[i].RoleOf(userId, roleMap[dogList[i].HouseholdId])
+		dogList[i].Role = schema.MaxRole(role, grantRole[dogList[i].ID])
+	}
 
 	mixin.ResSuccess(c, gin.H{
 		"code":    0,
@@ -81,13 +137,44 @@ func (d *DogController) ListDog(c *gin.Context) {
 func (d *DogController) ListCurrentDog(c *gin.Context) {
 	ctx := c.Request.Context()
 
-	dogList, err := d.Dep.DogModel.ListCurrentDog(ctx)
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	currentList, err := d.Dep.DogModel.ListCurrentDog(ctx, userId, dogActiveWindow(0))
 	if err != nil {
 		logger.Errorf(ctx, "list dog fail %+v", err)
 		mixin.ResError(c, errors.ListDogFail)
 		return
 	}
 
+	dogs := make([]*schema.Dog, 0, len(currentList))
+	for i := range currentList {
+		dogs = append(dogs, &currentList[i])
+	}
+	policyMap, err := listLocationPolicy(ctx, d.Dep, userId, dogs)
+	if err != nil {
+		logger.Errorf(ctx, "list location policy fail %+v", err)
+		mixin.ResError(c, errors.ListLocationPolicyFail)
+		return
+	}
+	roleMap, err := listDogRoles(ctx, d.Dep, userId, dogs)
+	if err != nil {
+		logger.Errorf(ctx, "list dog role fail %+v", err)
+		mixin.ResError(c, errors.ListDogFail)
+		return
+	}
+	dogList := make(schema.DogList, 0, len(currentList))
+	for _, dogItem := range dogs {
+		if maskDogLocation(policyMap, roleMap, userId, dogItem) {
+			dogList = append(dogList, *dogItem)
+		}
+	}
+
 	mixin.ResSuccess(c, gin.H{
 		"code":    0,
 		"message": "ok",
@@ -95,6 +182,106 @@ func (d *DogController) ListCurrentDog(c *gin.Context) {
 	})
 }
 
+// ListNearbyDog dogs active around the caller, nearest first
+func (d *DogController) ListNearbyDog(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var param types.NearbyDogParam
+	if err := c.ShouldBindQuery(&param); err != nil {
+		logger.Errorf(ctx, "parse query params failed %+v", err)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	var box util.BoundingBox
+	switch {
+	case param.MinLat != nil && param.MinLon != nil && param.MaxLat != nil && param.MaxLon != nil:
+		box = util.BoundingBox{
+			MinLongitude: *param.MinLon,
+			MinLatitude:  *param.MinLat,
+			MaxLongitude: *param.MaxLon,
+			MaxLatitude:  *param.MaxLat,
+		}
+	case param.Radius > 0:
+		box = util.NewBoundingBox(*param.Lat, *param.Lon, param.Radius)
+	default:
+		logger.Errorf(ctx, "nearby dog needs radius or bounds %+v", param)
+		mixin.ResError(c, errors.BoundsIllegal)
+		return
+	}
+	if !box.Valid() {
+		logger.Errorf(ctx, "illegal nearby dog area %+v", param)
+		mixin.ResError(c, errors.BoundsIllegal)
+		return
+	}
+
+	if param.Limit == 0 {
+		param.Limit = defaultNearbyDogLimit
+	}
+	since := time.Now().Add(-dogActiveWindow(param.WithinMinutes))
+
+	candidates, err := d.Dep.DogModel.ListNearby(ctx, userId, box, *param.Lat, *param.Lon, param.Radius, since)
+	if err != nil {
+		logger.Errorf(ctx, "list nearby dog fail %+v", err)
+		mixin.ResError(c, errors.ListNearbyDogFail)
+		return
+	}
+	// filtered, ordered and paged on the masked locations so the true ones never leak
+	nearbyList, err := maskNearbyDogs(ctx, d.Dep, userId, candidates, box, *param.Lat, *param.Lon, param.Radius)
+	if err != nil {
+		logger.Errorf(ctx, "list location policy fail %+v", err)
+		mixin.ResError(c, errors.ListLocationPolicyFail)
+		return
+	}
+
+	total := len(nearbyList)
+	start := 0
+	if param.Offset > 0 {
+		start = int((param.Offset - 1) * param.Limit)
+	}
+	dogList := schema.DogWithDistanceList{}
+	if start < total {
+		end := start + int(param.Limit)
+		if end > total {
+			end = total
+		}
+		dogList = nearbyList[start:end]
+	}
+
+	mixin.ResPage(c, dogList, &mixin.PaginationResult{
+		Total:  total,
+		Offset: param.Offset,
+		Limit:  param.Limit,
+	})
+}
+
+// dogActiveWindow how recent a dog location must be, 0 for the configured default
+func dogActiveWindow(minutes int) time.Duration {
+	cfg := configs.C.Dog
+	maxWindow := cfg.MaxActiveWindow
+	if maxWindow <= 0 {
+		maxWindow = defaultDogMaxActiveWindow
+	}
+	if minutes <= 0 {
+		minutes = cfg.ActiveWindow
+	}
+	if minutes <= 0 {
+		minutes = defaultDogActiveWindow
+	}
+	if minutes > maxWindow {
+		minutes = maxWindow
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
 // DeleteDog
 func (d *DogController) DeleteDog(c *gin.Context) {
 	ctx := c.Request.Context()
@@ -126,3 +313,143 @@ func (d *DogController) DeleteDog(c *gin.Context) {
 		"message": "ok",
 	})
 }
+
+// UpdateDog partial update of the profile, changes of the key fields are kept in the history
+func (d *DogController) UpdateDog(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var param types.DogUpdateParam
+	if err := c.ShouldBindJSON(&param); err != nil {
+		logger.Errorf(ctx, "parse query params failed %+v", err)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	dogItem, err := d.Dep.DogModel.GetById(ctx, param.DogId)
+	if err != nil {
+		logger.Errorf(ctx, "get dog fail %+v", err)
+		mixin.ResError(c, errors.UpdateDogFail)
+		return
+	}
+	role, err := dogRole(ctx, d.Dep, dogItem, userId)
+	if err != nil {
+		logger.Errorf(ctx, "get dog role fail %+v", err)
+		mixin.ResError(c, errors.UpdateDogFail)
+		return
+	}
+	if !schema.RoleAtLeast(role, schema.HouseholdRoleOwner) {
+		mixin.ResError(c, errors.DogNotExist)
+		return
+	}
+
+	updated := *dogItem
+	updateMap := map[string]interface{}{}
+	if param.Name != nil {
+		updated.Name = *param.Name
+		updateMap["name"] = updated.Name
+	}
+	if param.Breed != nil {
+		updated.Breed = *param.Breed
+		updateMap["breed"] = updated.Breed
+	}
+	if param.CustomizedBreed != nil {
+		updated.CustomizedBreed = *param.CustomizedBreed
+		updateMap["customized_breed"] = updated.CustomizedBreed
+	}
+	if param.Gender != nil {
+		updated.Gender = *param.Gender
+		updateMap["gender"] = updated.Gender
+	}
+	if param.HealthCondition != nil {
+		updated.HealthCondition = *param.HealthCondition
+		updateMap["health_condition"] = updated.HealthCondition
+	}
+	if param.Age != nil {
+		updated.Age = *param.Age
+		updateMap["age"] = updated.Age
+	}
+	if param.Personality != nil {
+		updated.Personality = schema.MysqlJSONArray(*param.Personality)
+		updateMap["personality"] = updated.Personality
+	}
+
+	changes := schema.DiffDog(dogItem, &updated, userId)
+	if len(changes) > 0 {
+		err = d.Dep.TranModel.ExecTrans(ctx, d.Dep.DBClient.Db, func(ctx context.Context) error {
+			if err := d.Dep.DogModel.Update(ctx, dogItem.ID, updateMap); err != nil {
+				return err
+			}
+			return d.Dep.DogChangeModel.CreateBatch(ctx, changes)
+		})
+		if err != nil {
+			logger.Errorf(ctx, "update dog fail %+v", err)
+			mixin.ResError(c, errors.UpdateDogFail)
+			return
+		}
+	}
+	updated.Role = role
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+		"data":    updated,
+	})
+}
+
+// ListDogChange history of the key profile fields, newest first
+func (d *DogController) ListDogChange(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var param types.DogChangeListParam
+	if err := c.ShouldBindQuery(&param); err != nil {
+		logger.Errorf(ctx, "parse query params failed %+v", err)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	dogItem, err := d.Dep.DogModel.GetById(ctx, param.DogId)
+	if err != nil {
+		logger.Errorf(ctx, "get dog fail %+v", err)
+		mixin.ResError(c, errors.ListDogChangeFail)
+		return
+	}
+	role, err := dogRole(ctx, d.Dep, dogItem, userId)
+	if err != nil {
+		logger.Errorf(ctx, "get dog role fail %+v", err)
+		mixin.ResError(c, errors.ListDogChangeFail)
+		return
+	}
+	if !schema.RoleAtLeast(role, schema.HouseholdRoleViewer) {
+		mixin.ResError(c, errors.DogNotExist)
+		return
+	}
+
+	changeList, err := d.Dep.DogChangeModel.ListByDogId(ctx, dogItem.ID, param.Limit)
+	if err != nil {
+		logger.Errorf(ctx, "list dog change fail %+v", err)
+		mixin.ResError(c, errors.ListDogChangeFail)
+		return
+	}
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+		"data":    changeList,
+	})
+}
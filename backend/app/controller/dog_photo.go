@@ -0,0 +1,365 @@
+package controller
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yiff028/comp90018-mobile-project/backend/api/types"
+	"github.com/yiff028/comp90018-mobile-project/backend/app/mixin"
+	"github.com/yiff028/comp90018-mobile-project/backend/app/model/schema"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/errors"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/logger"
+)
+
+// maxDogPhotos size of a gallery
+const maxDogPhotos = 12
+
+// AddDogPhoto append an uploaded image to the gallery, the first photo becomes the primary one
+func (d *DogController) AddDogPhoto(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var param types.DogPhotoParam
+	if err := c.ShouldBindJSON(&param); err != nil {
+		logger.Errorf(ctx, "parse query params failed %+v", err)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	// only images uploaded through createImage, so unused ones can be cleaned up
+	if !strings.HasPrefix(param.Url, ImageUrlPrefix) {
+		mixin.ResError(c, errors.DogPhotoIllegal)
+		return
+	}
+	if !d.checkPhotoOwner(c, param.DogId, userId) {
+		return
+	}
+
+	photoList, err := d.Dep.DogPhotoModel.ListByDogId(ctx, param.DogId)
+	if err != nil {
+		logger.Errorf(ctx, "list dog photo fail %+v", err)
+		mixin.ResError(c, errors.UpdateDogPhotoFail)
+		return
+	}
+	if len(photoList) >= maxDogPhotos {
+		mixin.ResError(c, errors.DogPhotoIllegal)
+		return
+	}
+
+	photo := schema.DogPhoto{
+		DogId:     param.DogId,
+		UserId:    userId,
+		Url:       param.Url,
+		Position:  len(photoList),
+		IsPrimary: param.IsPrimary || len(photoList) == 0,
+	}
+	if len(photoList) > 0 {
+		photo.Position = photoList[len(photoList)-1].Position + 1
+	}
+	err = d.Dep.TranModel.ExecTrans(ctx, d.Dep.DBClient.Db, func(ctx context.Context) error {
+		if err := d.Dep.DogPhotoModel.Create(ctx, &photo); err != nil {
+			return err
+		}
+		if !photo.IsPrimary {
+			return nil
+		}
+		if err := d.Dep.DogPhotoModel.SetPrimary(ctx, photo.DogId, photo.ID); err != nil {
+			return err
+		}
+		return syncDogImg(ctx, d.Dep, photo.DogId)
+	})
+	if err != nil {
+		logger.Errorf(ctx, "add dog photo fail %+v", err)
+		mixin.ResError(c, errors.UpdateDogPhotoFail)
+		return
+	}
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+		"data":    photo,
+	})
+}
+
+// DeleteDogPhoto the next photo takes over when the primary one is deleted
+func (d *DogController) DeleteDogPhoto(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var param types.DogPhotoEditParam
+	if err := c.ShouldBindJSON(&param); err != nil {
+		logger.Errorf(ctx, "parse query params failed %+v", err)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	photo, ok := d.getOwnedPhoto(c, param.PhotoId, userId)
+	if !ok {
+		return
+	}
+
+	err := d.Dep.TranModel.ExecTrans(ctx, d.Dep.DBClient.Db, func(ctx context.Context) error {
+		if _, err := d.Dep.DogPhotoModel.Delete(ctx, photo.ID); err != nil {
+			return err
+		}
+		return syncDogImg(ctx, d.Dep, photo.DogId)
+	})
+	if err != nil {
+		logger.Errorf(ctx, "delete dog photo fail %+v", err)
+		mixin.ResError(c, errors.UpdateDogPhotoFail)
+		return
+	}
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+	})
+}
+
+// SetPrimaryDogPhoto
+func (d *DogController) SetPrimaryDogPhoto(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var param types.DogPhotoEditParam
+	if err := c.ShouldBindJSON(&param); err != nil {
+		logger.Errorf(ctx, "parse query params failed %+v", err)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	photo, ok := d.getOwnedPhoto(c, param.PhotoId, userId)
+	if !ok {
+		return
+	}
+
+	err := d.Dep.TranModel.ExecTrans(ctx, d.Dep.DBClient.Db, func(ctx context.Context) error {
+		if err := d.Dep.DogPhotoModel.SetPrimary(ctx, photo.DogId, photo.ID); err != nil {
+			return err
+		}
+		return syncDogImg(ctx, d.Dep, photo.DogId)
+	})
+	if err != nil {
+		logger.Errorf(ctx, "set primary dog photo fail %+v", err)
+		mixin.ResError(c, errors.UpdateDogPhotoFail)
+		return
+	}
+	photo.IsPrimary = true
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+		"data":    photo,
+	})
+}
+
+// ReorderDogPhoto the ids must be exactly the photos of the dog
+func (d *DogController) ReorderDogPhoto(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var param types.DogPhotoOrderParam
+	if err := c.ShouldBindJSON(&param); err != nil {
+		logger.Errorf(ctx, "parse query params failed %+v", err)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	if !d.checkPhotoOwner(c, param.DogId, userId) {
+		return
+	}
+	photoList, err := d.Dep.DogPhotoModel.ListByDogId(ctx, param.DogId)
+	if err != nil {
+		logger.Errorf(ctx, "list dog photo fail %+v", err)
+		mixin.ResError(c, errors.UpdateDogPhotoFail)
+		return
+	}
+	photoMap := make(map[uint]*schema.DogPhoto, len(photoList))
+	for i := range photoList {
+		photoMap[photoList[i].ID] = &photoList[i]
+	}
+	if len(param.PhotoIds) != len(photoList) {
+		mixin.ResError(c, errors.DogPhotoIllegal)
+		return
+	}
+	// every photo of the dog exactly once
+	seen := make(map[uint]bool, len(param.PhotoIds))
+	for _, photoId := range param.PhotoIds {
+		if photoMap[photoId] == nil || seen[photoId] {
+			mixin.ResError(c, errors.DogPhotoIllegal)
+			return
+		}
+		seen[photoId] = true
+	}
+
+	err = d.Dep.TranModel.ExecTrans(ctx, d.Dep.DBClient.Db, func(ctx context.Context) error {
+		for position, photoId := range param.PhotoIds {
+			if photoMap[photoId].Position == position {
+				continue
+			}
+			if err := d.Dep.DogPhotoModel.SetPosition(ctx, photoId, position); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Errorf(ctx, "reorder dog photo fail %+v", err)
+		mixin.ResError(c, errors.UpdateDogPhotoFail)
+		return
+	}
+
+	res := make(schema.DogPhotoList, 0, len(param.PhotoIds))
+	for position, photoId := range param.PhotoIds {
+		photo := *photoMap[photoId]
+		photo.Position = position
+		res = append(res, photo)
+	}
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+		"data":    res,
+	})
+}
+
+// ListDogPhoto gallery in display order
+func (d *DogController) ListDogPhoto(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var param types.DogPhotoListParam
+	if err := c.ShouldBindQuery(&param); err != nil {
+		logger.Errorf(ctx, "parse query params failed %+v", err)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	dogItem, err := d.Dep.DogModel.GetById(ctx, param.DogId)
+	if err != nil {
+		logger.Errorf(ctx, "get dog fail %+v", err)
+		mixin.ResError(c, errors.ListDogPhotoFail)
+		return
+	}
+	role, err := dogRole(ctx, d.Dep, dogItem, userId)
+	if err != nil {
+		logger.Errorf(ctx, "get dog role fail %+v", err)
+		mixin.ResError(c, errors.ListDogPhotoFail)
+		return
+	}
+	if !schema.RoleAtLeast(role, schema.HouseholdRoleViewer) {
+		mixin.ResError(c, errors.DogNotExist)
+		return
+	}
+
+	photoList, err := d.Dep.DogPhotoModel.ListByDogId(ctx, dogItem.ID)
+	if err != nil {
+		logger.Errorf(ctx, "list dog photo fail %+v", err)
+		mixin.ResError(c, errors.ListDogPhotoFail)
+		return
+	}
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+		"data":    photoList,
+	})
+}
+
+// checkPhotoOwner writes the error response unless the user is an owner of the dog
+func (d *DogController) checkPhotoOwner(c *gin.Context, dogId uint, userId uint) bool {
+	ctx := c.Request.Context()
+
+	dogItem, err := d.Dep.DogModel.GetById(ctx, dogId)
+	if err != nil {
+		logger.Errorf(ctx, "get dog fail %+v", err)
+		mixin.ResError(c, errors.UpdateDogPhotoFail)
+		return false
+	}
+	role, err := dogRole(ctx, d.Dep, dogItem, userId)
+	if err != nil {
+		logger.Errorf(ctx, "get dog role fail %+v", err)
+		mixin.ResError(c, errors.UpdateDogPhotoFail)
+		return false
+	}
+	if !schema.RoleAtLeast(role, schema.HouseholdRoleOwner) {
+		mixin.ResError(c, errors.DogNotExist)
+		return false
+	}
+	return true
+}
+
+// getOwnedPhoto writes the error response unless the photo exists and the user is an owner of its dog
+func (d *DogController) getOwnedPhoto(c *gin.Context, photoId uint, userId uint) (*schema.DogPhoto, bool) {
+	ctx := c.Request.Context()
+
+	photo, err := d.Dep.DogPhotoModel.GetById(ctx, photoId)
+	if err != nil {
+		logger.Errorf(ctx, "get dog photo fail %+v", err)
+		mixin.ResError(c, errors.UpdateDogPhotoFail)
+		return nil, false
+	}
+	if photo == nil {
+		mixin.ResError(c, errors.DogPhotoNotExist)
+		return nil, false
+	}
+	if !d.checkPhotoOwner(c, photo.DogId, userId) {
+		return nil, false
+	}
+	return photo, true
+}
+
+// syncDogImg keep Dog.Img on the primary photo, promoting the first one when the primary is gone
+func syncDogImg(ctx context.Context, dep mixin.StoreDepency, dogId uint) error {
+	photoList, err := dep.DogPhotoModel.ListByDogId(ctx, dogId)
+	if err != nil {
+		return err
+	}
+	primary := photoList.Primary()
+	if primary == nil {
+		return dep.DogModel.SetImg(ctx, dogId, "")
+	}
+	if !primary.IsPrimary {
+		if err := dep.DogPhotoModel.SetPrimary(ctx, dogId, primary.ID); err != nil {
+			return err
+		}
+	}
+	return dep.DogModel.SetImg(ctx, dogId, primary.Url)
+}
@@ -0,0 +1,347 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yiff028/comp90018-mobile-project/backend/api/types"
+	"github.com/yiff028/comp90018-mobile-project/backend/app/mixin"
+	"github.com/yiff028/comp90018-mobile-project/backend/app/model/redismodel"
+	"github.com/yiff028/comp90018-mobile-project/backend/app/model/schema"
+	"github.com/yiff028/comp90018-mobile-project/backend/app/service"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/errors"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/logger"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/util"
+)
+
+const defaultGeofenceEventLimit = 50
+
+// GeofenceController
+type GeofenceController struct {
+	Dep mixin.StoreDepency
+	WS  service.WebService
+}
+
+// CreateGeofence
+func (g *GeofenceController) CreateGeofence(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var param types.GeofenceParam
+	if err := c.ShouldBindJSON(&param); err != nil {
+		logger.Errorf(ctx, "parse query params failed %+v", err)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	geofence, ok := g.buildGeofence(c, userId, param)
+	if !ok {
+		return
+	}
+	if err := g.Dep.GeofenceModel.Create(ctx, geofence); err != nil {
+		logger.Errorf(ctx, "create geofence fail %+v", err)
+		mixin.ResError(c, errors.CreateGeofenceFail)
+		return
+	}
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+		"data":    geofence,
+	})
+}
+
+// UpdateGeofence
+func (g *GeofenceController) UpdateGeofence(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var param types.GeofenceParam
+	if err := c.ShouldBindJSON(&param); err != nil || param.GeofenceId == 0 {
+		logger.Errorf(ctx, "parse query params failed %+v", err)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	geofence, ok := g.buildGeofence(c, userId, param)
+	if !ok {
+		return
+	}
+	geofence.ID = param.GeofenceId
+	affected, err := g.Dep.GeofenceModel.Update(ctx, geofence)
+	if err != nil {
+		logger.Errorf(ctx, "update geofence fail %+v", err)
+		mixin.ResError(c, errors.UpdateGeofenceFail)
+		return
+	}
+	if affected == 0 {
+		mixin.ResError(c, errors.GeofenceNotExist)
+		return
+	}
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+		"data":    geofence,
+	})
+}
+
+// buildGeofence validate the param, writes the error response when it is not ok
+func (g *GeofenceController) buildGeofence(c *gin.Context, userId uint, param types.GeofenceParam) (*schema.Geofence, bool) {
+	ctx := c.Request.Context()
+
+	if param.DogId != nil {
+		dogItem, err := g.Dep.DogModel.GetById(ctx, *param.DogId)
+		if err != nil {
+			logger.Errorf(ctx, "get dog by id fail %+v", err)
+			mixin.ResError(c, errors.DogNotExist)
+			return nil, false
+		}
+		if dogItem == nil || dogItem.UserId != userId {
+			mixin.ResError(c, errors.DogNotExist)
+			return nil, false
+		}
+	}
+
+	geofence := schema.Geofence{
+		UserId: userId,
+		DogId:  param.DogId,
+		Name:   param.Name,
+		Kind:   param.Kind,
+	}
+	if param.Kind == schema.GeofenceKindCircle {
+		geofence.Latitude = param.Latitude
+		geofence.Longitude = param.Longitude
+		geofence.Radius = param.Radius
+	} else {
+		for _, v := range param.Polygon {
+			geofence.Polygon = append(geofence.Polygon, util.GeoPoint{Latitude: v.Latitude, Longitude: v.Longitude})
+		}
+	}
+	if !geofence.Valid() {
+		logger.Errorf(ctx, "illegal geofence %+v", param)
+		mixin.ResError(c, errors.GeofenceIllegal)
+		return nil, false
+	}
+	return &geofence, true
+}
+
+// DeleteGeofence
+func (g *GeofenceController) DeleteGeofence(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var param types.GeofenceEditParam
+	if err := c.ShouldBindJSON(&param); err != nil {
+		logger.Errorf(ctx, "parse query params failed %+v", err)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	if err := g.Dep.GeofenceModel.Delete(ctx, param.GeofenceId, userId); err != nil {
+		logger.Errorf(ctx, "delete geofence fail %+v", err)
+		mixin.ResError(c, errors.DeleteGeofenceFail)
+		return
+	}
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+	})
+}
+
+// ListGeofence
+func (g *GeofenceController) ListGeofence(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	geofenceList, err := g.Dep.GeofenceModel.ListByUserId(ctx, userId)
+	if err != nil {
+		logger.Errorf(ctx, "list geofence fail %+v", err)
+		mixin.ResError(c, errors.ListGeofenceFail)
+		return
+	}
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+		"data":    geofenceList,
+	})
+}
+
+// ListGeofenceEvent enter and exit history, newest first
+func (g *GeofenceController) ListGeofenceEvent(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var param types.GeofenceEventListParam
+	if err := c.ShouldBindQuery(&param); err != nil {
+		logger.Errorf(ctx, "parse query params failed %+v", err)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	if param.Limit == 0 {
+		param.Limit = defaultGeofenceEventLimit
+	}
+	page := schema.PaginationParam{
+		Pagination: true,
+		Offset:     param.Offset,
+		Limit:      param.Limit,
+	}
+	eventList, pageResult, err := g.Dep.GeofenceModel.ListEvent(ctx, userId, param.DogId, param.GeofenceId, page)
+	if err != nil {
+		logger.Errorf(ctx, "list geofence event fail %+v", err)
+		mixin.ResError(c, errors.ListGeofenceEventFail)
+		return
+	}
+
+	mixin.ResPage(c, eventList, &mixin.PaginationResult{
+		Total:  pageResult.Total,
+		Offset: pageResult.Offset,
+		Limit:  pageResult.Limit,
+	})
+}
+
+// recordGeofenceEvents walk the dog from its previous location through the new points, oldest first, against the dog owner's geofences
+// so entering and leaving a fence between two uploads both count, no event is recorded before the location is known
+func recordGeofenceEvents(ctx context.Context, dep mixin.StoreDepency, route *schema.Route, prev *schema.Dog, points []util.GeoPoint) (schema.GeofenceEventList, error) {
+	if prev == nil || len(points) == 0 {
+		return nil, nil
+	}
+	geofenceList, err := dep.GeofenceModel.ListByUserId(ctx, prev.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	events := schema.GeofenceEventList{}
+	last := prev.Latitude != nil && prev.Longitude != nil
+	lastLatitude, lastLongitude := 0.0, 0.0
+	if last {
+		lastLatitude, lastLongitude = *prev.Latitude, *prev.Longitude
+	}
+	for _, point := range points {
+		if !last {
+			last = true
+			lastLatitude, lastLongitude = point.Latitude, point.Longitude
+			continue
+		}
+		for _, geofence := range geofenceList {
+			if !geofence.AppliesTo(prev.ID) {
+				continue
+			}
+			wasInside := geofence.Contains(lastLatitude, lastLongitude)
+			isInside := geofence.Contains(point.Latitude, point.Longitude)
+			if wasInside == isInside {
+				continue
+			}
+			eventType := schema.GeofenceEventExit
+			if isInside {
+				eventType = schema.GeofenceEventEnter
+			}
+			events = append(events, schema.GeofenceEvent{
+				GeofenceId:  geofence.ID,
+				UserId:      prev.UserId,
+				DogId:       prev.ID,
+				RouteId:     route.ID,
+				Type:        eventType,
+				Latitude:    point.Latitude,
+				Longitude:   point.Longitude,
+				CreatedTime: util.GetTimePtr(point.Time),
+			})
+		}
+		lastLatitude, lastLongitude = point.Latitude, point.Longitude
+	}
+	if err := dep.GeofenceModel.CreateEvents(ctx, events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// notifyGeofenceEvents tell the dog owners, called once the events are committed
+func notifyGeofenceEvents(ctx context.Context, dep mixin.StoreDepency, events schema.GeofenceEventList) {
+	if len(events) == 0 {
+		return
+	}
+	geofenceNames := map[uint]string{}
+	listed := map[uint]bool{}
+	for _, event := range events {
+		if listed[event.UserId] {
+			continue
+		}
+		listed[event.UserId] = true
+		geofenceList, err := dep.GeofenceModel.ListByUserId(ctx, event.UserId)
+		if err != nil {
+			logger.Errorf(ctx, "list geofence fail %+v", err)
+			return
+		}
+		for _, geofence := range geofenceList {
+			geofenceNames[geofence.ID] = geofence.Name
+		}
+	}
+	dogNames := map[uint]string{}
+
+	for _, event := range events {
+		dogName, ok := dogNames[event.DogId]
+		if !ok {
+			dogName = "Your dog"
+			if dogItem, err := dep.DogModel.GetById(ctx, event.DogId); err == nil && dogItem != nil {
+				dogName = dogItem.Name
+			}
+			dogNames[event.DogId] = dogName
+		}
+		action := "left"
+		if event.Type == schema.GeofenceEventEnter {
+			action = "entered"
+		}
+		enqueueNotification(ctx, dep, redismodel.NotificationJob{
+			UserIds:  []uint{event.UserId},
+			Category: schema.NotificationCategoryGeofence,
+			Title:    fmt.Sprintf("%s %s %s", dogName, action, geofenceNames[event.GeofenceId]),
+			Body:     fmt.Sprintf("%s %s %s at %s", dogName, action, geofenceNames[event.GeofenceId], event.CreatedTime.Format("15:04")),
+			RefId:    event.ID,
+			Data: map[string]string{
+				"geofenceId": strconv.Itoa(int(event.GeofenceId)),
+				"type":       event.Type,
+			},
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package controller
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yiff028/comp90018-mobile-project/backend/api/types"
+	configs "github.com/yiff028/comp90018-mobile-project/backend/app/config"
+	"github.com/yiff028/comp90018-mobile-project/backend/app/mixin"
+	"github.com/yiff028/comp90018-mobile-project/backend/app/model/schema"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/errors"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/logger"
+)
+
+const (
+	defaultGoalHistoryDays = 7
+	// goalStreakDays how far back streaks are counted
+	goalStreakDays = 365
+)
+
+// SetDogGoal
+func (d *DogController) SetDogGoal(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var param types.DogGoalParam
+	if err := c.ShouldBindJSON(&param); err != nil {
+		logger.Errorf(ctx, "parse query params failed %+v", err)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	dogItem, err := d.Dep.DogModel.GetById(ctx, param.DogId)
+	if err != nil {
+		logger.Errorf(ctx, "get dog fail %+v", err)
+		mixin.ResError(c, errors.SetDogGoalFail)
+		return
+	}
+	role, err := dogRole(ctx, d.Dep, dogItem, userId)
+	if err != nil {
+		logger.Errorf(ctx, "get dog role fail %+v", err)
+		mixin.ResError(c, errors.SetDogGoalFail)
+		return
+	}
+	if !schema.RoleAtLeast(role, schema.HouseholdRoleOwner) {
+		mixin.ResError(c, errors.DogNotExist)
+		return
+	}
+
+	if param.GoalType == "" {
+		param.GoalValue = 0
+		param.GoalReminder = ""
+	} else if param.GoalValue <= 0 {
+		mixin.ResError(c, errors.DogGoalIllegal)
+		return
+	}
+	if param.GoalReminder != "" {
+		if _, ok := schema.ParseGoalReminder(param.GoalReminder); !ok {
+			mixin.ResError(c, errors.DogGoalIllegal)
+			return
+		}
+	}
+	if param.GoalTimezone != "" {
+		if _, err := time.LoadLocation(param.GoalTimezone); err != nil {
+			mixin.ResError(c, errors.DogGoalIllegal)
+			return
+		}
+	}
+
+	err = d.Dep.DogModel.SetGoal(ctx, dogItem.ID, userId, param.GoalType, param.GoalValue, param.GoalReminder, param.GoalTimezone)
+	if err != nil {
+		logger.Errorf(ctx, "set dog goal fail %+v", err)
+		mixin.ResError(c, errors.SetDogGoalFail)
+		return
+	}
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+		"data": gin.H{
+			"dogId":        dogItem.ID,
+			"goalType":     param.GoalType,
+			"goalValue":    param.GoalValue,
+			"goalReminder": param.GoalReminder,
+			"goalTimezone": param.GoalTimezone,
+		},
+	})
+}
+
+// GetDogGoalProgress today's progress, streaks and the last days
+func (d *DogController) GetDogGoalProgress(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var param types.DogGoalProgressParam
+	if err := c.ShouldBindQuery(&param); err != nil {
+		logger.Errorf(ctx, "parse query params failed %+v", err)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	dogItem, err := d.Dep.DogModel.GetById(ctx, param.DogId)
+	if err != nil {
+		logger.Errorf(ctx, "get dog fail %+v", err)
+		mixin.ResError(c, errors.GetDogGoalProgressFail)
+		return
+	}
+	role, err := dogRole(ctx, d.Dep, dogItem, userId)
+	if err != nil {
+		logger.Errorf(ctx, "get dog role fail %+v", err)
+		mixin.ResError(c, errors.GetDogGoalProgressFail)
+		return
+	}
+	if !schema.RoleAtLeast(role, schema.HouseholdRoleViewer) {
+		mixin.ResError(c, errors.DogNotExist)
+		return
+	}
+	if !dogItem.HasGoal() {
+		mixin.ResError(c, errors.DogGoalIllegal)
+		return
+	}
+
+	days := param.Days
+	if days <= 0 {
+		days = defaultGoalHistoryDays
+	}
+
+	activities, err := ListDailyActivity(ctx, d.Dep, dogItem, goalStreakDays, time.Now())
+	if err != nil {
+		logger.Errorf(ctx, "list daily activity fail %+v", err)
+		mixin.ResError(c, errors.GetDogGoalProgressFail)
+		return
+	}
+	streak, bestStreak := schema.GoalStreak(activities)
+	today := activities[len(activities)-1]
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+		"data": gin.H{
+			"dogId":        dogItem.ID,
+			"goalType":     dogItem.GoalType,
+			"goalValue":    dogItem.GoalValue,
+			"goalReminder": dogItem.GoalReminder,
+			"today":        today,
+			"remaining":    math.Max(dogItem.GoalValue-today.Progress, 0),
+			"percent":      math.Min(today.Progress/dogItem.GoalValue*100, 100),
+			"streak":       streak,
+			"bestStreak":   bestStreak,
+			"history":      activities[len(activities)-days:],
+		},
+	})
+}
+
+// DogGoalLocation timezone the goal days of a dog are counted in
+func DogGoalLocation(dog *schema.Dog) *time.Location {
+	fallback := time.Local
+	if name := configs.C.Dog.GoalTimezone; name != "" {
+		if loc, err := time.LoadLocation(name); err == nil {
+			fallback = loc
+		}
+	}
+	return dog.GoalLocation(fallback)
+}
+
+// ListDailyActivity the last days local days of a dog up to and including the day of now
+func ListDailyActivity(ctx context.Context, dep mixin.StoreDepency, dog *schema.Dog, days int, now time.Time) ([]schema.DailyActivity, error) {
+	loc := DogGoalLocation(dog)
+	localNow := now.In(loc)
+	dayStart := time.Date(localNow.Year(), localNow.Month(), localNow.Day(), 0, 0, 0, 0, loc)
+	from := dayStart.AddDate(0, 0, 1-days)
+	to := dayStart.AddDate(0, 0, 1)
+
+	routeList, err := dep.RouteModel.ListByDogAndTime(ctx, dog.ID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return dog.DailyActivities(routeList, from, dayStart, loc), nil
+}
@@ -0,0 +1,372 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yiff028/comp90018-mobile-project/backend/api/types"
+	"github.com/yiff028/comp90018-mobile-project/backend/app/mixin"
+	"github.com/yiff028/comp90018-mobile-project/backend/app/model/redismodel"
+	"github.com/yiff028/comp90018-mobile-project/backend/app/model/schema"
+	"github.com/yiff028/comp90018-mobile-project/backend/app/service"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/errors"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/logger"
+)
+
+// maxGrantDays longest a grant may last
+const maxGrantDays = 90
+
+// GrantController
+type GrantController struct {
+	Dep mixin.StoreDepency
+	WS  service.WebService
+}
+
+// CreateGrant let someone walk or view one of the caller's dogs for a limited time
+func (g *GrantController) CreateGrant(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var param types.DogGrantCreateParam
+	if err := c.ShouldBindJSON(&param); err != nil {
+		logger.Errorf(ctx, "parse query params failed %+v", err)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	now := time.Now()
+	startTime := now
+	if param.StartTime != nil && param.StartTime.After(now) {
+		startTime = *param.StartTime
+	}
+	endTime := param.EndTime
+	if param.GranteeId == userId || !endTime.After(startTime) || endTime.Sub(startTime) > maxGrantDays*24*time.Hour {
+		mixin.ResError(c, errors.GrantIllegal)
+		return
+	}
+
+	dogItem, err := g.Dep.DogModel.GetById(ctx, param.DogId)
+	if err != nil {
+		logger.Errorf(ctx, "get dog fail %+v", err)
+		mixin.ResError(c, errors.CreateGrantFail)
+		return
+	}
+	role, err := dogRole(ctx, g.Dep, dogItem, userId)
+	if err != nil {
+		logger.Errorf(ctx, "get dog role fail %+v", err)
+		mixin.ResError(c, errors.CreateGrantFail)
+		return
+	}
+	if !schema.RoleAtLeast(role, schema.HouseholdRoleOwner) {
+		mixin.ResError(c, errors.DogNotExist)
+		return
+	}
+
+	grantee, err := g.Dep.UserModel.GetById(ctx, int(param.GranteeId))
+	if err != nil {
+		logger.Errorf(ctx, "get user fail %+v", err)
+		mixin.ResError(c, errors.CreateGrantFail)
+		return
+	}
+	if grantee == nil {
+		mixin.ResError(c, errors.UserNotExist)
+		return
+	}
+
+	grant := schema.DogGrant{
+		DogId:     dogItem.ID,
+		OwnerId:   userId,
+		GranteeId: grantee.ID,
+		Role:      param.Role,
+		StartTime: &startTime,
+		EndTime:   &endTime,
+	}
+	if err := g.Dep.DogGrantModel.Create(ctx, &grant); err != nil {
+		logger.Errorf(ctx, "create dog grant fail %+v", err)
+		mixin.ResError(c, errors.CreateGrantFail)
+		return
+	}
+
+	action := "view"
+	if grant.Role == schema.HouseholdRoleWalker {
+		action = "walk"
+	}
+	enqueueNotification(ctx, g.Dep, redismodel.NotificationJob{
+		UserIds:  []uint{grantee.ID},
+		Category: schema.NotificationCategoryDelegation,
+		Title:    fmt.Sprintf("You can %s %s", action, dogItem.Name),
+		Body:     fmt.Sprintf("From %s until %s", startTime.Format("Jan 2 15:04"), endTime.Format("Jan 2 15:04")),
+		RefId:    grant.ID,
+		Data: map[string]string{
+			"dogId": strconv.Itoa(int(dogItem.ID)),
+			"role":  grant.Role,
+		},
+	})
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+		"data":    grant,
+	})
+}
+
+// RevokeGrant end a grant before its end time
+func (g *GrantController) RevokeGrant(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var param types.DogGrantEditParam
+	if err := c.ShouldBindJSON(&param); err != nil {
+		logger.Errorf(ctx, "parse query params failed %+v", err)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	// the grantee's open walks with the dog end with the grant
+	var affected int64
+	var finishedList schema.RouteList
+	err := g.Dep.TranModel.ExecTrans(ctx, g.Dep.DBClient.Db, func(ctx context.Context) error {
+		var err error
+		affected, err = g.Dep.DogGrantModel.Revoke(ctx, param.GrantId, userId)
+		if err != nil || affected == 0 {
+			return err
+		}
+		grant, err := g.Dep.DogGrantModel.GetById(ctx, param.GrantId)
+		if err != nil || grant == nil {
+			return err
+		}
+		finishedList, err = finishGrantWalks(ctx, g.Dep, *grant)
+		return err
+	})
+	if err != nil {
+		logger.Errorf(ctx, "revoke dog grant fail %+v", err)
+		mixin.ResError(c, errors.RevokeGrantFail)
+		return
+	}
+	if affected == 0 {
+		mixin.ResError(c, errors.GrantNotExist)
+		return
+	}
+	for i := range finishedList {
+		NotifyDelegatedWalk(ctx, g.Dep, &finishedList[i])
+	}
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+	})
+}
+
+// ListGrant grants the caller gave, and the current or upcoming ones received
+func (g *GrantController) ListGrant(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	givenList, err := g.Dep.DogGrantModel.ListByOwnerId(ctx, userId)
+	if err != nil {
+		logger.Errorf(ctx, "list given dog grant fail %+v", err)
+		mixin.ResError(c, errors.ListGrantFail)
+		return
+	}
+	receivedList, err := g.Dep.DogGrantModel.ListByGranteeId(ctx, userId, time.Now())
+	if err != nil {
+		logger.Errorf(ctx, "list received dog grant fail %+v", err)
+		mixin.ResError(c, errors.ListGrantFail)
+		return
+	}
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+		"data": gin.H{
+			"given":    givenList,
+			"received": receivedList,
+		},
+	})
+}
+
+// NotifyDelegatedWalk tell the owners whose dogs were taken out under a grant that the walk started or finished
+// failures only get logged
+func NotifyDelegatedWalk(ctx context.Context, dep mixin.StoreDepency, route *schema.Route) {
+	if route.Status != schema.RouteStatusActive && route.Status != schema.RouteStatusFinished {
+		return
+	}
+	if len(route.DogIds) == 0 {
+		if err := fillRouteDogIds(ctx, dep, route); err != nil {
+			logger.Errorf(ctx, "list route dog fail %+v", err)
+			return
+		}
+	}
+	startTime := time.Now()
+	if route.CreatedTime != nil {
+		startTime = *route.CreatedTime
+	}
+	grantList, err := dep.DogGrantModel.ListActive(ctx, route.UserId, route.DogIds, startTime)
+	if err != nil {
+		logger.Errorf(ctx, "list dog grant fail %+v", err)
+		return
+	}
+	if len(grantList) == 0 {
+		return
+	}
+
+	walkerName := "Your walker"
+	if walker, err := dep.UserModel.GetById(ctx, int(route.UserId)); err == nil && walker != nil {
+		walkerName = walker.Nickname
+		if walkerName == "" {
+			walkerName = walker.Username
+		}
+	}
+	ownerIds := []uint{}
+	dogNames := map[uint][]string{}
+	seen := map[uint]bool{}
+	for _, grant := range grantList {
+		if seen[grant.DogId] {
+			continue
+		}
+		seen[grant.DogId] = true
+		dogItem, err := dep.DogModel.GetById(ctx, grant.DogId)
+		if err != nil || dogItem == nil {
+			continue
+		}
+		if _, ok := dogNames[grant.OwnerId]; !ok {
+			ownerIds = append(ownerIds, grant.OwnerId)
+		}
+		dogNames[grant.OwnerId] = append(dogNames[grant.OwnerId], dogItem.Name)
+	}
+
+	for _, ownerId := range ownerIds {
+		names := strings.Join(dogNames[ownerId], " and ")
+		title := fmt.Sprintf("%s started walking %s", walkerName, names)
+		body := "Follow the walk live"
+		if route.Status == schema.RouteStatusFinished {
+			title = fmt.Sprintf("%s finished walking %s", walkerName, names)
+			body = fmt.Sprintf("%.2f km in %d min", route.Distance/1000, route.ActiveDuration/60)
+		}
+		enqueueNotification(ctx, dep, redismodel.NotificationJob{
+			UserIds:  []uint{ownerId},
+			Category: schema.NotificationCategoryDelegation,
+			Title:    title,
+			Body:     body,
+			RefId:    route.ID,
+			Data: map[string]string{
+				"routeId": strconv.Itoa(int(route.ID)),
+				"status":  route.Status,
+			},
+		})
+	}
+}
+
+// ExpireDogGrants revoke the grants past their end time, finishing the walks still open under them
+func ExpireDogGrants(ctx context.Context, dep mixin.StoreDepency, now time.Time) (int, error) {
+	grantList, err := dep.DogGrantModel.ListEnded(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, grant := range grantList {
+		affected, err := dep.DogGrantModel.Expire(ctx, grant.ID)
+		if err != nil {
+			return count, err
+		}
+		if affected == 0 {
+			continue
+		}
+		count++
+
+		finishedList, err := finishGrantWalks(ctx, dep, grant)
+		if err != nil {
+			return count, err
+		}
+		for i := range finishedList {
+			NotifyDelegatedWalk(ctx, dep, &finishedList[i])
+		}
+		dogName := "the dog"
+		if dogItem, err := dep.DogModel.GetById(ctx, grant.DogId); err == nil && dogItem != nil {
+			dogName = dogItem.Name
+		}
+
+		enqueueNotification(ctx, dep, redismodel.NotificationJob{
+			UserIds:  []uint{grant.GranteeId},
+			Category: schema.NotificationCategoryDelegation,
+			Title:    fmt.Sprintf("Your access to %s ended", dogName),
+			Body:     fmt.Sprintf("The access ended at %s", grant.EndTime.Format("Jan 2 15:04")),
+			RefId:    grant.ID,
+			Data: map[string]string{
+				"dogId": strconv.Itoa(int(grant.DogId)),
+			},
+		})
+	}
+	return count, nil
+}
+
+// finishGrantWalks finish the open walks of the grantee with the dog once the grant is over,
+// unless they may still walk it through a household or another grant. The owners are notified by the caller
+func finishGrantWalks(ctx context.Context, dep mixin.StoreDepency, grant schema.DogGrant) (schema.RouteList, error) {
+	return finishWalksWithout(ctx, dep, grant.GranteeId, []uint{grant.DogId})
+}
+
+// finishWalksWithout finish the open walks of userId with any of the dogs they may no longer walk
+func finishWalksWithout(ctx context.Context, dep mixin.StoreDepency, userId uint, dogIds []uint) (schema.RouteList, error) {
+	dogList, err := dep.DogModel.ListByRole(ctx, userId, schema.HouseholdRoleWalker)
+	if err != nil {
+		return nil, err
+	}
+	walkable := make(map[uint]bool, len(dogList))
+	for _, dogItem := range dogList {
+		walkable[dogItem.ID] = true
+	}
+
+	finishedList := schema.RouteList{}
+	for _, dogId := range dogIds {
+		if walkable[dogId] {
+			continue
+		}
+		routeList, err := dep.RouteModel.ListOpenByUserAndDog(ctx, userId, dogId)
+		if err != nil {
+			return nil, err
+		}
+		for _, route := range routeList {
+			// a walk with several dogs is finished once, the second update affects nothing
+			affected, err := dep.RouteModel.UpdateStatus(ctx, route.ID, route.UserId, route.Status, schema.RouteStatusFinished)
+			if err != nil {
+				return nil, err
+			}
+			if affected == 0 {
+				continue
+			}
+			if err := dep.RouteShareModel.ExpireByRouteId(ctx, route.ID); err != nil {
+				logger.Errorf(ctx, "expire route share fail %+v", err)
+			}
+			route.Status = schema.RouteStatusFinished
+			finishedList = append(finishedList, route)
+		}
+	}
+	return finishedList, nil
+}
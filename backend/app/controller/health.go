@@ -0,0 +1,649 @@
+package controller
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yiff028/comp90018-mobile-project/backend/api/types"
+	"github.com/yiff028/comp90018-mobile-project/backend/app/mixin"
+	"github.com/yiff028/comp90018-mobile-project/backend/app/model/schema"
+	"github.com/yiff028/comp90018-mobile-project/backend/app/service"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/errors"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/logger"
+)
+
+const defaultHealthDueDays = 30
+
+// HealthController
+type HealthController struct {
+	Dep mixin.StoreDepency
+	WS  service.WebService
+}
+
+// CreateVaccination
+func (h *HealthController) CreateVaccination(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var param types.VaccinationParam
+	if err := c.ShouldBindJSON(&param); err != nil {
+		logger.Errorf(ctx, "parse query params failed %+v", err)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	vaccination, ok := h.buildVaccination(c, userId, param)
+	if !ok {
+		return
+	}
+	if err := h.Dep.HealthModel.CreateVaccination(ctx, vaccination); err != nil {
+		logger.Errorf(ctx, "create vaccination fail %+v", err)
+		mixin.ResError(c, errors.CreateHealthRecordFail)
+		return
+	}
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+		"data":    vaccination,
+	})
+}
+
+// UpdateVaccination
+func (h *HealthController) UpdateVaccination(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var param types.VaccinationParam
+	if err := c.ShouldBindJSON(&param); err != nil || param.VaccinationId == 0 {
+		logger.Errorf(ctx, "parse query params failed %+v", err)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	// the record stays with its dog, the user must own that dog
+	dogId, ok := h.recordDog(c, param.VaccinationId, h.vaccinationDog, errors.UpdateHealthRecordFail)
+	if !ok {
+		return
+	}
+	param.DogId = dogId
+	vaccination, ok := h.buildVaccination(c, userId, param)
+	if !ok {
+		return
+	}
+	vaccination.ID = param.VaccinationId
+	affected, err := h.Dep.HealthModel.UpdateVaccination(ctx, vaccination)
+	if err != nil {
+		logger.Errorf(ctx, "update vaccination fail %+v", err)
+		mixin.ResError(c, errors.UpdateHealthRecordFail)
+		return
+	}
+	if affected == 0 {
+		mixin.ResError(c, errors.HealthRecordNotExist)
+		return
+	}
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+		"data":    vaccination,
+	})
+}
+
+// DeleteVaccination
+func (h *HealthController) DeleteVaccination(c *gin.Context) {
+	h.deleteRecord(c, h.vaccinationDog, h.Dep.HealthModel.DeleteVaccination)
+}
+
+// ListVaccination
+func (h *HealthController) ListVaccination(c *gin.Context) {
+	h.listRecord(c, func(ctx context.Context, dogId uint) (interface{}, error) {
+		return h.Dep.HealthModel.ListVaccination(ctx, dogId)
+	})
+}
+
+// buildVaccination validate the param, writes the error response when it is not ok
+func (h *HealthController) buildVaccination(c *gin.Context, userId uint, param types.VaccinationParam) (*schema.Vaccination, bool) {
+	if !h.checkDogRole(c, param.DogId, userId, schema.HouseholdRoleOwner) {
+		return nil, false
+	}
+	if param.AdministeredDate != nil && param.DueDate != nil && param.DueDate.Before(*param.AdministeredDate) {
+		mixin.ResError(c, errors.HealthRecordIllegal)
+		return nil, false
+	}
+	return &schema.Vaccination{
+		DogId:            param.DogId,
+		UserId:           userId,
+		Name:             param.Name,
+		AdministeredDate: param.AdministeredDate,
+		DueDate:          param.DueDate,
+		VetName:          param.VetName,
+		Notes:            param.Notes,
+	}, true
+}
+
+// CreateMedication
+func (h *HealthController) CreateMedication(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var param types.MedicationParam
+	if err := c.ShouldBindJSON(&param); err != nil {
+		logger.Errorf(ctx, "parse query params failed %+v", err)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	medication, ok := h.buildMedication(c, userId, param)
+	if !ok {
+		return
+	}
+	if err := h.Dep.HealthModel.CreateMedication(ctx, medication); err != nil {
+		logger.Errorf(ctx, "create medication fail %+v", err)
+		mixin.ResError(c, errors.CreateHealthRecordFail)
+		return
+	}
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+		"data":    medication,
+	})
+}
+
+// UpdateMedication
+func (h *HealthController) UpdateMedication(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var param types.MedicationParam
+	if err := c.ShouldBindJSON(&param); err != nil || param.MedicationId == 0 {
+		logger.Errorf(ctx, "parse query params failed %+v", err)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	// the record stays with its dog, the user must own that dog
+	dogId, ok := h.recordDog(c, param.MedicationId, h.medicationDog, errors.UpdateHealthRecordFail)
+	if !ok {
+		return
+	}
+	param.DogId = dogId
+	medication, ok := h.buildMedication(c, userId, param)
+	if !ok {
+		return
+	}
+	medication.ID = param.MedicationId
+	affected, err := h.Dep.HealthModel.UpdateMedication(ctx, medication)
+	if err != nil {
+		logger.Errorf(ctx, "update medication fail %+v", err)
+		mixin.ResError(c, errors.UpdateHealthRecordFail)
+		return
+	}
+	if affected == 0 {
+		mixin.ResError(c, errors.HealthRecordNotExist)
+		return
+	}
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+		"data":    medication,
+	})
+}
+
+// DeleteMedication
+func (h *HealthController) DeleteMedication(c *gin.Context) {
+	h.deleteRecord(c, h.medicationDog, h.Dep.HealthModel.DeleteMedication)
+}
+
+// ListMedication each course with its next dose
+func (h *HealthController) ListMedication(c *gin.Context) {
+	h.listRecord(c, func(ctx context.Context, dogId uint) (interface{}, error) {
+		medicationList, err := h.Dep.HealthModel.ListMedication(ctx, dogId)
+		if err != nil {
+			return nil, err
+		}
+		now := time.Now()
+		res := make([]gin.H, 0, len(medicationList))
+		for _, medication := range medicationList {
+			res = append(res, gin.H{
+				"medication": medication,
+				"nextDose":   medication.NextDose(now),
+			})
+		}
+		return res, nil
+	})
+}
+
+// buildMedication validate the param, writes the error response when it is not ok
+func (h *HealthController) buildMedication(c *gin.Context, userId uint, param types.MedicationParam) (*schema.Medication, bool) {
+	if !h.checkDogRole(c, param.DogId, userId, schema.HouseholdRoleOwner) {
+		return nil, false
+	}
+	if param.EndTime != nil && param.EndTime.Before(*param.StartTime) {
+		mixin.ResError(c, errors.HealthRecordIllegal)
+		return nil, false
+	}
+	return &schema.Medication{
+		DogId:         param.DogId,
+		UserId:        userId,
+		Name:          param.Name,
+		Dosage:        param.Dosage,
+		IntervalHours: param.IntervalHours,
+		StartTime:     param.StartTime,
+		EndTime:       param.EndTime,
+		Notes:         param.Notes,
+	}, true
+}
+
+// CreateVetVisit
+func (h *HealthController) CreateVetVisit(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var param types.VetVisitParam
+	if err := c.ShouldBindJSON(&param); err != nil {
+		logger.Errorf(ctx, "parse query params failed %+v", err)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	visit, ok := h.buildVetVisit(c, userId, param)
+	if !ok {
+		return
+	}
+	if err := h.Dep.HealthModel.CreateVetVisit(ctx, visit); err != nil {
+		logger.Errorf(ctx, "create vet visit fail %+v", err)
+		mixin.ResError(c, errors.CreateHealthRecordFail)
+		return
+	}
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+		"data":    visit,
+	})
+}
+
+// UpdateVetVisit
+func (h *HealthController) UpdateVetVisit(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var param types.VetVisitParam
+	if err := c.ShouldBindJSON(&param); err != nil || param.VetVisitId == 0 {
+		logger.Errorf(ctx, "parse query params failed %+v", err)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	// the record stays with its dog, the user must own that dog
+	dogId, ok := h.recordDog(c, param.VetVisitId, h.vetVisitDog, errors.UpdateHealthRecordFail)
+	if !ok {
+		return
+	}
+	param.DogId = dogId
+	visit, ok := h.buildVetVisit(c, userId, param)
+	if !ok {
+		return
+	}
+	visit.ID = param.VetVisitId
+	affected, err := h.Dep.HealthModel.UpdateVetVisit(ctx, visit)
+	if err != nil {
+		logger.Errorf(ctx, "update vet visit fail %+v", err)
+		mixin.ResError(c, errors.UpdateHealthRecordFail)
+		return
+	}
+	if affected == 0 {
+		mixin.ResError(c, errors.HealthRecordNotExist)
+		return
+	}
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+		"data":    visit,
+	})
+}
+
+// DeleteVetVisit
+func (h *HealthController) DeleteVetVisit(c *gin.Context) {
+	h.deleteRecord(c, h.vetVisitDog, h.Dep.HealthModel.DeleteVetVisit)
+}
+
+// ListVetVisit
+func (h *HealthController) ListVetVisit(c *gin.Context) {
+	h.listRecord(c, func(ctx context.Context, dogId uint) (interface{}, error) {
+		return h.Dep.HealthModel.ListVetVisit(ctx, dogId)
+	})
+}
+
+// buildVetVisit validate the param, writes the error response when it is not ok
+func (h *HealthController) buildVetVisit(c *gin.Context, userId uint, param types.VetVisitParam) (*schema.VetVisit, bool) {
+	if !h.checkDogRole(c, param.DogId, userId, schema.HouseholdRoleOwner) {
+		return nil, false
+	}
+	if param.FollowUpDate != nil && param.FollowUpDate.Before(*param.VisitTime) {
+		mixin.ResError(c, errors.HealthRecordIllegal)
+		return nil, false
+	}
+	return &schema.VetVisit{
+		DogId:        param.DogId,
+		UserId:       userId,
+		VisitTime:    param.VisitTime,
+		Clinic:       param.Clinic,
+		Reason:       param.Reason,
+		Notes:        param.Notes,
+		Attachments:  schema.MysqlJSONArray(param.Attachments),
+		FollowUpDate: param.FollowUpDate,
+	}, true
+}
+
+// ListHealthDue overdue and upcoming items of every dog the user can see
+func (h *HealthController) ListHealthDue(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var param types.HealthDueParam
+	if err := c.ShouldBindQuery(&param); err != nil {
+		logger.Errorf(ctx, "parse query params failed %+v", err)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	days := param.Days
+	if days <= 0 {
+		days = defaultHealthDueDays
+	}
+	dogList, err := h.Dep.DogModel.ListByRole(ctx, userId, schema.HouseholdRoleViewer)
+	if err != nil {
+		logger.Errorf(ctx, "list dog fail %+v", err)
+		mixin.ResError(c, errors.ListHealthDueFail)
+		return
+	}
+	dogIds := make([]uint, 0, len(dogList))
+	for _, dogItem := range dogList {
+		dogIds = append(dogIds, dogItem.ID)
+	}
+	now := time.Now()
+	dueList, err := ListHealthDue(ctx, h.Dep, dogIds, now, now.AddDate(0, 0, days))
+	if err != nil {
+		logger.Errorf(ctx, "list health due fail %+v", err)
+		mixin.ResError(c, errors.ListHealthDueFail)
+		return
+	}
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+		"data":    dueList,
+	})
+}
+
+// ListHealthDue vaccinations and follow ups due before the given time, overdue ones included,
+// and the next dose of each medication course, soonest first. nil dogIds for every dog
+func ListHealthDue(ctx context.Context, dep mixin.StoreDepency, dogIds []uint, now time.Time, before time.Time) (schema.HealthDueList, error) {
+	if dogIds != nil && len(dogIds) == 0 {
+		return schema.HealthDueList{}, nil
+	}
+	vaccinationList, err := dep.HealthModel.ListDueVaccination(ctx, dogIds, before)
+	if err != nil {
+		return nil, err
+	}
+	medicationList, err := dep.HealthModel.ListActiveMedication(ctx, dogIds, now)
+	if err != nil {
+		return nil, err
+	}
+	visitList, err := dep.HealthModel.ListDueFollowUp(ctx, dogIds, before)
+	if err != nil {
+		return nil, err
+	}
+
+	dueList := schema.HealthDueList{}
+	for _, vaccination := range vaccinationList {
+		dueList = append(dueList, schema.HealthDue{
+			Kind:     schema.HealthKindVaccination,
+			RecordId: vaccination.ID,
+			DogId:    vaccination.DogId,
+			UserId:   vaccination.UserId,
+			Name:     vaccination.Name,
+			DueTime:  vaccination.DueDate,
+			Overdue:  vaccination.DueDate.Before(now),
+		})
+	}
+	for _, medication := range medicationList {
+		next := medication.NextDose(now)
+		if next == nil || !next.Before(before) {
+			continue
+		}
+		dueList = append(dueList, schema.HealthDue{
+			Kind:     schema.HealthKindMedication,
+			RecordId: medication.ID,
+			DogId:    medication.DogId,
+			UserId:   medication.UserId,
+			Name:     medication.Name,
+			DueTime:  next,
+		})
+	}
+	for _, visit := range visitList {
+		name := visit.Reason
+		if name == "" {
+			name = visit.Clinic
+		}
+		dueList = append(dueList, schema.HealthDue{
+			Kind:     schema.HealthKindVetFollowUp,
+			RecordId: visit.ID,
+			DogId:    visit.DogId,
+			UserId:   visit.UserId,
+			Name:     name,
+			DueTime:  visit.FollowUpDate,
+			Overdue:  visit.FollowUpDate.Before(now),
+		})
+	}
+	sort.SliceStable(dueList, func(i, j int) bool {
+		return dueList[i].DueTime.Before(*dueList[j].DueTime)
+	})
+	return dueList, nil
+}
+
+// checkDogRole writes the error response when the user's role on the dog is below minRole
+func (h *HealthController) checkDogRole(c *gin.Context, dogId uint, userId uint, minRole string) bool {
+	ctx := c.Request.Context()
+
+	dogItem, err := h.Dep.DogModel.GetById(ctx, dogId)
+	if err != nil {
+		logger.Errorf(ctx, "get dog by id fail %+v", err)
+		mixin.ResError(c, errors.DogNotExist)
+		return false
+	}
+	role, err := dogRole(ctx, h.Dep, dogItem, userId)
+	if err != nil {
+		logger.Errorf(ctx, "get dog role fail %+v", err)
+		mixin.ResError(c, errors.DogNotExist)
+		return false
+	}
+	if !schema.RoleAtLeast(role, minRole) {
+		mixin.ResError(c, errors.DogNotExist)
+		return false
+	}
+	return true
+}
+
+// recordDogFunc dog of a health record, 0 when the record does not exist
+type recordDogFunc func(ctx context.Context, id uint) (uint, error)
+
+// vaccinationDog
+func (h *HealthController) vaccinationDog(ctx context.Context, id uint) (uint, error) {
+	item, err := h.Dep.HealthModel.GetVaccination(ctx, id)
+	if err != nil || item == nil {
+		return 0, err
+	}
+	return item.DogId, nil
+}
+
+// medicationDog
+func (h *HealthController) medicationDog(ctx context.Context, id uint) (uint, error) {
+	item, err := h.Dep.HealthModel.GetMedication(ctx, id)
+	if err != nil || item == nil {
+		return 0, err
+	}
+	return item.DogId, nil
+}
+
+// vetVisitDog
+func (h *HealthController) vetVisitDog(ctx context.Context, id uint) (uint, error) {
+	item, err := h.Dep.HealthModel.GetVetVisit(ctx, id)
+	if err != nil || item == nil {
+		return 0, err
+	}
+	return item.DogId, nil
+}
+
+// recordDog writes the error response when the record can not be loaded
+func (h *HealthController) recordDog(c *gin.Context, id uint, dogFunc recordDogFunc, failErr error) (uint, bool) {
+	ctx := c.Request.Context()
+
+	dogId, err := dogFunc(ctx, id)
+	if err != nil {
+		logger.Errorf(ctx, "get health record fail %+v", err)
+		mixin.ResError(c, failErr)
+		return 0, false
+	}
+	if dogId == 0 {
+		mixin.ResError(c, errors.HealthRecordNotExist)
+		return 0, false
+	}
+	return dogId, true
+}
+
+// deleteRecord shared by the three record kinds, any owner of the record's dog may delete it
+func (h *HealthController) deleteRecord(c *gin.Context, dogFunc recordDogFunc, deleteFunc func(ctx context.Context, id uint, dogId uint) (int64, error)) {
+	ctx := c.Request.Context()
+
+	var param types.HealthRecordEditParam
+	if err := c.ShouldBindJSON(&param); err != nil {
+		logger.Errorf(ctx, "parse query params failed %+v", err)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	dogId, ok := h.recordDog(c, param.Id, dogFunc, errors.DeleteHealthRecordFail)
+	if !ok {
+		return
+	}
+	if !h.checkDogRole(c, dogId, userId, schema.HouseholdRoleOwner) {
+		return
+	}
+	affected, err := deleteFunc(ctx, param.Id, dogId)
+	if err != nil {
+		logger.Errorf(ctx, "delete health record fail %+v", err)
+		mixin.ResError(c, errors.DeleteHealthRecordFail)
+		return
+	}
+	if affected == 0 {
+		mixin.ResError(c, errors.HealthRecordNotExist)
+		return
+	}
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+	})
+}
+
+// listRecord shared by the three record kinds, everyone who can see the dog sees all its records
+func (h *HealthController) listRecord(c *gin.Context, listFunc func(ctx context.Context, dogId uint) (interface{}, error)) {
+	ctx := c.Request.Context()
+
+	var param types.HealthRecordListParam
+	if err := c.ShouldBindQuery(&param); err != nil {
+		logger.Errorf(ctx, "parse query params failed %+v", err)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	if !h.checkDogRole(c, param.DogId, userId, schema.HouseholdRoleViewer) {
+		return
+	}
+	recordList, err := listFunc(ctx, param.DogId)
+	if err != nil {
+		logger.Errorf(ctx, "list health record fail %+v", err)
+		mixin.ResError(c, errors.ListHealthRecordFail)
+		return
+	}
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+		"data":    recordList,
+	})
+}
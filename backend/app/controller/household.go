@@ -0,0 +1,645 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yiff028/comp90018-mobile-project/backend/api/types"
+	"github.com/yiff028/comp90018-mobile-project/backend/app/mixin"
+	"github.com/yiff028/comp90018-mobile-project/backend/app/model/redismodel"
+	"github.com/yiff028/comp90018-mobile-project/backend/app/model/schema"
+	"github.com/yiff028/comp90018-mobile-project/backend/app/service"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/errors"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/logger"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/util"
+)
+
+const (
+	defaultInviteTTL    = 72
+	householdTokenBytes = 32
+)
+
+// HouseholdController
+type HouseholdController struct {
+	Dep mixin.StoreDepency
+	WS  service.WebService
+}
+
+// CreateHousehold the creator becomes its first owner
+func (h *HouseholdController) CreateHousehold(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var param types.HouseholdCreateParam
+	if err := c.ShouldBindJSON(&param); err != nil {
+		logger.Errorf(ctx, "parse query params failed %+v", err)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	household := schema.Household{
+		Name:      param.Name,
+		CreatorId: userId,
+	}
+	err := h.Dep.TranModel.ExecTrans(ctx, h.Dep.DBClient.Db, func(ctx context.Context) error {
+		if err := h.Dep.HouseholdModel.Create(ctx, &household); err != nil {
+			return err
+		}
+		return h.Dep.HouseholdModel.AddMember(ctx, &schema.HouseholdMember{
+			HouseholdId: household.ID,
+			UserId:      userId,
+			Role:        schema.HouseholdRoleOwner,
+		})
+	})
+	if err != nil {
+		logger.Errorf(ctx, "create household fail %+v", err)
+		mixin.ResError(c, errors.CreateHouseholdFail)
+		return
+	}
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+		"data":    household,
+	})
+}
+
+// ListHousehold households of the caller with their members and dogs
+func (h *HouseholdController) ListHousehold(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	householdList, err := h.Dep.HouseholdModel.ListByUserId(ctx, userId)
+	if err != nil {
+		logger.Errorf(ctx, "list household fail %+v", err)
+		mixin.ResError(c, errors.ListHouseholdFail)
+		return
+	}
+	householdIds := make([]uint, 0, len(householdList))
+	for _, household := range householdList {
+		householdIds = append(householdIds, household.ID)
+	}
+	memberList, err := h.Dep.HouseholdModel.ListMember(ctx, householdIds)
+	if err != nil {
+		logger.Errorf(ctx, "list household member fail %+v", err)
+		mixin.ResError(c, errors.ListHouseholdFail)
+		return
+	}
+	dogList, err := h.Dep.DogModel.ListByHouseholdIds(ctx, householdIds)
+	if err != nil {
+		logger.Errorf(ctx, "list household dog fail %+v", err)
+		mixin.ResError(c, errors.ListHouseholdFail)
+		return
+	}
+
+	members := map[uint]schema.HouseholdMemberList{}
+	roleMap := map[uint]string{}
+	for _, member := range memberList {
+		members[member.HouseholdId] = append(members[member.HouseholdId], member)
+		if member.UserId == userId {
+			roleMap[member.HouseholdId] = member.Role
+		}
+	}
+	dogs := map[uint]schema.DogList{}
+	for _, dogItem := range dogList {
+		dogItem.Role = dogItem.RoleOf(userId, roleMap[dogItem.HouseholdId])
+		dogs[dogItem.HouseholdId] = append(dogs[dogItem.HouseholdId], dogItem)
+	}
+
+	res := make([]gin.H, 0, len(householdList))
+	for _, household := range householdList {
+		householdDogs := dogs[household.ID]
+		if householdDogs == nil {
+			householdDogs = schema.DogList{}
+		}
+		res = append(res, gin.H{
+			"household": household,
+			"role":      roleMap[household.ID],
+			"members":   members[household.ID],
+			"dogs":      householdDogs,
+		})
+	}
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+		"data":    res,
+	})
+}
+
+// CreateInvite token for someone to join the household with the given role, only owners may invite
+func (h *HouseholdController) CreateInvite(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var param types.HouseholdInviteParam
+	if err := c.ShouldBindJSON(&param); err != nil {
+		logger.Errorf(ctx, "parse query params failed %+v", err)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	if _, ok := h.checkMemberRole(c, param.HouseholdId, userId, schema.HouseholdRoleOwner); !ok {
+		return
+	}
+
+	token, err := util.RandomToken(householdTokenBytes)
+	if err != nil {
+		logger.Errorf(ctx, "generate invite token fail %+v", errors.WithStack(err))
+		mixin.ResError(c, errors.CreateInviteFail)
+		return
+	}
+	ttl := param.TTLHours
+	if ttl == 0 {
+		ttl = defaultInviteTTL
+	}
+	invite := schema.HouseholdInvite{
+		HouseholdId: param.HouseholdId,
+		InviterId:   userId,
+		Role:        param.Role,
+		Token:       token,
+		ExpiresTime: util.GetTimePtr(time.Now().Add(time.Duration(ttl) * time.Hour)),
+	}
+	if err := h.Dep.HouseholdModel.CreateInvite(ctx, &invite); err != nil {
+		logger.Errorf(ctx, "create invite fail %+v", err)
+		mixin.ResError(c, errors.CreateInviteFail)
+		return
+	}
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+		"data":    invite,
+	})
+}
+
+// JoinHousehold accept an invite, members joining again keep their role and leave the invite unused
+func (h *HouseholdController) JoinHousehold(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var param types.HouseholdJoinParam
+	if err := c.ShouldBindJSON(&param); err != nil {
+		logger.Errorf(ctx, "parse query params failed %+v", err)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	invite, err := h.Dep.HouseholdModel.GetInviteByToken(ctx, param.Token)
+	if err != nil {
+		logger.Errorf(ctx, "get invite fail %+v", err)
+		mixin.ResError(c, errors.JoinHouseholdFail)
+		return
+	}
+	if invite == nil || !invite.IsValid(time.Now()) {
+		mixin.ResError(c, errors.InviteNotExist)
+		return
+	}
+
+	member, err := h.Dep.HouseholdModel.GetMember(ctx, invite.HouseholdId, userId)
+	if err != nil {
+		logger.Errorf(ctx, "get household member fail %+v", err)
+		mixin.ResError(c, errors.JoinHouseholdFail)
+		return
+	}
+	if member == nil {
+		member = &schema.HouseholdMember{
+			HouseholdId: invite.HouseholdId,
+			UserId:      userId,
+			Role:        invite.Role,
+		}
+		accepted := false
+		err = h.Dep.TranModel.ExecTrans(ctx, h.Dep.DBClient.Db, func(ctx context.Context) error {
+			affected, err := h.Dep.HouseholdModel.AcceptInvite(ctx, invite.ID, userId)
+			if err != nil || affected == 0 {
+				return err
+			}
+			accepted = true
+			return h.Dep.HouseholdModel.AddMember(ctx, member)
+		})
+		if err != nil {
+			logger.Errorf(ctx, "join household fail %+v", err)
+			mixin.ResError(c, errors.JoinHouseholdFail)
+			return
+		}
+		// someone else used the invite in the meantime
+		if !accepted {
+			mixin.ResError(c, errors.InviteNotExist)
+			return
+		}
+	}
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+		"data":    member,
+	})
+}
+
+// UpdateMember change the role of a member, only owners may do so and the last owner stays one
+func (h *HouseholdController) UpdateMember(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var param types.HouseholdMemberParam
+	if err := c.ShouldBindJSON(&param); err != nil {
+		logger.Errorf(ctx, "parse query params failed %+v", err)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	if param.Role == "" {
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+	if _, ok := h.checkMemberRole(c, param.HouseholdId, userId, schema.HouseholdRoleOwner); !ok {
+		return
+	}
+	member, ok := h.checkLastOwner(c, param.HouseholdId, param.UserId, param.Role, errors.UpdateMemberFail)
+	if !ok {
+		return
+	}
+
+	// a member demoted below walker stops the walks they have open with the household's dogs
+	var finishedList schema.RouteList
+	err := h.Dep.TranModel.ExecTrans(ctx, h.Dep.DBClient.Db, func(ctx context.Context) error {
+		if _, err := h.Dep.HouseholdModel.UpdateMemberRole(ctx, param.HouseholdId, param.UserId, param.Role); err != nil {
+			return err
+		}
+		if schema.RoleAtLeast(param.Role, schema.HouseholdRoleWalker) {
+			return nil
+		}
+		var err error
+		finishedList, err = h.finishMemberWalks(ctx, param.HouseholdId, param.UserId)
+		return err
+	})
+	if err != nil {
+		logger.Errorf(ctx, "update household member fail %+v", err)
+		mixin.ResError(c, errors.UpdateMemberFail)
+		return
+	}
+	member.Role = param.Role
+	notifyEndedWalks(ctx, h.Dep, finishedList)
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+		"data":    member,
+	})
+}
+
+// RemoveMember owners may remove anyone and every member may leave, the dogs of the member stop being shared
+func (h *HouseholdController) RemoveMember(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var param types.HouseholdMemberParam
+	if err := c.ShouldBindJSON(&param); err != nil {
+		logger.Errorf(ctx, "parse query params failed %+v", err)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	minRole := schema.HouseholdRoleOwner
+	if param.UserId == userId {
+		minRole = schema.HouseholdRoleViewer
+	}
+	if _, ok := h.checkMemberRole(c, param.HouseholdId, userId, minRole); !ok {
+		return
+	}
+	if _, ok := h.checkLastOwner(c, param.HouseholdId, param.UserId, "", errors.RemoveMemberFail); !ok {
+		return
+	}
+
+	var finishedList schema.RouteList
+	err := h.Dep.TranModel.ExecTrans(ctx, h.Dep.DBClient.Db, func(ctx context.Context) error {
+		if _, err := h.Dep.HouseholdModel.RemoveMember(ctx, param.HouseholdId, param.UserId); err != nil {
+			return err
+		}
+		if err := h.Dep.DogModel.LeaveHousehold(ctx, param.HouseholdId, param.UserId); err != nil {
+			return err
+		}
+		var err error
+		finishedList, err = h.finishMemberWalks(ctx, param.HouseholdId, param.UserId)
+		return err
+	})
+	if err != nil {
+		logger.Errorf(ctx, "remove household member fail %+v", err)
+		mixin.ResError(c, errors.RemoveMemberFail)
+		return
+	}
+	notifyEndedWalks(ctx, h.Dep, finishedList)
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+	})
+}
+
+// AddHouseholdDog share one of the caller's own dogs with a household the caller belongs to
+func (h *HouseholdController) AddHouseholdDog(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var param types.HouseholdDogParam
+	if err := c.ShouldBindJSON(&param); err != nil {
+		logger.Errorf(ctx, "parse query params failed %+v", err)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	if _, ok := h.checkMemberRole(c, param.HouseholdId, userId, schema.HouseholdRoleViewer); !ok {
+		return
+	}
+	dogItem, err := h.Dep.DogModel.GetById(ctx, param.DogId)
+	if err != nil {
+		logger.Errorf(ctx, "get dog fail %+v", err)
+		mixin.ResError(c, errors.UpdateHouseholdDogFail)
+		return
+	}
+	// shared owners may not hand the dog on to another household
+	if dogItem == nil || dogItem.UserId != userId {
+		mixin.ResError(c, errors.DogNotExist)
+		return
+	}
+
+	if err := h.Dep.DogModel.SetHousehold(ctx, dogItem.ID, param.HouseholdId); err != nil {
+		logger.Errorf(ctx, "set dog household fail %+v", err)
+		mixin.ResError(c, errors.UpdateHouseholdDogFail)
+		return
+	}
+	dogItem.HouseholdId = param.HouseholdId
+	dogItem.Role = schema.HouseholdRoleOwner
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+		"data":    dogItem,
+	})
+}
+
+// RemoveHouseholdDog stop sharing a dog, allowed to its owners
+func (h *HouseholdController) RemoveHouseholdDog(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var param types.HouseholdDogParam
+	if err := c.ShouldBindJSON(&param); err != nil {
+		logger.Errorf(ctx, "parse query params failed %+v", err)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	dogItem, err := h.Dep.DogModel.GetById(ctx, param.DogId)
+	if err != nil {
+		logger.Errorf(ctx, "get dog fail %+v", err)
+		mixin.ResError(c, errors.UpdateHouseholdDogFail)
+		return
+	}
+	role, err := dogRole(ctx, h.Dep, dogItem, userId)
+	if err != nil {
+		logger.Errorf(ctx, "get dog role fail %+v", err)
+		mixin.ResError(c, errors.UpdateHouseholdDogFail)
+		return
+	}
+	if !schema.RoleAtLeast(role, schema.HouseholdRoleOwner) {
+		mixin.ResError(c, errors.DogNotExist)
+		return
+	}
+
+	if dogItem.HouseholdId != 0 {
+		if err := h.Dep.DogModel.SetHousehold(ctx, dogItem.ID, 0); err != nil {
+			logger.Errorf(ctx, "set dog household fail %+v", err)
+			mixin.ResError(c, errors.UpdateHouseholdDogFail)
+			return
+		}
+	}
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+	})
+}
+
+// finishMemberWalks finish the open walks of userId with the household's dogs they may no longer walk,
+// unless a grant still lets them. Run inside the member transaction, the caller notifies after it
+func (h *HouseholdController) finishMemberWalks(ctx context.Context, householdId uint, userId uint) (schema.RouteList, error) {
+	dogList, err := h.Dep.DogModel.ListByHouseholdIds(ctx, []uint{householdId})
+	if err != nil {
+		return nil, err
+	}
+	dogIds := make([]uint, 0, len(dogList))
+	for _, dogItem := range dogList {
+		dogIds = append(dogIds, dogItem.ID)
+	}
+	return finishWalksWithout(ctx, h.Dep, userId, dogIds)
+}
+
+// notifyEndedWalks tell the walker and the dogs' owners that walks were finished because the walker lost access
+// failures only get logged
+func notifyEndedWalks(ctx context.Context, dep mixin.StoreDepency, routes schema.RouteList) {
+	for i := range routes {
+		route := &routes[i]
+		if err := fillRouteDogIds(ctx, dep, route); err != nil {
+			logger.Errorf(ctx, "list route dog fail %+v", err)
+			continue
+		}
+		names := []string{}
+		ownerIds := []uint{}
+		seen := map[uint]bool{route.UserId: true}
+		for _, dogId := range route.DogIds {
+			dogItem, err := dep.DogModel.GetById(ctx, dogId)
+			if err != nil || dogItem == nil {
+				continue
+			}
+			names = append(names, dogItem.Name)
+			dogOwnerIds, err := DogOwnerIds(ctx, dep, dogItem)
+			if err != nil {
+				logger.Errorf(ctx, "list owners of dog %d fail %+v", dogId, err)
+				continue
+			}
+			for _, ownerId := range dogOwnerIds {
+				if !seen[ownerId] {
+					seen[ownerId] = true
+					ownerIds = append(ownerIds, ownerId)
+				}
+			}
+		}
+		dogNames := strings.Join(names, " and ")
+		data := map[string]string{
+			"routeId": strconv.Itoa(int(route.ID)),
+			"status":  route.Status,
+		}
+
+		enqueueNotification(ctx, dep, redismodel.NotificationJob{
+			UserIds:  []uint{route.UserId},
+			Category: schema.NotificationCategoryDelegation,
+			Title:    fmt.Sprintf("Your walk with %s was finished", dogNames),
+			Body:     "You may no longer walk the dog",
+			RefId:    route.ID,
+			Data:     data,
+		})
+		if len(ownerIds) == 0 {
+			continue
+		}
+		enqueueNotification(ctx, dep, redismodel.NotificationJob{
+			UserIds:  ownerIds,
+			Category: schema.NotificationCategoryDelegation,
+			Title:    fmt.Sprintf("A walk with %s was finished", dogNames),
+			Body:     fmt.Sprintf("The walker may no longer walk the dog, %.2f km in %d min", route.Distance/1000, route.ActiveDuration/60),
+			RefId:    route.ID,
+			Data:     data,
+		})
+	}
+}
+
+// checkMemberRole writes the error response unless userId holds at least minRole in the household
+func (h *HouseholdController) checkMemberRole(c *gin.Context, householdId uint, userId uint, minRole string) (*schema.HouseholdMember, bool) {
+	ctx := c.Request.Context()
+
+	member, err := h.Dep.HouseholdModel.GetMember(ctx, householdId, userId)
+	if err != nil {
+		logger.Errorf(ctx, "get household member fail %+v", err)
+		mixin.ResError(c, errors.HouseholdNotExist)
+		return nil, false
+	}
+	if member == nil {
+		mixin.ResError(c, errors.HouseholdNotExist)
+		return nil, false
+	}
+	if !schema.RoleAtLeast(member.Role, minRole) {
+		mixin.ResError(c, errors.HouseholdRoleDenied)
+		return nil, false
+	}
+	return member, true
+}
+
+// checkLastOwner writes the error response when the member does not exist
+// or giving them newRole, "" for removing them, would leave the household without an owner
+func (h *HouseholdController) checkLastOwner(c *gin.Context, householdId uint, userId uint, newRole string, failErr error) (*schema.HouseholdMember, bool) {
+	ctx := c.Request.Context()
+
+	member, err := h.Dep.HouseholdModel.GetMember(ctx, householdId, userId)
+	if err != nil {
+		logger.Errorf(ctx, "get household member fail %+v", err)
+		mixin.ResError(c, failErr)
+		return nil, false
+	}
+	if member == nil {
+		mixin.ResError(c, errors.MemberNotExist)
+		return nil, false
+	}
+	if member.Role != schema.HouseholdRoleOwner || newRole == schema.HouseholdRoleOwner {
+		return member, true
+	}
+	count, err := h.Dep.HouseholdModel.CountOwner(ctx, householdId)
+	if err != nil {
+		logger.Errorf(ctx, "count household owner fail %+v", err)
+		mixin.ResError(c, failErr)
+		return nil, false
+	}
+	if count <= 1 {
+		mixin.ResError(c, errors.HouseholdOwnerMissing)
+		return nil, false
+	}
+	return member, true
+}
+
+// dogRole role of userId on the dog through its household or a current grant
+// empty when the dog is nil or the user may not access it
+func dogRole(ctx context.Context, dep mixin.StoreDepency, dogItem *schema.Dog, userId uint) (string, error) {
+	if dogItem == nil {
+		return "", nil
+	}
+	role, err := dep.HouseholdModel.GetDogRole(ctx, dogItem, userId)
+	if err != nil || role == schema.HouseholdRoleOwner {
+		return role, err
+	}
+	grantList, err := dep.DogGrantModel.ListActive(ctx, userId, []uint{dogItem.ID}, time.Now())
+	if err != nil {
+		return "", err
+	}
+	for _, grant := range grantList {
+		role = schema.MaxRole(role, grant.Role)
+	}
+	return role, nil
+}
+
+// DogOwnerIds the dog's creator and the owners of its household, grants never make an owner
+func DogOwnerIds(ctx context.Context, dep mixin.StoreDepency, dogItem *schema.Dog) ([]uint, error) {
+	ownerIds := []uint{dogItem.UserId}
+	if dogItem.HouseholdId == 0 {
+		return ownerIds, nil
+	}
+	memberList, err := dep.HouseholdModel.ListMember(ctx, []uint{dogItem.HouseholdId})
+	if err != nil {
+		return nil, err
+	}
+	for _, member := range memberList {
+		if member.Role == schema.HouseholdRoleOwner && member.UserId != dogItem.UserId {
+			ownerIds = append(ownerIds, member.UserId)
+		}
+	}
+	return ownerIds, nil
+}
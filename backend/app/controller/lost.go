@@ -0,0 +1,379 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yiff028/comp90018-mobile-project/backend/api/types"
+	"github.com/yiff028/comp90018-mobile-project/backend/app/mixin"
+	"github.com/yiff028/comp90018-mobile-project/backend/app/model/redismodel"
+	"github.com/yiff028/comp90018-mobile-project/backend/app/model/schema"
+	"github.com/yiff028/comp90018-mobile-project/backend/app/service"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/errors"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/logger"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/util"
+)
+
+const (
+	// lostBroadcastRadius metres around the last seen location
+	lostBroadcastRadius = 3000
+	// lostBroadcastWindow how recently a user's dog must have been around
+	lostBroadcastWindow  = 24 * time.Hour
+	defaultLostAlertArea = 5000
+	maxNearbyLostAlert   = 100
+)
+
+// LostController
+type LostController struct {
+	Dep mixin.StoreDepency
+	WS  service.WebService
+}
+
+// ReportLost flag the dog as lost and open an alert at its last seen location
+func (l *LostController) ReportLost(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var param types.LostReportParam
+	if err := c.ShouldBindJSON(&param); err != nil {
+		logger.Errorf(ctx, "parse query params failed %+v", err)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	dogItem, err := l.Dep.DogModel.GetById(ctx, param.DogId)
+	if err != nil {
+		logger.Errorf(ctx, "get dog by id fail %+v", err)
+		mixin.ResError(c, errors.DogNotExist)
+		return
+	}
+	role, err := dogRole(ctx, l.Dep, dogItem, userId)
+	if err != nil {
+		logger.Errorf(ctx, "get dog role fail %+v", err)
+		mixin.ResError(c, errors.ReportLostFail)
+		return
+	}
+	if !schema.RoleAtLeast(role, schema.HouseholdRoleOwner) {
+		mixin.ResError(c, errors.DogNotExist)
+		return
+	}
+
+	alert := schema.LostAlert{
+		DogId:        dogItem.ID,
+		UserId:       userId,
+		Description:  param.Description,
+		PhotoUrl:     param.PhotoUrl,
+		LastSeenTime: param.LastSeenTime,
+		Status:       schema.LostAlertStatusOpen,
+	}
+	if alert.PhotoUrl == "" {
+		alert.PhotoUrl = dogItem.Img
+	}
+	switch {
+	case param.Latitude != nil && param.Longitude != nil:
+		alert.Latitude, alert.Longitude = *param.Latitude, *param.Longitude
+	case dogItem.Latitude != nil && dogItem.Longitude != nil:
+		alert.Latitude, alert.Longitude = *dogItem.Latitude, *dogItem.Longitude
+		if alert.LastSeenTime == nil {
+			alert.LastSeenTime = dogItem.LocationUpdatedTime
+		}
+	default:
+		mixin.ResError(c, errors.LostLocationUnknown)
+		return
+	}
+	if alert.LastSeenTime == nil {
+		alert.LastSeenTime = util.GetTimePtr(time.Now())
+	}
+
+	alreadyLost := false
+	err = l.Dep.TranModel.ExecTrans(ctx, l.Dep.DBClient.Db, func(ctx context.Context) error {
+		openAlert, err := l.Dep.LostAlertModel.GetOpenByDogId(ctx, dogItem.ID)
+		if err != nil {
+			return err
+		}
+		if openAlert != nil {
+			alreadyLost = true
+			return nil
+		}
+		if err := l.Dep.LostAlertModel.Create(ctx, &alert); err != nil {
+			return err
+		}
+		return l.Dep.DogModel.SetLost(ctx, dogItem.ID, true, alert.LastSeenTime)
+	})
+	if err != nil {
+		logger.Errorf(ctx, "report lost fail %+v", err)
+		mixin.ResError(c, errors.ReportLostFail)
+		return
+	}
+	if alreadyLost {
+		mixin.ResError(c, errors.DogAlreadyLost)
+		return
+	}
+
+	nearbyUserIds, err := l.listNearbyUserIds(ctx, &alert)
+	if err != nil {
+		logger.Errorf(ctx, "list nearby user fail %+v", err)
+	}
+	enqueueNotification(ctx, l.Dep, redismodel.NotificationJob{
+		UserIds:  nearbyUserIds,
+		Category: schema.NotificationCategoryLostAlert,
+		Title:    fmt.Sprintf("Lost dog nearby: %s", dogItem.Name),
+		Body:     lostAlertBody(dogItem, &alert),
+		RefId:    alert.ID,
+		Data:     map[string]string{"alertId": strconv.Itoa(int(alert.ID))},
+	})
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+		"data": gin.H{
+			"alert":       alert,
+			"nearbyUsers": len(nearbyUserIds),
+		},
+	})
+}
+
+// listNearbyUserIds users whose dogs were recently around the last seen location, the owner excluded
+func (l *LostController) listNearbyUserIds(ctx context.Context, alert *schema.LostAlert) ([]uint, error) {
+	userIds, err := l.Dep.DogModel.ListOwnerIdsNearby(ctx, alert.Latitude, alert.Longitude, lostBroadcastRadius, time.Now().Add(-lostBroadcastWindow))
+	if err != nil {
+		return nil, err
+	}
+	nearby := make([]uint, 0, len(userIds))
+	for _, id := range userIds {
+		if id != alert.UserId {
+			nearby = append(nearby, id)
+		}
+	}
+	return nearby, nil
+}
+
+// lostAlertBody
+func lostAlertBody(dogItem *schema.Dog, alert *schema.LostAlert) string {
+	body := fmt.Sprintf("%s was last seen at %s.", dogItem.Name, alert.LastSeenTime.Format("2006-01-02 15:04"))
+	if dogItem.Breed != "" {
+		body = fmt.Sprintf("%s (%s) was last seen at %s.", dogItem.Name, dogItem.Breed, alert.LastSeenTime.Format("2006-01-02 15:04"))
+	}
+	if alert.Description != "" {
+		body += " " + alert.Description
+	}
+	return body
+}
+
+// ResolveAlert the dog was found
+func (l *LostController) ResolveAlert(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var param types.LostAlertEditParam
+	if err := c.ShouldBindJSON(&param); err != nil {
+		logger.Errorf(ctx, "parse query params failed %+v", err)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	alert, err := l.Dep.LostAlertModel.GetById(ctx, param.AlertId)
+	if err != nil {
+		logger.Errorf(ctx, "get lost alert fail %+v", err)
+		mixin.ResError(c, errors.LostAlertNotExist)
+		return
+	}
+	if alert == nil || alert.UserId != userId {
+		mixin.ResError(c, errors.LostAlertNotExist)
+		return
+	}
+
+	var affected int64
+	err = l.Dep.TranModel.ExecTrans(ctx, l.Dep.DBClient.Db, func(ctx context.Context) error {
+		affected, err = l.Dep.LostAlertModel.Resolve(ctx, alert.ID, userId)
+		if err != nil || affected == 0 {
+			return err
+		}
+		return l.Dep.DogModel.SetLost(ctx, alert.DogId, false, nil)
+	})
+	if err != nil {
+		logger.Errorf(ctx, "resolve lost alert fail %+v", err)
+		mixin.ResError(c, errors.ResolveLostAlertFail)
+		return
+	}
+	if affected == 0 {
+		mixin.ResError(c, errors.LostAlertNotExist)
+		return
+	}
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+	})
+}
+
+// ListNearbyAlert open alerts around the caller, nearest first
+func (l *LostController) ListNearbyAlert(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var param types.NearbyLostAlertParam
+	if err := c.ShouldBindQuery(&param); err != nil {
+		logger.Errorf(ctx, "parse query params failed %+v", err)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	radius := param.Radius
+	if radius == 0 {
+		radius = defaultLostAlertArea
+	}
+	alertList, err := l.Dep.LostAlertModel.ListNearbyOpen(ctx, *param.Lat, *param.Lon, radius, maxNearbyLostAlert)
+	if err != nil {
+		logger.Errorf(ctx, "list nearby lost alert fail %+v", err)
+		mixin.ResError(c, errors.ListLostAlertFail)
+		return
+	}
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+		"data":    alertList,
+	})
+}
+
+// ReportSighting anyone may report where they saw a lost dog
+func (l *LostController) ReportSighting(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var param types.LostSightingParam
+	if err := c.ShouldBindJSON(&param); err != nil {
+		logger.Errorf(ctx, "parse query params failed %+v", err)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	alert, err := l.Dep.LostAlertModel.GetById(ctx, param.AlertId)
+	if err != nil {
+		logger.Errorf(ctx, "get lost alert fail %+v", err)
+		mixin.ResError(c, errors.LostAlertNotExist)
+		return
+	}
+	if alert == nil || alert.Status != schema.LostAlertStatusOpen {
+		mixin.ResError(c, errors.LostAlertNotExist)
+		return
+	}
+
+	sighting := schema.LostSighting{
+		AlertId:   alert.ID,
+		UserId:    userId,
+		Latitude:  *param.Latitude,
+		Longitude: *param.Longitude,
+		Note:      param.Note,
+		PhotoUrl:  param.PhotoUrl,
+		SeenTime:  param.SeenTime,
+	}
+	if sighting.SeenTime == nil || sighting.SeenTime.After(time.Now()) {
+		sighting.SeenTime = util.GetTimePtr(time.Now())
+	}
+	if err := l.Dep.LostAlertModel.CreateSighting(ctx, &sighting); err != nil {
+		logger.Errorf(ctx, "create sighting fail %+v", err)
+		mixin.ResError(c, errors.CreateSightingFail)
+		return
+	}
+	if alert.UserId != userId {
+		body := fmt.Sprintf("Seen at %s", sighting.SeenTime.Format("15:04"))
+		if sighting.Note != "" {
+			body += ": " + sighting.Note
+		}
+		enqueueNotification(ctx, l.Dep, redismodel.NotificationJob{
+			UserIds:  []uint{alert.UserId},
+			Category: schema.NotificationCategoryLostSighting,
+			Title:    "New sighting of your dog",
+			Body:     body,
+			RefId:    sighting.ID,
+			Data:     map[string]string{"alertId": strconv.Itoa(int(alert.ID))},
+		})
+	}
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+		"data":    sighting,
+	})
+}
+
+// ListSighting only the owner sees who reported what
+func (l *LostController) ListSighting(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var param types.LostSightingListParam
+	if err := c.ShouldBindQuery(&param); err != nil {
+		logger.Errorf(ctx, "parse query params failed %+v", err)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	alert, err := l.Dep.LostAlertModel.GetById(ctx, param.AlertId)
+	if err != nil {
+		logger.Errorf(ctx, "get lost alert fail %+v", err)
+		mixin.ResError(c, errors.LostAlertNotExist)
+		return
+	}
+	if alert == nil || alert.UserId != userId {
+		mixin.ResError(c, errors.LostAlertNotExist)
+		return
+	}
+
+	sightingList, err := l.Dep.LostAlertModel.ListSighting(ctx, alert.ID)
+	if err != nil {
+		logger.Errorf(ctx, "list sighting fail %+v", err)
+		mixin.ResError(c, errors.ListSightingFail)
+		return
+	}
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+		"data": gin.H{
+			"alert":     alert,
+			"sightings": sightingList,
+		},
+	})
+}
@@ -0,0 +1,270 @@
+package controller
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yiff028/comp90018-mobile-project/backend/api/types"
+	"github.com/yiff028/comp90018-mobile-project/backend/app/mixin"
+	"github.com/yiff028/comp90018-mobile-project/backend/app/model/redismodel"
+	"github.com/yiff028/comp90018-mobile-project/backend/app/model/schema"
+	"github.com/yiff028/comp90018-mobile-project/backend/app/service"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/errors"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/logger"
+)
+
+const defaultNotificationLimit = 20
+
+// NotificationController
+type NotificationController struct {
+	Dep mixin.StoreDepency
+	WS  service.WebService
+}
+
+// ListNotification inbox, newest first
+func (n *NotificationController) ListNotification(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var param types.NotificationListParam
+	if err := c.ShouldBindQuery(&param); err != nil {
+		logger.Errorf(ctx, "parse query params failed %+v", err)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	if param.Limit == 0 {
+		param.Limit = defaultNotificationLimit
+	}
+	page := schema.PaginationParam{
+		Pagination: true,
+		Offset:     param.Offset,
+		Limit:      param.Limit,
+	}
+	notificationList, pageResult, err := n.Dep.NotificationModel.List(ctx, userId, param.UnreadOnly, page)
+	if err != nil {
+		logger.Errorf(ctx, "list notification fail %+v", err)
+		mixin.ResError(c, errors.ListNotificationFail)
+		return
+	}
+
+	mixin.ResPage(c, notificationList, &mixin.PaginationResult{
+		Total:  pageResult.Total,
+		Offset: pageResult.Offset,
+		Limit:  pageResult.Limit,
+	})
+}
+
+// CountUnread
+func (n *NotificationController) CountUnread(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	count, err := n.Dep.NotificationModel.CountUnread(ctx, userId)
+	if err != nil {
+		logger.Errorf(ctx, "count unread notification fail %+v", err)
+		mixin.ResError(c, errors.ListNotificationFail)
+		return
+	}
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+		"data":    count,
+	})
+}
+
+// MarkRead
+func (n *NotificationController) MarkRead(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var param types.NotificationReadParam
+	if err := c.ShouldBindJSON(&param); err != nil {
+		logger.Errorf(ctx, "parse query params failed %+v", err)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	affected, err := n.Dep.NotificationModel.MarkRead(ctx, userId, param.Ids)
+	if err != nil {
+		logger.Errorf(ctx, "mark notification read fail %+v", err)
+		mixin.ResError(c, errors.MarkNotificationReadFail)
+		return
+	}
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+		"data":    affected,
+	})
+}
+
+// ListPreference
+func (n *NotificationController) ListPreference(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	preferenceList, err := n.Dep.NotificationModel.ListPreference(ctx, userId)
+	if err != nil {
+		logger.Errorf(ctx, "list notification preference fail %+v", err)
+		mixin.ResError(c, errors.ListNotificationPrefFail)
+		return
+	}
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+		"data":    preferenceList,
+	})
+}
+
+// UpdatePreference
+func (n *NotificationController) UpdatePreference(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var param types.NotificationPreferenceParam
+	if err := c.ShouldBindJSON(&param); err != nil {
+		logger.Errorf(ctx, "parse query params failed %+v", err)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+	if !schema.ValidNotificationCategory(param.Category) {
+		logger.Errorf(ctx, "unknown notification category %s", param.Category)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	preference := schema.NotificationPreference{
+		UserId:   userId,
+		Category: param.Category,
+		InApp:    param.InApp,
+		Email:    param.Email,
+		Push:     param.Push,
+	}
+	if err := n.Dep.NotificationModel.SavePreference(ctx, &preference); err != nil {
+		logger.Errorf(ctx, "update notification preference fail %+v", err)
+		mixin.ResError(c, errors.UpdateNotificationPrefFail)
+		return
+	}
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+		"data":    preference,
+	})
+}
+
+// RegisterDevice
+func (n *NotificationController) RegisterDevice(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var param types.PushDeviceParam
+	if err := c.ShouldBindJSON(&param); err != nil {
+		logger.Errorf(ctx, "parse query params failed %+v", err)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	device := schema.PushDevice{
+		UserId:   userId,
+		Token:    param.Token,
+		Platform: param.Platform,
+	}
+	if err := n.Dep.NotificationModel.SaveDevice(ctx, &device); err != nil {
+		logger.Errorf(ctx, "register device fail %+v", err)
+		mixin.ResError(c, errors.RegisterDeviceFail)
+		return
+	}
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+	})
+}
+
+// UnregisterDevice e.g. on logout
+func (n *NotificationController) UnregisterDevice(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var param types.PushDeviceParam
+	if err := c.ShouldBindJSON(&param); err != nil {
+		logger.Errorf(ctx, "parse query params failed %+v", err)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	if err := n.Dep.NotificationModel.DeleteDevice(ctx, userId, param.Token); err != nil {
+		logger.Errorf(ctx, "unregister device fail %+v", err)
+		mixin.ResError(c, errors.UnregisterDeviceFail)
+		return
+	}
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+	})
+}
+
+// enqueueNotification delivery happens in the notification workers, failures only get logged
+func enqueueNotification(ctx context.Context, dep mixin.StoreDepency, job redismodel.NotificationJob) {
+	if len(job.UserIds) == 0 {
+		return
+	}
+	if err := dep.NotificationQueue.Enqueue(job); err != nil {
+		logger.Errorf(ctx, "enqueue notification fail %+v", errors.WithStack(err))
+	}
+}
@@ -0,0 +1,124 @@
+package controller
+
+import (
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yiff028/comp90018-mobile-project/backend/api/types"
+	"github.com/yiff028/comp90018-mobile-project/backend/app/mixin"
+	"github.com/yiff028/comp90018-mobile-project/backend/app/model/schema"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/errors"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/logger"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/util"
+)
+
+const (
+	defaultPlaymateRadius = 2000
+	defaultPlaymateLimit  = 10
+)
+
+// ListPlaymate nearby active dogs ranked by how well they would play with the dog
+func (d *DogController) ListPlaymate(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var param types.PlaymateParam
+	if err := c.ShouldBindQuery(&param); err != nil {
+		logger.Errorf(ctx, "parse query params failed %+v", err)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	dogItem, err := d.Dep.DogModel.GetById(ctx, param.DogId)
+	if err != nil {
+		logger.Errorf(ctx, "get dog fail %+v", err)
+		mixin.ResError(c, errors.ListPlaymateFail)
+		return
+	}
+	role, err := dogRole(ctx, d.Dep, dogItem, userId)
+	if err != nil {
+		logger.Errorf(ctx, "get dog role fail %+v", err)
+		mixin.ResError(c, errors.ListPlaymateFail)
+		return
+	}
+	if !schema.RoleAtLeast(role, schema.HouseholdRoleViewer) {
+		mixin.ResError(c, errors.DogNotExist)
+		return
+	}
+
+	latitude, longitude := param.Latitude, param.Longitude
+	if latitude == nil || longitude == nil {
+		latitude, longitude = dogItem.Latitude, dogItem.Longitude
+	}
+	if latitude == nil || longitude == nil {
+		mixin.ResError(c, errors.DogLocationUnknown)
+		return
+	}
+	if param.Radius <= 0 {
+		param.Radius = defaultPlaymateRadius
+	}
+	if param.Limit <= 0 {
+		param.Limit = defaultPlaymateLimit
+	}
+
+	box := util.NewBoundingBox(*latitude, *longitude, param.Radius)
+	since := time.Now().Add(-dogActiveWindow(param.WithinMinutes))
+	candidates, err := d.Dep.DogModel.ListNearby(ctx, userId, box, *latitude, *longitude, param.Radius, since)
+	if err != nil {
+		logger.Errorf(ctx, "list nearby dog fail %+v", err)
+		mixin.ResError(c, errors.ListPlaymateFail)
+		return
+	}
+	// matched on the masked locations so the radius cannot narrow down a fuzzed dog
+	nearbyList, err := maskNearbyDogs(ctx, d.Dep, userId, candidates, box, *latitude, *longitude, param.Radius)
+	if err != nil {
+		logger.Errorf(ctx, "list location policy fail %+v", err)
+		mixin.ResError(c, errors.ListLocationPolicyFail)
+		return
+	}
+
+	// the caller's own and shared dogs already know each other
+	ownList, err := d.Dep.DogModel.ListByRole(ctx, userId, schema.HouseholdRoleViewer)
+	if err != nil {
+		logger.Errorf(ctx, "list dog fail %+v", err)
+		mixin.ResError(c, errors.ListPlaymateFail)
+		return
+	}
+	ownIds := make(map[uint]bool, len(ownList))
+	for _, own := range ownList {
+		ownIds[own.ID] = true
+	}
+
+	matchList := make([]schema.PlaymateMatch, 0, len(nearbyList))
+	for _, item := range nearbyList {
+		if item.ID == dogItem.ID || ownIds[item.ID] || item.IsLost {
+			continue
+		}
+		matchList = append(matchList, schema.MatchPlaymate(*dogItem, item))
+	}
+	// best match first, the nearer dog wins a tie
+	sort.SliceStable(matchList, func(i, j int) bool {
+		if matchList[i].Score != matchList[j].Score {
+			return matchList[i].Score > matchList[j].Score
+		}
+		return matchList[i].Dog.Distance < matchList[j].Dog.Distance
+	})
+	if len(matchList) > param.Limit {
+		matchList = matchList[:param.Limit]
+	}
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+		"data":    matchList,
+	})
+}
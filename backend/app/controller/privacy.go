@@ -0,0 +1,380 @@
+package controller
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yiff028/comp90018-mobile-project/backend/api/types"
+	"github.com/yiff028/comp90018-mobile-project/backend/app/mixin"
+	"github.com/yiff028/comp90018-mobile-project/backend/app/model/schema"
+	"github.com/yiff028/comp90018-mobile-project/backend/app/service"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/errors"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/logger"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/util"
+)
+
+// PrivacyController
+type PrivacyController struct {
+	Dep mixin.StoreDepency
+	WS  service.WebService
+}
+
+// GetPrivacy
+func (p *PrivacyController) GetPrivacy(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	privacy, err := p.Dep.PrivacyModel.Get(ctx, userId)
+	if err != nil {
+		logger.Errorf(ctx, "get privacy fail %+v", err)
+		mixin.ResError(c, errors.GetPrivacyFail)
+		return
+	}
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+		"data":    privacy,
+	})
+}
+
+// UpdatePrivacy
+func (p *PrivacyController) UpdatePrivacy(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var param types.PrivacyUpdateParam
+	if err := c.ShouldBindJSON(&param); err != nil {
+		logger.Errorf(ctx, "parse query params failed %+v", err)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	privacy := schema.UserPrivacy{
+		UserId:     userId,
+		Visibility: param.Visibility,
+		FuzzMetres: param.FuzzMetres,
+	}
+	if err := p.Dep.PrivacyModel.Save(ctx, &privacy); err != nil {
+		logger.Errorf(ctx, "update privacy fail %+v", err)
+		mixin.ResError(c, errors.UpdatePrivacyFail)
+		return
+	}
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+		"data":    privacy,
+	})
+}
+
+// ListHomeZone
+func (p *PrivacyController) ListHomeZone(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	zoneList, err := p.Dep.PrivacyModel.ListHomeZone(ctx, userId)
+	if err != nil {
+		logger.Errorf(ctx, "list home zone fail %+v", err)
+		mixin.ResError(c, errors.ListHomeZoneFail)
+		return
+	}
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+		"data":    zoneList,
+	})
+}
+
+// CreateHomeZone
+func (p *PrivacyController) CreateHomeZone(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var param types.HomeZoneCreateParam
+	if err := c.ShouldBindJSON(&param); err != nil {
+		logger.Errorf(ctx, "parse query params failed %+v", err)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	zone := schema.HomeZone{
+		UserId:    userId,
+		Name:      param.Name,
+		Latitude:  *param.Latitude,
+		Longitude: *param.Longitude,
+		Radius:    param.Radius,
+	}
+	if err := p.Dep.PrivacyModel.CreateHomeZone(ctx, &zone); err != nil {
+		logger.Errorf(ctx, "create home zone fail %+v", err)
+		mixin.ResError(c, errors.CreateHomeZoneFail)
+		return
+	}
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+		"data":    zone,
+	})
+}
+
+// DeleteHomeZone
+func (p *PrivacyController) DeleteHomeZone(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var param types.HomeZoneEditParam
+	if err := c.ShouldBindJSON(&param); err != nil {
+		logger.Errorf(ctx, "parse query params failed %+v", err)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	if err := p.Dep.PrivacyModel.DeleteHomeZone(ctx, param.ZoneId, userId); err != nil {
+		logger.Errorf(ctx, "delete home zone fail %+v", err)
+		mixin.ResError(c, errors.DeleteHomeZoneFail)
+		return
+	}
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+	})
+}
+
+// ListFriend
+func (p *PrivacyController) ListFriend(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	friendList, err := p.Dep.FriendModel.List(ctx, userId)
+	if err != nil {
+		logger.Errorf(ctx, "list friend fail %+v", err)
+		mixin.ResError(c, errors.ListFriendFail)
+		return
+	}
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+		"data":    friendList,
+	})
+}
+
+// AddFriend let another user see what is shared with friends
+func (p *PrivacyController) AddFriend(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var param types.FriendEditParam
+	if err := c.ShouldBindJSON(&param); err != nil {
+		logger.Errorf(ctx, "parse query params failed %+v", err)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	friend, err := p.Dep.UserModel.GetById(ctx, int(param.FriendId))
+	if err != nil {
+		logger.Errorf(ctx, "get user fail %+v", err)
+		mixin.ResError(c, errors.AddFriendFail)
+		return
+	}
+	if friend == nil || param.FriendId == userId {
+		logger.Errorf(ctx, "friend %d illegal", param.FriendId)
+		mixin.ResError(c, errors.UserNotExist)
+		return
+	}
+
+	if err := p.Dep.FriendModel.Add(ctx, userId, param.FriendId); err != nil {
+		logger.Errorf(ctx, "add friend fail %+v", err)
+		mixin.ResError(c, errors.AddFriendFail)
+		return
+	}
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+	})
+}
+
+// RemoveFriend
+func (p *PrivacyController) RemoveFriend(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var param types.FriendEditParam
+	if err := c.ShouldBindJSON(&param); err != nil {
+		logger.Errorf(ctx, "parse query params failed %+v", err)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	if err := p.Dep.FriendModel.Remove(ctx, userId, param.FriendId); err != nil {
+		logger.Errorf(ctx, "remove friend fail %+v", err)
+		mixin.ResError(c, errors.RemoveFriendFail)
+		return
+	}
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+	})
+}
+
+// listLocationPolicy policies of the dog owners as seen by viewerId
+func listLocationPolicy(ctx context.Context, dep mixin.StoreDepency, viewerId uint, dogs []*schema.Dog) (map[uint]*schema.LocationPolicy, error) {
+	ownerIds := []uint{}
+	seen := map[uint]bool{}
+	for _, dogItem := range dogs {
+		if !seen[dogItem.UserId] {
+			seen[dogItem.UserId] = true
+			ownerIds = append(ownerIds, dogItem.UserId)
+		}
+	}
+	return dep.PrivacyModel.ListPolicy(ctx, viewerId, ownerIds)
+}
+
+// listDogRoles role of viewerId on each of the dogs keyed by dog id, missing when they hold none
+func listDogRoles(ctx context.Context, dep mixin.StoreDepency, viewerId uint, dogs []*schema.Dog) (map[uint]string, error) {
+	roleMap := make(map[uint]string, len(dogs))
+	if len(dogs) == 0 {
+		return roleMap, nil
+	}
+	memberRoles, err := dep.HouseholdModel.ListRoleByUserId(ctx, viewerId)
+	if err != nil {
+		return nil, err
+	}
+	dogIds := make([]uint, 0, len(dogs))
+	for _, dogItem := range dogs {
+		dogIds = append(dogIds, dogItem.ID)
+		if role := dogItem.RoleOf(viewerId, memberRoles[dogItem.HouseholdId]); role != "" {
+			roleMap[dogItem.ID] = role
+		}
+	}
+	grantList, err := dep.DogGrantModel.ListActive(ctx, viewerId, dogIds, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	for _, grant := range grantList {
+		roleMap[grant.DogId] = schema.MaxRole(roleMap[grant.DogId], grant.Role)
+	}
+	return roleMap, nil
+}
+
+// maskDogLocation fuzz the dog location in place, false if viewerId must not see it
+func maskDogLocation(policyMap map[uint]*schema.LocationPolicy, roleMap map[uint]string, viewerId uint, dogItem *schema.Dog) bool {
+	if dogItem.Latitude == nil || dogItem.Longitude == nil {
+		return false
+	}
+	policy, ok := policyMap[dogItem.UserId]
+	if !ok {
+		return false
+	}
+	lat, lon, ok := policy.Mask(viewerId, roleMap[dogItem.ID], *dogItem.Latitude, *dogItem.Longitude)
+	if !ok {
+		return false
+	}
+	dogItem.Latitude = &lat
+	dogItem.Longitude = &lon
+	return true
+}
+
+// maskNearbyDogs mask the candidates as seen by viewerId, keeping the ones whose masked location is inside box
+// and within radius metres of the point, nearest first by the masked location
+func maskNearbyDogs(ctx context.Context, dep mixin.StoreDepency, viewerId uint, candidates schema.DogList, box util.BoundingBox, latitude, longitude, radius float64) (schema.DogWithDistanceList, error) {
+	dogs := make([]*schema.Dog, 0, len(candidates))
+	for i := range candidates {
+		dogs = append(dogs, &candidates[i])
+	}
+	policyMap, err := listLocationPolicy(ctx, dep, viewerId, dogs)
+	if err != nil {
+		return nil, err
+	}
+	roleMap, err := listDogRoles(ctx, dep, viewerId, dogs)
+	if err != nil {
+		return nil, err
+	}
+
+	// dogs inside their owner's home zone are left out
+	dogList := make(schema.DogWithDistanceList, 0, len(candidates))
+	for _, dogItem := range candidates {
+		if !maskDogLocation(policyMap, roleMap, viewerId, &dogItem) {
+			continue
+		}
+		if !box.Contains(*dogItem.Latitude, *dogItem.Longitude) {
+			continue
+		}
+		distance := util.Haversine(latitude, longitude, *dogItem.Latitude, *dogItem.Longitude)
+		if radius > 0 && distance > radius {
+			continue
+		}
+		dogList = append(dogList, schema.DogWithDistance{Dog: dogItem, Distance: distance})
+	}
+	sort.SliceStable(dogList, func(i, j int) bool {
+		if dogList[i].Distance != dogList[j].Distance {
+			return dogList[i].Distance < dogList[j].Distance
+		}
+		return dogList[i].ID < dogList[j].ID
+	})
+	return dogList, nil
+}
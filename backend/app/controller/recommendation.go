@@ -0,0 +1,95 @@
+package controller
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yiff028/comp90018-mobile-project/backend/api/types"
+	"github.com/yiff028/comp90018-mobile-project/backend/app/mixin"
+	"github.com/yiff028/comp90018-mobile-project/backend/app/model/schema"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/errors"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/logger"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/util"
+)
+
+const kelvinOffset = 273.15
+
+// GetDogRecommendation suggested walk length and intensity for today
+func (d *DogController) GetDogRecommendation(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var param types.DogRecommendationParam
+	if err := c.ShouldBindQuery(&param); err != nil {
+		logger.Errorf(ctx, "parse query params failed %+v", err)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	dogItem, err := d.Dep.DogModel.GetById(ctx, param.DogId)
+	if err != nil {
+		logger.Errorf(ctx, "get dog fail %+v", err)
+		mixin.ResError(c, errors.GetDogRecommendationFail)
+		return
+	}
+	role, err := dogRole(ctx, d.Dep, dogItem, userId)
+	if err != nil {
+		logger.Errorf(ctx, "get dog role fail %+v", err)
+		mixin.ResError(c, errors.GetDogRecommendationFail)
+		return
+	}
+	if !schema.RoleAtLeast(role, schema.HouseholdRoleViewer) {
+		mixin.ResError(c, errors.DogNotExist)
+		return
+	}
+
+	latitude, longitude := param.Latitude, param.Longitude
+	if latitude == nil || longitude == nil {
+		latitude, longitude = dogItem.Latitude, dogItem.Longitude
+	}
+	var weather *schema.WeatherCondition
+	if latitude != nil && longitude != nil {
+		// the recommendation still works without weather
+		weather, err = d.currentWeather(ctx, *longitude, *latitude)
+		if err != nil {
+			logger.Warnf(ctx, "get weather for recommendation fail %+v", err)
+		}
+	}
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+		"data":    schema.RecommendExercise(*dogItem, weather),
+	})
+}
+
+// currentWeather
+func (d *DogController) currentWeather(ctx context.Context, lon float64, lat float64) (*schema.WeatherCondition, error) {
+	weatherBytes, err := loadWeather(ctx, d.Dep, d.WS, lon, lat)
+	if err != nil {
+		return nil, err
+	}
+	var res types.OneCallWeather
+	if err := util.JSONUnmarshal(weatherBytes, &res); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	weather := schema.WeatherCondition{
+		TempC:     res.Current.Temp - kelvinOffset,
+		Humidity:  res.Current.Humidity,
+		WindSpeed: res.Current.WindSpeed,
+		Uvi:       res.Current.Uvi,
+	}
+	if len(res.Current.Weather) > 0 {
+		weather.Main = res.Current.Weather[0].Main
+	}
+	return &weather, nil
+}
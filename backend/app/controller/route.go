@@ -1,17 +1,30 @@
 package controller
 
 import (
+	"bytes"
 	"context"
+	"fmt"
+	"mime/multipart"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/yiff028/comp90018-mobile-project/backend/api/types"
+	"github.com/yiff028/comp90018-mobile-project/backend/api/types/keys"
 	"github.com/yiff028/comp90018-mobile-project/backend/app/mixin"
 	"github.com/yiff028/comp90018-mobile-project/backend/app/model/schema"
 	"github.com/yiff028/comp90018-mobile-project/backend/app/service"
 	"github.com/yiff028/comp90018-mobile-project/backend/pkg/errors"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/geoformat"
 	"github.com/yiff028/comp90018-mobile-project/backend/pkg/logger"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/util"
 )
 
+// maxRouteDogs most dogs one walk can take
+const maxRouteDogs = 6
+
 // RouteController
 type RouteController struct {
 	Dep mixin.StoreDepency
@@ -37,37 +50,224 @@ func (r *RouteController) CreateRoute(c *gin.Context) {
 		return
 	}
 
+	// dogIds takes several dogs on one walk, dogId alone still works
+	dogIds := []uint{}
+	seen := map[uint]bool{}
+	for _, dogId := range append([]uint{param.DogId}, param.DogIds...) {
+		if dogId != 0 && !seen[dogId] {
+			seen[dogId] = true
+			dogIds = append(dogIds, dogId)
+		}
+	}
+	if len(dogIds) == 0 || len(dogIds) > maxRouteDogs {
+		mixin.ResError(c, errors.RouteDogIllegal)
+		return
+	}
+
 	//check dog validity
-	dogList, err := r.Dep.DogModel.List(ctx, userId)
+	exist, err := r.userOwnsDogs(ctx, userId, dogIds)
 	if err != nil {
 		logger.Errorf(ctx, "list dog fail %+v", err)
 		mixin.ResError(c, errors.ListDogFail)
 		return
 	}
-	exist := false
-	for _, dogItem := range dogList {
-		if dogItem.ID == param.DogId {
-			exist = true
-		}
-	}
 
 	if !exist {
 		mixin.ResError(c, errors.DogNotExist)
 		return
 	}
 
-	param.UserId = userId
-	err = r.Dep.RouteModel.Create(ctx, &param)
+	// a new walk always starts active with empty stats
+	route := schema.Route{
+		UserId: userId,
+		DogId:  dogIds[0],
+		DogIds: dogIds,
+		Status: schema.RouteStatusActive,
+	}
+	err = r.Dep.TranModel.ExecTrans(ctx, r.Dep.DBClient.Db, func(ctx context.Context) error {
+		if err := r.Dep.RouteModel.Create(ctx, &route); err != nil {
+			return err
+		}
+		return r.Dep.RouteDogModel.CreateBatch(ctx, route.ID, dogIds)
+	})
 	if err != nil {
 		logger.Errorf(ctx, "create route fail %+v", err)
 		mixin.ResError(c, errors.CreateRouteFail)
 		return
 	}
+	NotifyDelegatedWalk(ctx, r.Dep, &route)
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+		"data":    route,
+	})
+}
+
+// userOwnsDog
+func (r *RouteController) userOwnsDog(ctx context.Context, userId uint, dogId uint) (bool, error) {
+	return r.userOwnsDogs(ctx, userId, []uint{dogId})
+}
+
+// userOwnsDogs every one of the dogs may be walked by the user
+func (r *RouteController) userOwnsDogs(ctx context.Context, userId uint, dogIds []uint) (bool, error) {
+	dogList, err := r.Dep.DogModel.ListByRole(ctx, userId, schema.HouseholdRoleWalker)
+	if err != nil {
+		return false, err
+	}
+	owned := map[uint]bool{}
+	for _, dogItem := range dogList {
+		owned[dogItem.ID] = true
+	}
+	for _, dogId := range dogIds {
+		if !owned[dogId] {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// userCanViewRoute the walker, or anyone who may see one of the walked dogs
+func (r *RouteController) userCanViewRoute(ctx context.Context, userId uint, route *schema.Route) (bool, error) {
+	if route.UserId == userId {
+		return true, nil
+	}
+	dogList, err := r.Dep.DogModel.List(ctx, userId)
+	if err != nil {
+		return false, err
+	}
+	for _, dogItem := range dogList {
+		for _, dogId := range route.DogIds {
+			if dogItem.ID == dogId {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// fillRouteDogIds set DogIds of the routes from route_dog
+func fillRouteDogIds(ctx context.Context, dep mixin.StoreDepency, routes ...*schema.Route) error {
+	routeIds := make([]uint, 0, len(routes))
+	for _, route := range routes {
+		routeIds = append(routeIds, route.ID)
+	}
+	dogIdsMap, err := dep.RouteDogModel.ListByRouteIds(ctx, routeIds)
+	if err != nil {
+		return err
+	}
+	for _, route := range routes {
+		route.DogIds = dogIdsMap[route.ID]
+		route.DogIds = route.AllDogIds()
+	}
+	return nil
+}
+
+// PauseRoute
+func (r *RouteController) PauseRoute(c *gin.Context) {
+	r.transitRoute(c, schema.RouteStatusPaused)
+}
+
+// ResumeRoute
+func (r *RouteController) ResumeRoute(c *gin.Context) {
+	r.transitRoute(c, schema.RouteStatusActive)
+}
+
+// FinishRoute
+func (r *RouteController) FinishRoute(c *gin.Context) {
+	r.transitRoute(c, schema.RouteStatusFinished)
+}
+
+// AbandonRoute
+func (r *RouteController) AbandonRoute(c *gin.Context) {
+	r.transitRoute(c, schema.RouteStatusAbandoned)
+}
+
+// transitRoute move the caller's route to status if the state machine allows it
+func (r *RouteController) transitRoute(c *gin.Context, status string) {
+	ctx := c.Request.Context()
+
+	var param types.RouteEditParam
+	if err := c.ShouldBindJSON(&param); err != nil {
+		logger.Errorf(ctx, "parse query params failed %+v", err)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	route, err := r.Dep.RouteModel.GetRouteById(ctx, param.RouteId)
+	if err != nil {
+		logger.Errorf(ctx, "get route by id fail %+v", err)
+		mixin.ResError(c, errors.GetRouteByIdFail)
+		return
+	}
+
+	if route == nil || route.UserId != userId {
+		mixin.ResError(c, errors.GetRouteByIdFail)
+		return
+	}
+
+	if !route.CanTransitTo(status) {
+		mixin.ResError(c, errors.RouteStatusIllegal)
+		return
+	}
+
+	// pauses are kept so the time and distance while paused do not count as walking
+	var affected int64
+	err = r.Dep.TranModel.ExecTrans(ctx, r.Dep.DBClient.Db, func(ctx context.Context) error {
+		var err error
+		affected, err = r.Dep.RouteModel.UpdateStatus(ctx, route.ID, userId, route.Status, status)
+		if err != nil || affected == 0 {
+			return err
+		}
+		now := time.Now()
+		if status == schema.RouteStatusPaused {
+			return r.Dep.RoutePauseModel.Pause(ctx, route.ID, now)
+		}
+		if route.Status == schema.RouteStatusPaused {
+			return r.Dep.RoutePauseModel.Resume(ctx, route.ID, now)
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Errorf(ctx, "update route status fail %+v", err)
+		mixin.ResError(c, errors.UpdateRouteFail)
+		return
+	}
+	// changed by someone else (e.g. the stale sweeper) in the meantime
+	if affected == 0 {
+		mixin.ResError(c, errors.RouteStatusIllegal)
+		return
+	}
+
+	route, err = r.Dep.RouteModel.GetRouteById(ctx, param.RouteId)
+	if err != nil {
+		logger.Errorf(ctx, "get route by id fail %+v", err)
+		mixin.ResError(c, errors.GetRouteByIdFail)
+		return
+	}
+
+	// share links end with the walk
+	if route.IsClosed() {
+		if err := r.Dep.RouteShareModel.ExpireByRouteId(ctx, route.ID); err != nil {
+			logger.Errorf(ctx, "expire route share fail %+v", err)
+		}
+	}
+	if route.Status == schema.RouteStatusFinished {
+		NotifyDelegatedWalk(ctx, r.Dep, route)
+	}
 
 	mixin.ResSuccess(c, gin.H{
 		"code":    0,
 		"message": "ok",
-		"data":    param,
+		"data":    route,
 	})
 }
 
@@ -89,6 +289,15 @@ func (r *RouteController) ListRoute(c *gin.Context) {
 		mixin.ResError(c, errors.ListRouteFail)
 		return
 	}
+	routes := make([]*schema.Route, 0, len(*routeList))
+	for i := range *routeList {
+		routes = append(routes, &(*routeList)[i])
+	}
+	if err := fillRouteDogIds(ctx, r.Dep, routes...); err != nil {
+		logger.Errorf(ctx, "list route dog fail %+v", err)
+		mixin.ResError(c, errors.ListRouteFail)
+		return
+	}
 
 	mixin.ResSuccess(c, gin.H{
 		"code":    0,
@@ -97,6 +306,77 @@ func (r *RouteController) ListRoute(c *gin.Context) {
 	})
 }
 
+// ListDogStats walking of each of the user's dogs, a walk with several dogs counts for each of them
+func (r *RouteController) ListDogStats(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var param types.DogStatsParam
+	if err := c.ShouldBindQuery(&param); err != nil {
+		logger.Errorf(ctx, "parse query params failed %+v", err)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	to := param.To
+	if to.IsZero() {
+		to = time.Now()
+	}
+	from := param.From
+	if from.IsZero() {
+		from = to.AddDate(0, 0, -30)
+	}
+	if !from.Before(to) {
+		mixin.ResError(c, errors.ExportRangeIllegal)
+		return
+	}
+
+	dogList, err := r.Dep.DogModel.List(ctx, userId)
+	if err != nil {
+		logger.Errorf(ctx, "list dog fail %+v", err)
+		mixin.ResError(c, errors.ListDogStatsFail)
+		return
+	}
+	dogIds := make([]uint, 0, len(dogList))
+	for _, dogItem := range dogList {
+		dogIds = append(dogIds, dogItem.ID)
+	}
+	statsList, err := r.Dep.RouteModel.SumStatsByDog(ctx, dogIds, from, to)
+	if err != nil {
+		logger.Errorf(ctx, "sum dog stats fail %+v", err)
+		mixin.ResError(c, errors.ListDogStatsFail)
+		return
+	}
+	statsMap := map[uint]schema.DogStats{}
+	for _, stats := range statsList {
+		statsMap[stats.DogId] = stats
+	}
+
+	// dogs without walks are listed with zeros
+	res := make([]gin.H, 0, len(dogList))
+	for _, dogItem := range dogList {
+		stats := statsMap[dogItem.ID]
+		stats.DogId = dogItem.ID
+		res = append(res, gin.H{
+			"dogName": dogItem.Name,
+			"stats":   stats,
+		})
+	}
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+		"data":    res,
+	})
+}
+
 // GetRouteDetail
 func (r *RouteController) GetRouteDetail(c *gin.Context) {
 	ctx := c.Request.Context()
@@ -108,6 +388,14 @@ func (r *RouteController) GetRouteDetail(c *gin.Context) {
 		return
 	}
 
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
 	route, err := r.Dep.RouteModel.GetRouteById(ctx, param.RouteId)
 	if err != nil {
 		logger.Errorf(ctx, "get route by id fail %+v", err)
@@ -115,23 +403,101 @@ func (r *RouteController) GetRouteDetail(c *gin.Context) {
 		return
 	}
 
-	routePoints, err := r.Dep.RoutePointModel.ListByRouteId(ctx, param.RouteId)
+	if route == nil {
+		mixin.ResError(c, errors.GetRouteByIdFail)
+		return
+	}
+	if err := fillRouteDogIds(ctx, r.Dep, route); err != nil {
+		logger.Errorf(ctx, "list route dog fail %+v", err)
+		mixin.ResError(c, errors.GetRouteByIdFail)
+		return
+	}
+	canView, err := r.userCanViewRoute(ctx, userId, route)
+	if err != nil {
+		logger.Errorf(ctx, "list dog fail %+v", err)
+		mixin.ResError(c, errors.GetRouteByIdFail)
+		return
+	}
+	if !canView {
+		mixin.ResError(c, errors.GetRouteByIdFail)
+		return
+	}
+
+	routePoints, err := r.simplifiedRoutePoints(ctx, route, param.Tolerance)
 	if err != nil {
 		logger.Errorf(ctx, "get route point by id fail %+v", err)
 		mixin.ResError(c, errors.ListRoutePointFail)
 		return
 	}
 
+	data := gin.H{
+		"route": route,
+	}
+	if param.Format == "polyline" {
+		data["polyline"] = util.EncodePolyline(routePoints.GeoPoints())
+	} else {
+		data["routePoints"] = routePoints
+	}
+	if param.IncludeRejected {
+		rejectedPoints, err := r.Dep.RoutePointModel.ListRejectedByRouteId(ctx, param.RouteId)
+		if err != nil {
+			logger.Errorf(ctx, "get rejected route point by id fail %+v", err)
+			mixin.ResError(c, errors.ListRoutePointFail)
+			return
+		}
+		data["rejectedPoints"] = rejectedPoints
+	}
+
 	mixin.ResSuccess(c, gin.H{
 		"code":    0,
 		"message": "ok",
-		"data": gin.H{
-			"route":       route,
-			"routePoints": routePoints,
-		},
+		"data":    data,
 	})
 }
 
+// simplifiedRoutePoints accepted points of the route, simplified with douglas-peucker when tolerance > 0
+// the simplified geometry is cached per route, tolerance and point count
+func (r *RouteController) simplifiedRoutePoints(ctx context.Context, route *schema.Route, tolerance float64) (schema.RoutePointList, error) {
+	if tolerance <= 0 {
+		routePoints, err := r.Dep.RoutePointModel.ListByRouteId(ctx, route.ID)
+		if err != nil {
+			return nil, err
+		}
+		return *routePoints, nil
+	}
+
+	cacheKey := keys.GetRouteSimplifiedKey(route.ID, tolerance, route.PointCount)
+	cachedInfo, err := r.Dep.RedisClient.Get(cacheKey)
+	if err == nil && cachedInfo != "" {
+		cached := schema.RoutePointList{}
+		if err := util.JSONUnmarshal([]byte(cachedInfo), &cached); err == nil {
+			return cached, nil
+		}
+		logger.Errorf(ctx, "json unmarshal simplified route fail %+v", err)
+	}
+
+	routePoints, err := r.Dep.RoutePointModel.ListByRouteId(ctx, route.ID)
+	if err != nil {
+		return nil, err
+	}
+	indexes := util.SimplifyIndexes(routePoints.GeoPoints(), tolerance)
+	simplified := make(schema.RoutePointList, 0, len(indexes))
+	for _, index := range indexes {
+		simplified = append(simplified, (*routePoints)[index])
+	}
+
+	marshalStr, err := util.JSONMarshal(simplified)
+	if err != nil {
+		logger.Errorf(ctx, "json marshal fail %+v", err)
+		return simplified, nil
+	}
+	// a cache failure only costs a recompute next time
+	if _, err := r.Dep.RedisClient.Set(cacheKey, marshalStr, 24*time.Hour); err != nil {
+		logger.Errorf(ctx, "set to redis fail %+v", err)
+	}
+	return simplified, nil
+}
+
 // UpdateRouteLocation
 func (r *RouteController) UpdateRouteLocation(c *gin.Context) {
 	ctx := c.Request.Context()
@@ -164,12 +530,57 @@ func (r *RouteController) UpdateRouteLocation(c *gin.Context) {
 		return
 	}
 
+	if route.Status != schema.RouteStatusActive {
+		mixin.ResError(c, errors.RouteNotActive)
+		return
+	}
+	if err := fillRouteDogIds(ctx, r.Dep, route); err != nil {
+		logger.Errorf(ctx, "list route dog fail %+v", err)
+		mixin.ResError(c, errors.GetRouteByIdFail)
+		return
+	}
+
+	point := schema.RoutePoint{
+		RouteId:     param.RouteId,
+		Longitude:   param.Longitude,
+		Latitude:    param.Latitude,
+		CreatedTime: param.CreatedTime,
+	}
+	if point.CreatedTime == nil {
+		point.CreatedTime = util.GetTimePtr(time.Now())
+	}
+
+	var geofenceEvents schema.GeofenceEventList
+	// owner of each dog on the walk, their privacy and fences apply rather than the walker's
+	dogOwners := map[uint]uint{}
 	err = r.Dep.TranModel.ExecTrans(ctx, r.Dep.DBClient.Db, func(ctx context.Context) error {
+		recentPoints, err := r.Dep.RoutePointModel.ListRecentByRouteId(ctx, param.RouteId, nil, util.FilterHistory)
+		if err != nil {
+			return err
+		}
+
+		pauseList, err := r.Dep.RoutePauseModel.ListByRouteId(ctx, param.RouteId)
+		if err != nil {
+			return err
+		}
+		pauses := pauseList.Pauses()
+
+		// gps filter, rejected points are kept for the record but do not count
+		// the first point after a pause starts afresh, the walker may be somewhere else by then
+		history := recentPoints.RawGeoPoints()
+		if len(recentPoints) > 0 && pauses.Overlaps(*recentPoints[len(recentPoints)-1].CreatedTime, *point.CreatedTime) {
+			history = nil
+		}
+		point.ApplyFilter(util.NewGeoFilter(history))
+
 		//add location
-		err = r.Dep.RoutePointModel.Create(ctx, param)
+		err = r.Dep.RoutePointModel.Create(ctx, point)
 		if err != nil {
 			return err
 		}
+		if point.Rejected {
+			return nil
+		}
 
 		// add location count
 		err = r.Dep.RouteModel.AddPointCount(ctx, userId, param.RouteId)
@@ -177,12 +588,36 @@ func (r *RouteController) UpdateRouteLocation(c *gin.Context) {
 			return err
 		}
 
-		// update dog location
-		err = r.Dep.DogModel.UpdateLocation(ctx, route.DogId, route.UserId, param.Longitude, param.Latitude)
+		// walk stats
+		seg := util.WalkStats{LastPointTime: point.CreatedTime}
+		if len(recentPoints) > 0 {
+			seg = util.SegmentStats(recentPoints[len(recentPoints)-1].GeoPoint(), point.GeoPoint(), pauses)
+		}
+		err = r.Dep.RouteModel.AddStats(ctx, param.RouteId, seg)
 		if err != nil {
 			return err
 		}
 
+		// update the location of every dog on the walk, with geofence enter / exit
+		for _, dogId := range route.DogIds {
+			prevDog, err := r.Dep.DogModel.GetById(ctx, dogId)
+			if err != nil {
+				return err
+			}
+			if prevDog != nil {
+				dogOwners[dogId] = prevDog.UserId
+			}
+			err = r.Dep.DogModel.UpdateLocation(ctx, dogId, route.UserId, point.Longitude, point.Latitude)
+			if err != nil {
+				return err
+			}
+			events, err := recordGeofenceEvents(ctx, r.Dep, route, prevDog, []util.GeoPoint{point.GeoPoint()})
+			if err != nil {
+				return err
+			}
+			geofenceEvents = append(geofenceEvents, events...)
+		}
+
 		return nil
 	})
 	if err != nil {
@@ -190,9 +625,506 @@ func (r *RouteController) UpdateRouteLocation(c *gin.Context) {
 		mixin.ResError(c, errors.CreateRoutePointFail)
 		return
 	}
+	if !point.Rejected {
+		for dogId, ownerId := range dogOwners {
+			publishDogLocation(ctx, r.Dep, dogId, ownerId, point.Latitude, point.Longitude, *point.CreatedTime)
+		}
+	}
+	notifyGeofenceEvents(ctx, r.Dep, geofenceEvents)
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+		"data": gin.H{
+			"rejected":     point.Rejected,
+			"rejectReason": point.RejectReason,
+		},
+	})
+}
+
+// UploadPoints batch upload of points recorded offline, deduped by the client seq
+func (r *RouteController) UploadPoints(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var param types.UploadRoutePointsParam
+	if err := c.ShouldBindJSON(&param); err != nil {
+		logger.Errorf(ctx, "parse query params failed %+v", err)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	//check if ownership is valid
+	route, err := r.Dep.RouteModel.GetRouteById(ctx, param.RouteId)
+	if err != nil {
+		logger.Errorf(ctx, "get route by id fail %+v", err)
+		mixin.ResError(c, errors.GetRouteByIdFail)
+		return
+	}
+
+	if route == nil || route.UserId != userId {
+		mixin.ResError(c, errors.GetRouteByIdFail)
+		return
+	}
+
+	// points recorded offline may arrive after the walk was finished, only abandoned walks refuse them
+	if route.Status == schema.RouteStatusAbandoned {
+		mixin.ResError(c, errors.RouteNotActive)
+		return
+	}
+	if err := fillRouteDogIds(ctx, r.Dep, route); err != nil {
+		logger.Errorf(ctx, "list route dog fail %+v", err)
+		mixin.ResError(c, errors.GetRouteByIdFail)
+		return
+	}
+
+	// order by time and drop seqs repeated inside the batch
+	sort.SliceStable(param.Points, func(i, j int) bool {
+		return param.Points[i].CreatedTime.Before(param.Points[j].CreatedTime)
+	})
+	seen := map[uint64]bool{}
+	seqs := []uint64{}
+	for _, point := range param.Points {
+		if !seen[*point.Seq] {
+			seen[*point.Seq] = true
+			seqs = append(seqs, *point.Seq)
+		}
+	}
+
+	accepted, rejected, duplicate := 0, 0, 0
+	var newest *schema.RoutePoint
+	var geofenceEvents schema.GeofenceEventList
+	// owner of each dog on the walk, their privacy and fences apply rather than the walker's
+	dogOwners := map[uint]uint{}
+	dogMoved := false
+	err = r.Dep.TranModel.ExecTrans(ctx, r.Dep.DBClient.Db, func(ctx context.Context) error {
+		existSeqs, err := r.Dep.RoutePointModel.ListSeqByRouteId(ctx, route.ID, seqs)
+		if err != nil {
+			return err
+		}
+		duplicate = len(param.Points) - len(seqs) + len(existSeqs)
+		exist := map[uint64]bool{}
+		for _, seq := range existSeqs {
+			exist[seq] = true
+		}
+
+		pointList := schema.RoutePointList{}
+		for _, point := range param.Points {
+			if exist[*point.Seq] {
+				continue
+			}
+			exist[*point.Seq] = true
+			pointList = append(pointList, schema.RoutePoint{
+				RouteId:     route.ID,
+				Longitude:   point.Longitude,
+				Latitude:    point.Latitude,
+				CreatedTime: util.GetTimePtr(point.CreatedTime),
+				Seq:         point.Seq,
+			})
+		}
+		if len(pointList) == 0 {
+			return nil
+		}
+
+		// gps filter seeded with the accepted points preceding the batch
+		recentPoints, err := r.Dep.RoutePointModel.ListRecentByRouteId(ctx, route.ID, pointList[0].CreatedTime, util.FilterHistory)
+		if err != nil {
+			return err
+		}
+		pauseList, err := r.Dep.RoutePauseModel.ListByRouteId(ctx, route.ID)
+		if err != nil {
+			return err
+		}
+		pauses := pauseList.Pauses()
+		filter := util.NewGeoFilter(recentPoints.RawGeoPoints())
+		var lastTime *time.Time
+		if len(recentPoints) > 0 {
+			lastTime = recentPoints[len(recentPoints)-1].CreatedTime
+		}
+		for i := range pointList {
+			// the filter starts afresh after a pause
+			if lastTime != nil && pauses.Overlaps(*lastTime, *pointList[i].CreatedTime) {
+				filter = util.NewGeoFilter(nil)
+			}
+			pointList[i].ApplyFilter(filter)
+			if !pointList[i].Rejected {
+				lastTime = pointList[i].CreatedTime
+			}
+			if pointList[i].Rejected {
+				rejected++
+				continue
+			}
+			accepted++
+			newest = &pointList[i]
+		}
+
+		err = r.Dep.RoutePointModel.CreateBatch(ctx, pointList)
+		if err != nil {
+			return err
+		}
+
+		// points may interleave with existing ones, recompute stats and point count from scratch
+		allPoints, err := r.Dep.RoutePointModel.ListByRouteId(ctx, route.ID)
+		if err != nil {
+			return err
+		}
+		err = r.Dep.RouteModel.SetStats(ctx, route.ID, util.ComputeWalkStats(allPoints.GeoPoints(), pauses))
+		if err != nil {
+			return err
+		}
+
+		// update dog location from the newest point only, and only if it is newer than what we had
+		if newest == nil {
+			return nil
+		}
+		if route.LastPointTime == nil || newest.CreatedTime.After(*route.LastPointTime) {
+			// every accepted point past the last known one is checked against the fences, in order
+			movedPoints := []util.GeoPoint{}
+			for _, point := range pointList {
+				if point.Rejected || (route.LastPointTime != nil && !point.CreatedTime.After(*route.LastPointTime)) {
+					continue
+				}
+				movedPoints = append(movedPoints, point.GeoPoint())
+			}
+			for _, dogId := range route.DogIds {
+				prevDog, err := r.Dep.DogModel.GetById(ctx, dogId)
+				if err != nil {
+					return err
+				}
+				if prevDog != nil {
+					dogOwners[dogId] = prevDog.UserId
+				}
+				err = r.Dep.DogModel.UpdateLocationAt(ctx, dogId, route.UserId, newest.Longitude, newest.Latitude, *newest.CreatedTime)
+				if err != nil {
+					return err
+				}
+				events, err := recordGeofenceEvents(ctx, r.Dep, route, prevDog, movedPoints)
+				if err != nil {
+					return err
+				}
+				geofenceEvents = append(geofenceEvents, events...)
+			}
+			dogMoved = true
+		}
+
+		return nil
+	})
+	if err != nil {
+		logger.Errorf(ctx, "upload route points fail %+v", err)
+		mixin.ResError(c, errors.UploadRoutePointFail)
+		return
+	}
+	if dogMoved {
+		for dogId, ownerId := range dogOwners {
+			publishDogLocation(ctx, r.Dep, dogId, ownerId, newest.Latitude, newest.Longitude, *newest.CreatedTime)
+		}
+		notifyGeofenceEvents(ctx, r.Dep, geofenceEvents)
+	}
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+		"data": gin.H{
+			"accepted":  accepted,
+			"rejected":  rejected,
+			"duplicate": duplicate,
+		},
+	})
+}
+
+// maxExportDays longest date range a single export may cover
+const maxExportDays = 366
+
+// ExportRoute export one route or the routes of a date range as gpx, geojson or kml
+func (r *RouteController) ExportRoute(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var param types.ExportRouteParam
+	if err := c.ShouldBindQuery(&param); err != nil {
+		logger.Errorf(ctx, "parse query params failed %+v", err)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	format, _ := geoformat.GetFormat(param.Format)
+
+	routeList := schema.RouteList{}
+	fileName := ""
+	if param.RouteId != 0 {
+		route, err := r.Dep.RouteModel.GetRouteById(ctx, param.RouteId)
+		if err != nil {
+			logger.Errorf(ctx, "get route by id fail %+v", err)
+			mixin.ResError(c, errors.GetRouteByIdFail)
+			return
+		}
+		if route == nil || route.UserId != userId {
+			mixin.ResError(c, errors.GetRouteByIdFail)
+			return
+		}
+		routeList = append(routeList, *route)
+		fileName = fmt.Sprintf("walk-%d.%s", route.ID, format.Ext)
+	} else {
+		// to is inclusive
+		to := param.To.AddDate(0, 0, 1)
+		if param.From.IsZero() || param.To.IsZero() || !to.After(param.From) || to.Sub(param.From) > maxExportDays*24*time.Hour {
+			mixin.ResError(c, errors.ExportRangeIllegal)
+			return
+		}
+		list, err := r.Dep.RouteModel.ListRouteByTime(ctx, userId, param.From, to)
+		if err != nil {
+			logger.Errorf(ctx, "list route by time fail %+v", err)
+			mixin.ResError(c, errors.ListRouteFail)
+			return
+		}
+		routeList = list
+		fileName = fmt.Sprintf("walks-%s-%s.%s", param.From.Format("20060102"), param.To.Format("20060102"), format.Ext)
+	}
+
+	dogList, err := r.Dep.DogModel.List(ctx, userId)
+	if err != nil {
+		logger.Errorf(ctx, "list dog fail %+v", err)
+		mixin.ResError(c, errors.ListDogFail)
+		return
+	}
+	dogNames := map[uint]string{}
+	for _, dogItem := range dogList {
+		dogNames[dogItem.ID] = dogItem.Name
+	}
+
+	homeZones, err := r.Dep.PrivacyModel.ListHomeZone(ctx, userId)
+	if err != nil {
+		logger.Errorf(ctx, "list home zone fail %+v", err)
+		mixin.ResError(c, errors.ListHomeZoneFail)
+		return
+	}
+
+	tracks := make([]geoformat.Track, 0, len(routeList))
+	for _, route := range routeList {
+		routePoints, err := r.Dep.RoutePointModel.ListByRouteId(ctx, route.ID)
+		if err != nil {
+			logger.Errorf(ctx, "get route point by id fail %+v", err)
+			mixin.ResError(c, errors.ListRoutePointFail)
+			return
+		}
+		track := geoformat.Track{
+			ID:     route.ID,
+			Points: homeZones.Clip(routePoints.GeoPoints()),
+		}
+		for _, dogId := range route.AllDogIds() {
+			// a dog walked together with the user's may no longer be shared with them
+			if _, ok := dogNames[dogId]; !ok {
+				dogItem, err := r.Dep.DogModel.GetById(ctx, dogId)
+				if err != nil {
+					logger.Errorf(ctx, "get dog by id fail %+v", err)
+					mixin.ResError(c, errors.ExportRouteFail)
+					return
+				}
+				if dogItem != nil {
+					dogNames[dogId] = dogItem.Name
+				}
+			}
+			if name := dogNames[dogId]; name != "" {
+				track.DogNames = append(track.DogNames, name)
+			}
+		}
+		if route.CreatedTime != nil {
+			track.Start = *route.CreatedTime
+		}
+		track.Name = fmt.Sprintf("Walk with %s %s", track.DogName(), track.Start.Format("2006-01-02 15:04"))
+		tracks = append(tracks, track)
+	}
+
+	var buf bytes.Buffer
+	if err := format.Write(&buf, tracks); err != nil {
+		logger.Errorf(ctx, "write %s fail %+v", format.Ext, err)
+		mixin.ResError(c, errors.ExportRouteFail)
+		return
+	}
+
+	mixin.ResAttachment(c, format.ContentType, fileName, buf.Bytes())
+}
+
+// maxImportFileSize 10MB
+const maxImportFileSize = 10 << 20
+
+// ImportRoute create finished routes from gpx or geojson files of other trackers
+func (r *RouteController) ImportRoute(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var param types.ImportRouteParam
+	if err := c.ShouldBind(&param); err != nil {
+		logger.Errorf(ctx, "parse query params failed %+v", err)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	//check dog validity
+	exist, err := r.userOwnsDog(ctx, userId, param.DogId)
+	if err != nil {
+		logger.Errorf(ctx, "list dog fail %+v", err)
+		mixin.ResError(c, errors.ListDogFail)
+		return
+	}
+
+	if !exist {
+		mixin.ResError(c, errors.DogNotExist)
+		return
+	}
+
+	results := make([]types.ImportRouteResult, 0, len(param.Files))
+	imported := 0
+	for _, fileHeader := range param.Files {
+		result := types.ImportRouteResult{FileName: fileHeader.Filename, RouteIds: []uint{}}
+		routeIds, pointCount, err := r.importRouteFile(ctx, userId, param.DogId, fileHeader)
+		if err != nil {
+			logger.Errorf(ctx, "import route file %s fail %+v", fileHeader.Filename, err)
+			result.Error = err.Error()
+		} else {
+			result.RouteIds = routeIds
+			result.PointCount = pointCount
+			imported++
+		}
+		results = append(results, result)
+	}
+	if len(param.Files) > 0 && imported == 0 {
+		mixin.ResError(c, errors.ImportRouteFail)
+		return
+	}
 
 	mixin.ResSuccess(c, gin.H{
 		"code":    0,
 		"message": "ok",
+		"data":    results,
+	})
+}
+
+// importRouteFile every track of the file becomes a finished route, all in one transaction
+func (r *RouteController) importRouteFile(ctx context.Context, userId uint, dogId uint, fileHeader *multipart.FileHeader) ([]uint, int, error) {
+	if fileHeader.Size > maxImportFileSize {
+		return nil, 0, fmt.Errorf("file larger than %d bytes", maxImportFileSize)
+	}
+	ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(fileHeader.Filename)), ".")
+	if ext == "json" {
+		ext = geoformat.GeoJSON.Ext
+	}
+	format, ok := geoformat.GetFormat(ext)
+	if !ok || format.Parse == nil {
+		return nil, 0, fmt.Errorf("unsupported file type %q, expect gpx or geojson", ext)
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return nil, 0, err
+	}
+	defer file.Close()
+
+	tracks, err := format.Parse(file)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	now := time.Now()
+	routeIds := []uint{}
+	pointCount := 0
+	err = r.Dep.TranModel.ExecTrans(ctx, r.Dep.DBClient.Db, func(ctx context.Context) error {
+		for i, track := range tracks {
+			if len(track.Points) == 0 {
+				continue
+			}
+			// untimed points are stamped with the import time, they only contribute distance
+			untimed := false
+			for j := range track.Points {
+				p := &track.Points[j]
+				if p.Longitude < -180 || p.Longitude > 180 || p.Latitude < -90 || p.Latitude > 90 {
+					return fmt.Errorf("track %d point %d out of range", i+1, j+1)
+				}
+				if p.Time.IsZero() {
+					p.Time = now
+					untimed = true
+				}
+			}
+			sort.SliceStable(track.Points, func(a, b int) bool {
+				return track.Points[a].Time.Before(track.Points[b].Time)
+			})
+
+			first, last := track.Points[0], track.Points[len(track.Points)-1]
+			route := schema.Route{
+				UserId:       userId,
+				DogId:        dogId,
+				Status:       schema.RouteStatusFinished,
+				CreatedTime:  util.GetTimePtr(first.Time),
+				FinishedTime: util.GetTimePtr(last.Time),
+			}
+			err := r.Dep.RouteModel.Create(ctx, &route)
+			if err != nil {
+				return err
+			}
+			err = r.Dep.RouteDogModel.CreateBatch(ctx, route.ID, []uint{dogId})
+			if err != nil {
+				return err
+			}
+
+			filter := util.NewGeoFilter(nil)
+			pointList := make(schema.RoutePointList, 0, len(track.Points))
+			accepted := []util.GeoPoint{}
+			for _, p := range track.Points {
+				point := schema.RoutePoint{
+					RouteId:     route.ID,
+					Longitude:   p.Longitude,
+					Latitude:    p.Latitude,
+					CreatedTime: util.GetTimePtr(p.Time),
+				}
+				// without timestamps every step looks like a jump, so the filter is skipped
+				if !untimed {
+					point.ApplyFilter(filter)
+				}
+				if !point.Rejected {
+					accepted = append(accepted, point.GeoPoint())
+				}
+				pointList = append(pointList, point)
+			}
+			err = r.Dep.RoutePointModel.CreateBatch(ctx, pointList)
+			if err != nil {
+				return err
+			}
+			err = r.Dep.RouteModel.SetStats(ctx, route.ID, util.ComputeWalkStats(accepted, nil))
+			if err != nil {
+				return err
+			}
+
+			routeIds = append(routeIds, route.ID)
+			pointCount += len(accepted)
+		}
+		if len(routeIds) == 0 {
+			return fmt.Errorf("no track found in file")
+		}
+		return nil
 	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return routeIds, pointCount, nil
 }
@@ -0,0 +1,271 @@
+package controller
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yiff028/comp90018-mobile-project/backend/api/types"
+	"github.com/yiff028/comp90018-mobile-project/backend/app/mixin"
+	"github.com/yiff028/comp90018-mobile-project/backend/app/model/schema"
+	"github.com/yiff028/comp90018-mobile-project/backend/app/service"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/errors"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/logger"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/util"
+)
+
+const (
+	defaultShareTTL = 240
+	shareTokenBytes = 32
+)
+
+// ShareController
+type ShareController struct {
+	Dep mixin.StoreDepency
+	WS  service.WebService
+}
+
+// CreateShare link to watch an ongoing walk without an account
+func (s *ShareController) CreateShare(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var param types.RouteShareCreateParam
+	if err := c.ShouldBindJSON(&param); err != nil {
+		logger.Errorf(ctx, "parse query params failed %+v", err)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	route, err := s.Dep.RouteModel.GetRouteById(ctx, param.RouteId)
+	if err != nil {
+		logger.Errorf(ctx, "get route by id fail %+v", err)
+		mixin.ResError(c, errors.GetRouteByIdFail)
+		return
+	}
+	if route == nil || route.UserId != userId {
+		mixin.ResError(c, errors.GetRouteByIdFail)
+		return
+	}
+	if route.IsClosed() {
+		mixin.ResError(c, errors.RouteNotActive)
+		return
+	}
+
+	token, err := util.RandomToken(shareTokenBytes)
+	if err != nil {
+		logger.Errorf(ctx, "generate share token fail %+v", errors.WithStack(err))
+		mixin.ResError(c, errors.CreateShareFail)
+		return
+	}
+	ttl := param.TTLMinutes
+	if ttl == 0 {
+		ttl = defaultShareTTL
+	}
+	share := schema.RouteShare{
+		RouteId:     route.ID,
+		UserId:      userId,
+		Token:       token,
+		ExpiresTime: util.GetTimePtr(time.Now().Add(time.Duration(ttl) * time.Minute)),
+	}
+	if err := s.Dep.RouteShareModel.Create(ctx, &share); err != nil {
+		logger.Errorf(ctx, "create share fail %+v", err)
+		mixin.ResError(c, errors.CreateShareFail)
+		return
+	}
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+		"data":    share,
+	})
+}
+
+// RevokeShare
+func (s *ShareController) RevokeShare(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var param types.RouteShareEditParam
+	if err := c.ShouldBindJSON(&param); err != nil {
+		logger.Errorf(ctx, "parse query params failed %+v", err)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	affected, err := s.Dep.RouteShareModel.Revoke(ctx, param.ShareId, userId)
+	if err != nil {
+		logger.Errorf(ctx, "revoke share fail %+v", err)
+		mixin.ResError(c, errors.RevokeShareFail)
+		return
+	}
+	if affected == 0 {
+		mixin.ResError(c, errors.ShareNotExist)
+		return
+	}
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+	})
+}
+
+// ListShare shares of one of the user's routes
+func (s *ShareController) ListShare(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var param types.RouteShareListParam
+	if err := c.ShouldBindQuery(&param); err != nil {
+		logger.Errorf(ctx, "parse query params failed %+v", err)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	shareList, err := s.Dep.RouteShareModel.ListByRouteId(ctx, param.RouteId, userId)
+	if err != nil {
+		logger.Errorf(ctx, "list share fail %+v", err)
+		mixin.ResError(c, errors.ListShareFail)
+		return
+	}
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+		"data":    shareList,
+	})
+}
+
+// GetSharedRoute public, the points so far and the dog's latest position
+// points inside the home zones of the walker or of the dog's owner are never returned
+func (s *ShareController) GetSharedRoute(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var param types.SharedRouteParam
+	if err := c.ShouldBindQuery(&param); err != nil {
+		logger.Errorf(ctx, "parse query params failed %+v", err)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+
+	share, err := s.Dep.RouteShareModel.GetByToken(ctx, param.Token)
+	if err != nil {
+		logger.Errorf(ctx, "get share fail %+v", err)
+		mixin.ResError(c, errors.ShareNotExist)
+		return
+	}
+	if share == nil || !share.IsValid(time.Now()) {
+		mixin.ResError(c, errors.ShareNotExist)
+		return
+	}
+
+	route, err := s.Dep.RouteModel.GetRouteById(ctx, share.RouteId)
+	if err != nil {
+		logger.Errorf(ctx, "get route by id fail %+v", err)
+		mixin.ResError(c, errors.GetRouteByIdFail)
+		return
+	}
+	// the share ends with the walk
+	if route == nil || route.IsClosed() {
+		mixin.ResError(c, errors.ShareNotExist)
+		return
+	}
+
+	dogItem, err := s.Dep.DogModel.GetById(ctx, route.DogId)
+	if err != nil {
+		logger.Errorf(ctx, "get dog by id fail %+v", err)
+		mixin.ResError(c, errors.DogNotExist)
+		return
+	}
+	homeZones, err := s.Dep.PrivacyModel.ListHomeZone(ctx, route.UserId)
+	if err != nil {
+		logger.Errorf(ctx, "list home zone fail %+v", err)
+		mixin.ResError(c, errors.ListHomeZoneFail)
+		return
+	}
+	// the dog's owner may not be the walker, their home zones and location privacy apply as well
+	var ownerPolicy *schema.LocationPolicy
+	if dogItem != nil {
+		policyMap, err := s.Dep.PrivacyModel.ListPolicy(ctx, 0, []uint{dogItem.UserId})
+		if err != nil {
+			logger.Errorf(ctx, "list location policy fail %+v", err)
+			mixin.ResError(c, errors.ListLocationPolicyFail)
+			return
+		}
+		ownerPolicy = policyMap[dogItem.UserId]
+		homeZones = append(homeZones, ownerPolicy.HomeZones...)
+	}
+
+	routePoints, err := s.Dep.RoutePointModel.ListByRouteId(ctx, route.ID)
+	if err != nil {
+		logger.Errorf(ctx, "get route point by id fail %+v", err)
+		mixin.ResError(c, errors.ListRoutePointFail)
+		return
+	}
+	points := make([]gin.H, 0, len(*routePoints))
+	for _, point := range *routePoints {
+		if param.After != nil && point.CreatedTime != nil && !point.CreatedTime.After(*param.After) {
+			continue
+		}
+		if homeZones.Contains(point.Latitude, point.Longitude) {
+			continue
+		}
+		points = append(points, gin.H{
+			"latitude":  point.Latitude,
+			"longitude": point.Longitude,
+			"time":      point.CreatedTime,
+		})
+	}
+
+	dog := gin.H{}
+	if dogItem != nil {
+		dog["name"] = dogItem.Name
+		dog["img"] = dogItem.Img
+		if dogItem.Latitude != nil && dogItem.Longitude != nil && !homeZones.Contains(*dogItem.Latitude, *dogItem.Longitude) {
+			if lat, lon, ok := ownerPolicy.MaskShared(*dogItem.Latitude, *dogItem.Longitude); ok {
+				dog["latitude"] = lat
+				dog["longitude"] = lon
+				dog["locationUpdatedTime"] = dogItem.LocationUpdatedTime
+			}
+		}
+	}
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+		"data": gin.H{
+			"route": gin.H{
+				"status":         route.Status,
+				"distance":       route.Distance,
+				"activeDuration": route.ActiveDuration,
+				"avgSpeed":       route.AvgSpeed,
+				"createdTime":    route.CreatedTime,
+				"lastPointTime":  route.LastPointTime,
+			},
+			"dog":         dog,
+			"routePoints": points,
+			"expiresTime": share.ExpiresTime,
+		},
+	})
+}
@@ -0,0 +1,162 @@
+package controller
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yiff028/comp90018-mobile-project/backend/api/types"
+	"github.com/yiff028/comp90018-mobile-project/backend/app/mixin"
+	"github.com/yiff028/comp90018-mobile-project/backend/app/model/redismodel"
+	"github.com/yiff028/comp90018-mobile-project/backend/app/model/schema"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/errors"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/logger"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/util"
+)
+
+const (
+	// streamMaxDuration clients reconnect after it, keeps the friend list fresh and bounds the write deadline
+	streamMaxDuration = 30 * time.Minute
+	streamHeartbeat   = 25 * time.Second
+	streamFriendTTL   = time.Minute
+)
+
+// StreamDogLocation server-sent events of dogs moving inside the box
+func (d *DogController) StreamDogLocation(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var param types.StreamDogLocationParam
+	if err := c.ShouldBindQuery(&param); err != nil {
+		logger.Errorf(ctx, "parse query params failed %+v", err)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	box := util.BoundingBox{
+		MinLongitude: *param.MinLon,
+		MinLatitude:  *param.MinLat,
+		MaxLongitude: *param.MaxLon,
+		MaxLatitude:  *param.MaxLat,
+	}
+	if !box.Valid() {
+		logger.Errorf(ctx, "illegal bounds %+v", box)
+		mixin.ResError(c, errors.BoundsIllegal)
+		return
+	}
+
+	ps, err := d.Dep.LocationChannel.Subscribe(box)
+	if err != nil {
+		logger.Errorf(ctx, "subscribe dog location fail %+v", err)
+		mixin.ResError(c, errors.StreamDogLocationFail)
+		return
+	}
+	defer ps.Close()
+
+	friendOf, err := d.listFriendOf(ctx, userId)
+	if err != nil {
+		logger.Errorf(ctx, "list friend fail %+v", err)
+		mixin.ResError(c, errors.ListFriendFail)
+		return
+	}
+	friendLoaded := time.Now()
+
+	// the server write timeout would cut the stream otherwise
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Now().Add(streamMaxDuration + streamHeartbeat)); err != nil {
+		logger.Warnf(ctx, "extend write deadline fail %+v", err)
+	}
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	messages := ps.Channel()
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	deadline := time.NewTimer(streamMaxDuration)
+	defer deadline.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-deadline.C:
+			return false
+		case <-heartbeat.C:
+			c.SSEvent("ping", time.Now().Unix())
+			return true
+		case msg, ok := <-messages:
+			if !ok {
+				return false
+			}
+			event, err := redismodel.ParseDogLocationEvent(msg.Payload)
+			if err != nil {
+				logger.Errorf(ctx, "parse dog location event fail %+v", err)
+				return true
+			}
+			if !box.Contains(event.Latitude, event.Longitude) {
+				return true
+			}
+			if time.Since(friendLoaded) > streamFriendTTL {
+				if refreshed, err := d.listFriendOf(ctx, userId); err == nil {
+					friendOf = refreshed
+					friendLoaded = time.Now()
+				}
+			}
+			if event.Visibility == schema.VisibilityFriends && event.UserId != userId && !friendOf[event.UserId] {
+				return true
+			}
+			c.SSEvent("location", event)
+			return true
+		}
+	})
+}
+
+// listFriendOf users who added userId as a friend
+func (d *DogController) listFriendOf(ctx context.Context, userId uint) (map[uint]bool, error) {
+	friendList, err := d.Dep.FriendModel.ListByFriendId(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	friendOf := make(map[uint]bool, len(friendList))
+	for _, friend := range friendList {
+		friendOf[friend.UserId] = true
+	}
+	return friendOf, nil
+}
+
+// publishDogLocation push the new location to the map subscribers under the owner's privacy, failures only get logged
+func publishDogLocation(ctx context.Context, dep mixin.StoreDepency, dogId uint, ownerId uint, latitude float64, longitude float64, at time.Time) {
+	policyMap, err := dep.PrivacyModel.ListPolicy(ctx, 0, []uint{ownerId})
+	if err != nil {
+		logger.Errorf(ctx, "list location policy fail %+v", err)
+		return
+	}
+	policy := policyMap[ownerId]
+	lat, lon, ok := policy.MaskShared(latitude, longitude)
+	if !ok {
+		return
+	}
+
+	event := redismodel.DogLocationEvent{
+		DogId:      dogId,
+		UserId:     ownerId,
+		Latitude:   lat,
+		Longitude:  lon,
+		Visibility: policy.Privacy.Visibility,
+		Time:       at,
+	}
+	if err := dep.LocationChannel.Publish(event); err != nil {
+		logger.Errorf(ctx, "publish dog location fail %+v", errors.WithStack(err))
+	}
+}
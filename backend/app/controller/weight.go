@@ -0,0 +1,229 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yiff028/comp90018-mobile-project/backend/api/types"
+	"github.com/yiff028/comp90018-mobile-project/backend/app/mixin"
+	"github.com/yiff028/comp90018-mobile-project/backend/app/model/schema"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/errors"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/logger"
+)
+
+const (
+	defaultWeightTrendDays = 90
+	// weightClockSkew how far ahead of the server a phone's clock may run
+	weightClockSkew = 5 * time.Minute
+)
+
+// AddWeight the dog's weight follows its latest measurement
+func (d *DogController) AddWeight(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var param types.WeightLogParam
+	if err := c.ShouldBindJSON(&param); err != nil {
+		logger.Errorf(ctx, "parse query params failed %+v", err)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	dogItem, err := d.Dep.DogModel.GetById(ctx, param.DogId)
+	if err != nil {
+		logger.Errorf(ctx, "get dog fail %+v", err)
+		mixin.ResError(c, errors.AddWeightFail)
+		return
+	}
+	role, err := dogRole(ctx, d.Dep, dogItem, userId)
+	if err != nil {
+		logger.Errorf(ctx, "get dog role fail %+v", err)
+		mixin.ResError(c, errors.AddWeightFail)
+		return
+	}
+	if !schema.RoleAtLeast(role, schema.HouseholdRoleWalker) {
+		mixin.ResError(c, errors.DogNotExist)
+		return
+	}
+
+	measuredTime := time.Now()
+	if param.MeasuredTime != nil {
+		// a future measurement would stay the dog's weight until that time passes
+		if param.MeasuredTime.After(measuredTime.Add(weightClockSkew)) {
+			mixin.ResError(c, errors.WeightLogIllegal)
+			return
+		}
+		measuredTime = *param.MeasuredTime
+	}
+	weightLog := schema.WeightLog{
+		DogId:        dogItem.ID,
+		UserId:       userId,
+		Weight:       param.Weight,
+		MeasuredTime: &measuredTime,
+		Notes:        param.Notes,
+	}
+	err = d.Dep.TranModel.ExecTrans(ctx, d.Dep.DBClient.Db, func(ctx context.Context) error {
+		if err := d.Dep.WeightModel.Create(ctx, &weightLog); err != nil {
+			return err
+		}
+		return syncDogWeight(ctx, d.Dep, dogItem.ID)
+	})
+	if err != nil {
+		logger.Errorf(ctx, "add weight fail %+v", err)
+		mixin.ResError(c, errors.AddWeightFail)
+		return
+	}
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+		"data":    weightLog,
+	})
+}
+
+// DeleteWeight
+func (d *DogController) DeleteWeight(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var param types.WeightDeleteParam
+	if err := c.ShouldBindJSON(&param); err != nil {
+		logger.Errorf(ctx, "parse query params failed %+v", err)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	weightLog, err := d.Dep.WeightModel.GetById(ctx, param.WeightId)
+	if err != nil {
+		logger.Errorf(ctx, "get weight log fail %+v", err)
+		mixin.ResError(c, errors.DeleteWeightFail)
+		return
+	}
+	if weightLog == nil {
+		mixin.ResError(c, errors.WeightLogNotExist)
+		return
+	}
+	dogItem, err := d.Dep.DogModel.GetById(ctx, weightLog.DogId)
+	if err != nil {
+		logger.Errorf(ctx, "get dog fail %+v", err)
+		mixin.ResError(c, errors.DeleteWeightFail)
+		return
+	}
+	role, err := dogRole(ctx, d.Dep, dogItem, userId)
+	if err != nil {
+		logger.Errorf(ctx, "get dog role fail %+v", err)
+		mixin.ResError(c, errors.DeleteWeightFail)
+		return
+	}
+	// owners correct anyone's entry, walkers only their own while they still walk the dog
+	if !schema.RoleAtLeast(role, schema.HouseholdRoleOwner) &&
+		!(weightLog.UserId == userId && schema.RoleAtLeast(role, schema.HouseholdRoleWalker)) {
+		mixin.ResError(c, errors.WeightLogNotExist)
+		return
+	}
+
+	err = d.Dep.TranModel.ExecTrans(ctx, d.Dep.DBClient.Db, func(ctx context.Context) error {
+		if _, err := d.Dep.WeightModel.Delete(ctx, weightLog.ID, weightLog.DogId); err != nil {
+			return err
+		}
+		return syncDogWeight(ctx, d.Dep, weightLog.DogId)
+	})
+	if err != nil {
+		logger.Errorf(ctx, "delete weight fail %+v", err)
+		mixin.ResError(c, errors.DeleteWeightFail)
+		return
+	}
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+	})
+}
+
+// GetWeightTrend measurements with moving average, rate of change, breed relative alert and weekly walking
+func (d *DogController) GetWeightTrend(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var param types.WeightTrendParam
+	if err := c.ShouldBindQuery(&param); err != nil {
+		logger.Errorf(ctx, "parse query params failed %+v", err)
+		mixin.ResError(c, errors.ErrParseQueryFail)
+		return
+	}
+
+	userId := c.GetUint("UserID")
+	if userId == 0 {
+		errInfoWithStack := errors.WithStack(errors.New("userid from cookie fail"))
+		logger.Errorf(ctx, "fetch userid failed %+v", errInfoWithStack)
+		mixin.ResError(c, errors.ErrQueryUserInfoFail)
+		return
+	}
+
+	dogItem, err := d.Dep.DogModel.GetById(ctx, param.DogId)
+	if err != nil {
+		logger.Errorf(ctx, "get dog fail %+v", err)
+		mixin.ResError(c, errors.GetWeightTrendFail)
+		return
+	}
+	role, err := dogRole(ctx, d.Dep, dogItem, userId)
+	if err != nil {
+		logger.Errorf(ctx, "get dog role fail %+v", err)
+		mixin.ResError(c, errors.GetWeightTrendFail)
+		return
+	}
+	if !schema.RoleAtLeast(role, schema.HouseholdRoleViewer) {
+		mixin.ResError(c, errors.DogNotExist)
+		return
+	}
+
+	days := param.Days
+	if days <= 0 {
+		days = defaultWeightTrendDays
+	}
+	to := time.Now()
+	from := to.AddDate(0, 0, -days)
+
+	weightLogList, err := d.Dep.WeightModel.ListByDogId(ctx, dogItem.ID, from)
+	if err != nil {
+		logger.Errorf(ctx, "list weight log fail %+v", err)
+		mixin.ResError(c, errors.GetWeightTrendFail)
+		return
+	}
+	routeList, err := d.Dep.RouteModel.ListByDogAndTime(ctx, dogItem.ID, from, to)
+	if err != nil {
+		logger.Errorf(ctx, "list route fail %+v", err)
+		mixin.ResError(c, errors.GetWeightTrendFail)
+		return
+	}
+
+	mixin.ResSuccess(c, gin.H{
+		"code":    0,
+		"message": "ok",
+		"data":    schema.AnalyzeWeight(*dogItem, weightLogList, routeList, from, to, DogGoalLocation(dogItem)),
+	})
+}
+
+// syncDogWeight set the dog's weight to its latest measurement, unchanged when there is none
+func syncDogWeight(ctx context.Context, dep mixin.StoreDepency, dogId uint) error {
+	latest, err := dep.WeightModel.GetLatest(ctx, dogId)
+	if err != nil || latest == nil {
+		return err
+	}
+	return dep.DogModel.SetWeight(ctx, dogId, latest.Weight)
+}
@@ -2,17 +2,35 @@ package mixin
 
 import (
 	"github.com/yiff028/comp90018-mobile-project/backend/app/model"
+	"github.com/yiff028/comp90018-mobile-project/backend/app/model/redismodel"
 	"github.com/yiff028/comp90018-mobile-project/backend/pkg/driver"
 )
 
 // StoreDepency
 type StoreDepency struct {
-	RedisClient     driver.ClientType
-	DBClient        driver.Database
-	TranModel       model.Transaction
-	UserModel       model.User
-	DogModel        model.Dog
-	RouteModel      model.Route
-	RoutePointModel model.RoutePoint
-	BinModel        model.Bin
+	RedisClient       driver.ClientType
+	DBClient          driver.Database
+	TranModel         model.Transaction
+	UserModel         model.User
+	DogModel          model.Dog
+	DogPhotoModel     model.DogPhoto
+	DogChangeModel    model.DogChange
+	RouteModel        model.Route
+	RoutePointModel   model.RoutePoint
+	RouteDogModel     model.RouteDog
+	RoutePauseModel   model.RoutePause
+	BinModel          model.Bin
+	PrivacyModel      model.Privacy
+	FriendModel       model.Friend
+	RouteShareModel   model.RouteShare
+	GeofenceModel     model.Geofence
+	LostAlertModel    model.LostAlert
+	NotificationModel model.Notification
+	JobRunModel       model.JobRun
+	HealthModel       model.Health
+	WeightModel       model.Weight
+	HouseholdModel    model.Household
+	DogGrantModel     model.DogGrant
+	LocationChannel   redismodel.LocationChannel
+	NotificationQueue redismodel.NotificationQueue
 }
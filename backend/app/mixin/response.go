@@ -160,6 +160,13 @@ func ResFile(c *gin.Context, filePath string) {
 	http.ServeContent(c.Writer, c.Request, filePath, fileInfo.ModTime(), file)
 }
 
+// ResAttachment
+func ResAttachment(c *gin.Context, contentType string, fileName string, data []byte) {
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+	c.Data(http.StatusOK, contentType, data)
+	c.Abort()
+}
+
 // ResError
 func ResError(c *gin.Context, err error, status ...int) {
 	ctx := c.Request.Context()
@@ -5,6 +5,7 @@ import (
 
 	"github.com/yiff028/comp90018-mobile-project/backend/app/model/schema"
 	"github.com/yiff028/comp90018-mobile-project/backend/pkg/errors"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/util"
 	"gorm.io/gorm"
 )
 
@@ -60,3 +61,54 @@ func (r *Bin) ListAll(ctx context.Context) (schema.BinList, error) {
 
 	return binList, nil
 }
+
+// ListNearby bins within radius metres of the point, nearest first
+func (r *Bin) ListNearby(ctx context.Context, latitude float64, longitude float64, radius float64, limit int) (schema.BinWithDistanceList, error) {
+	box := util.NewBoundingBox(latitude, longitude, radius)
+	db := r.withinBoundsDB(ctx, box, latitude, longitude).Having("distance <= ?", radius)
+	return r.findWithDistance(db, limit)
+}
+
+// ListWithinBounds bins inside the box, ordered by distance to the given point
+func (r *Bin) ListWithinBounds(ctx context.Context, box util.BoundingBox, latitude float64, longitude float64, limit int) (schema.BinWithDistanceList, error) {
+	db := r.withinBoundsDB(ctx, box, latitude, longitude)
+	return r.findWithDistance(db, limit)
+}
+
+// withinBoundsDB MBRContains lets mysql use the spatial index on location
+func (r *Bin) withinBoundsDB(ctx context.Context, box util.BoundingBox, latitude float64, longitude float64) *gorm.DB {
+	db := schema.GetBinDB(ctx, r.DB)
+	db = db.Select("bin.*, ST_Distance_Sphere(location, "+schema.SRID4326+") AS distance", util.PointWKT(latitude, longitude))
+	db = db.Where("MBRContains("+schema.SRID4326+", location)", box.PolygonWKT())
+	return db
+}
+
+func (r *Bin) findWithDistance(db *gorm.DB, limit int) (schema.BinWithDistanceList, error) {
+	binList := schema.BinWithDistanceList{}
+
+	db = db.Order("distance ASC")
+	if limit > 0 {
+		db = db.Limit(limit)
+	}
+
+	db = db.Find(&binList)
+	if err := db.Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return binList, nil
+}
+
+// BackfillLocation fill the spatial column of bins created before it existed
+func (r *Bin) BackfillLocation(ctx context.Context) (int64, error) {
+	db := schema.GetBinDB(ctx, r.DB)
+
+	raw := "UPDATE bin SET location = ST_GeomFromText(CONCAT('POINT(', longitude, ' ', latitude, ')'), 4326, 'axis-order=long-lat') " +
+		"where location IS NULL"
+
+	result := db.Exec(raw)
+	if err := result.Error; err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return result.RowsAffected, nil
+}
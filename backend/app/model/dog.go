@@ -6,9 +6,13 @@ import (
 
 	"github.com/yiff028/comp90018-mobile-project/backend/app/model/schema"
 	"github.com/yiff028/comp90018-mobile-project/backend/pkg/errors"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/util"
 	"gorm.io/gorm"
 )
 
+// maxNearbyDogCount most recently seen dogs considered by a nearby query
+const maxNearbyDogCount = 1000
+
 type Dog struct {
 	DB *gorm.DB
 }
@@ -23,11 +27,16 @@ func (d *Dog) Create(ctx context.Context, item *schema.Dog) error {
 	return nil
 }
 
-// List
+// List dogs of the user and of every household the user belongs to
 func (d *Dog) List(ctx context.Context, userId uint) (schema.DogList, error) {
-	db := schema.GetDogDB(ctx, d.DB).Debug()
+	return d.ListByRole(ctx, userId, schema.HouseholdRoleViewer)
+}
+
+// ListByRole dogs on which the user holds at least minRole
+func (d *Dog) ListByRole(ctx context.Context, userId uint, minRole string) (schema.DogList, error) {
+	db := schema.GetDogDB(ctx, d.DB)
 
-	db = db.Where("user_id = ?", userId)
+	db = db.Scopes(withRole(userId, schema.HouseholdRolesAtLeast(minRole)))
 	dogList := schema.DogList{}
 
 	db = db.Find(&dogList)
@@ -38,10 +47,24 @@ func (d *Dog) List(ctx context.Context, userId uint) (schema.DogList, error) {
 	return dogList, nil
 }
 
-// Delete
+// GetById
+func (d *Dog) GetById(ctx context.Context, dogId uint) (*schema.Dog, error) {
+	db := schema.GetDogDB(ctx, d.DB).Where("id = ?", dogId)
+
+	item := schema.Dog{}
+	ok, err := schema.FindOne(ctx, db, &item)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	} else if !ok {
+		return nil, nil
+	}
+	return &item, nil
+}
+
+// Delete only by an owner of the dog
 func (d *Dog) Delete(ctx context.Context, dogId uint, userId uint) error {
 	db := schema.GetDogDB(ctx, d.DB)
-	result := db.Where("id = ?", dogId).Where("user_id = ?", userId).Delete(&schema.Dog{})
+	result := db.Where("id = ?", dogId).Scopes(withRole(userId, schema.HouseholdRolesAtLeast(schema.HouseholdRoleOwner))).Delete(&schema.Dog{})
 	if err := result.Error; err != nil {
 		return errors.WithStack(err)
 	}
@@ -50,13 +73,18 @@ func (d *Dog) Delete(ctx context.Context, dogId uint, userId uint) error {
 
 // UpdateLocation
 func (d *Dog) UpdateLocation(ctx context.Context, dogId uint, userId uint, longitude float64, latitude float64) error {
+	return d.UpdateLocationAt(ctx, dogId, userId, longitude, latitude, time.Now())
+}
+
+// UpdateLocationAt location recorded at a given time, e.g. synced from offline points
+// userId is the walker, who must be allowed to walk the dog
+func (d *Dog) UpdateLocationAt(ctx context.Context, dogId uint, userId uint, longitude float64, latitude float64, at time.Time) error {
 	db := schema.GetDogDB(ctx, d.DB)
-	db = db.Where("id = ?", dogId).Where("user_id = ?", userId)
-	now := time.Now()
+	db = db.Where("id = ?", dogId).Scopes(withRole(userId, schema.HouseholdRolesAtLeast(schema.HouseholdRoleWalker)))
 	updateMap := map[string]interface{}{}
 	updateMap["longitude"] = longitude
 	updateMap["latitude"] = latitude
-	updateMap["location_updated_time"] = now
+	updateMap["location_updated_time"] = at
 
 	result := db.Updates(updateMap)
 	if err := result.Error; err != nil {
@@ -65,13 +93,44 @@ func (d *Dog) UpdateLocation(ctx context.Context, dogId uint, userId uint, longi
 	return nil
 }
 
-// ListCurrentDog
-func (d *Dog) ListCurrentDog(ctx context.Context) (schema.DogList, error) {
-	db := schema.GetDogDB(ctx, d.DB)
+// SetLost flag or unflag the dog as lost
+func (d *Dog) SetLost(ctx context.Context, dogId uint, lost bool, since *time.Time) error {
+	db := schema.GetDogDB(ctx, d.DB).Where("id = ?", dogId)
+	updateMap := map[string]interface{}{}
+	updateMap["is_lost"] = lost
+	updateMap["lost_since"] = since
+
+	result := db.Updates(updateMap)
+	if err := result.Error; err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// ListOwnerIdsNearby owners of the dogs seen within radius metres since the given time
+func (d *Dog) ListOwnerIdsNearby(ctx context.Context, latitude float64, longitude float64, radius float64, since time.Time) ([]uint, error) {
+	box := util.NewBoundingBox(latitude, longitude, radius)
+
+	db := schema.GetDogDB(ctx, d.DB).
+		Where("latitude BETWEEN ? AND ?", box.MinLatitude, box.MaxLatitude).
+		Where("longitude BETWEEN ? AND ?", box.MinLongitude, box.MaxLongitude).
+		Where("location_updated_time >= ?", since).
+		Where("ST_Distance_Sphere(POINT(longitude, latitude), POINT(?, ?)) <= ?", longitude, latitude, radius)
 
-	startTime := time.Now().Add(-300 * time.Minute)
+	userIds := []uint{}
+	if err := db.Distinct().Pluck("user_id", &userIds).Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return userIds, nil
+}
+
+// ListCurrentDog dogs whose location viewerId may see
+func (d *Dog) ListCurrentDog(ctx context.Context, viewerId uint, window time.Duration) (schema.DogList, error) {
+	db := schema.GetDogDB(ctx, d.DB).Select("dog.*").Scopes(visibleTo(viewerId))
 
-	db = db.Where("location_updated_time >= ?", startTime)
+	startTime := time.Now().Add(-window)
+
+	db = db.Where("dog.location_updated_time >= ?", startTime)
 	dogList := schema.DogList{}
 
 	db = db.Find(&dogList)
@@ -81,3 +140,126 @@ func (d *Dog) ListCurrentDog(ctx context.Context) (schema.DogList, error) {
 
 	return dogList, nil
 }
+
+// ListNearby dogs visible to viewerId located since the given time whose masked location may fall inside box
+// radius in metres around the point, 0 keeps every dog of the box
+// nothing is ordered or paged on the true coordinates, the caller masks the locations and does both on the masked ones
+func (d *Dog) ListNearby(ctx context.Context, viewerId uint, box util.BoundingBox, latitude float64, longitude float64, radius float64, since time.Time) (schema.DogList, error) {
+	// a fuzzed location may lie up to the owner's grid size away from the true one
+	box = box.Expand(schema.MaxFuzzMetres)
+
+	db := schema.GetDogDB(ctx, d.DB).Select("dog.*").Scopes(visibleTo(viewerId))
+	db = db.Where("dog.latitude BETWEEN ? AND ?", box.MinLatitude, box.MaxLatitude).
+		Where("dog.longitude BETWEEN ? AND ?", box.MinLongitude, box.MaxLongitude).
+		Where("dog.location_updated_time >= ?", since)
+	if radius > 0 {
+		db = db.Where("ST_Distance_Sphere(POINT(dog.longitude, dog.latitude), POINT(?, ?)) <= ? + COALESCE(user_privacy.fuzz_metres, 0)",
+			longitude, latitude, radius)
+	}
+	db = db.Order("dog.location_updated_time DESC").Limit(maxNearbyDogCount)
+
+	dogList := schema.DogList{}
+	if err := db.Find(&dogList).Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return dogList, nil
+}
+
+// ListImg image urls of every dog, used to find unreferenced uploads
+func (d *Dog) ListImg(ctx context.Context) ([]string, error) {
+	db := schema.GetDogDB(ctx, d.DB).Where("img <> ''")
+
+	var imgList []string
+	if err := db.Pluck("img", &imgList).Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return imgList, nil
+}
+
+// SetGoal
+func (d *Dog) SetGoal(ctx context.Context, dogId uint, userId uint, goalType string, goalValue float64, reminder string, timezone string) error {
+	db := schema.GetDogDB(ctx, d.DB).Where("id = ?", dogId).Scopes(withRole(userId, schema.HouseholdRolesAtLeast(schema.HouseholdRoleOwner)))
+	result := db.Updates(map[string]interface{}{
+		"goal_type":     goalType,
+		"goal_value":    goalValue,
+		"goal_reminder": reminder,
+		"goal_timezone": timezone,
+	})
+	if err := result.Error; err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// ListWithGoalReminder dogs with a goal and a reminder time
+func (d *Dog) ListWithGoalReminder(ctx context.Context) (schema.DogList, error) {
+	db := schema.GetDogDB(ctx, d.DB).Where("goal_reminder <> ''").Where("goal_type <> ''").Where("goal_value > 0")
+
+	dogList := schema.DogList{}
+	if err := db.Find(&dogList).Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return dogList, nil
+}
+
+// SetWeight current weight, kept in sync with the latest weight log
+func (d *Dog) SetWeight(ctx context.Context, dogId uint, weight float64) error {
+	db := schema.GetDogDB(ctx, d.DB).Where("id = ?", dogId)
+	if err := db.Update("weight", weight).Error; err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// SetHousehold move the dog into a household, 0 stops sharing it
+func (d *Dog) SetHousehold(ctx context.Context, dogId uint, householdId uint) error {
+	db := schema.GetDogDB(ctx, d.DB).Where("id = ?", dogId)
+	if err := db.Update("household_id", householdId).Error; err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// LeaveHousehold stop sharing the dogs userId created in the household
+func (d *Dog) LeaveHousehold(ctx context.Context, householdId uint, userId uint) error {
+	db := schema.GetDogDB(ctx, d.DB).Where("household_id = ?", householdId).Where("user_id = ?", userId)
+	if err := db.Update("household_id", 0).Error; err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// ListByHouseholdIds
+func (d *Dog) ListByHouseholdIds(ctx context.Context, householdIds []uint) (schema.DogList, error) {
+	dogList := schema.DogList{}
+	if len(householdIds) == 0 {
+		return dogList, nil
+	}
+	db := schema.GetDogDB(ctx, d.DB).Where("household_id IN ?", householdIds)
+
+	if err := db.Find(&dogList).Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return dogList, nil
+}
+
+// Update partial update, only the columns of updateMap change
+func (d *Dog) Update(ctx context.Context, dogId uint, updateMap map[string]interface{}) error {
+	db := schema.GetDogDB(ctx, d.DB).Where("id = ?", dogId)
+	updateMap["updated_time"] = gorm.Expr("CURRENT_TIMESTAMP")
+
+	result := db.Updates(updateMap)
+	if err := result.Error; err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// SetImg url of the primary photo
+func (d *Dog) SetImg(ctx context.Context, dogId uint, img string) error {
+	db := schema.GetDogDB(ctx, d.DB).Where("id = ?", dogId)
+	if err := db.Update("img", img).Error; err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package model
+
+import (
+	"context"
+
+	"github.com/yiff028/comp90018-mobile-project/backend/app/model/schema"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/errors"
+	"gorm.io/gorm"
+)
+
+type DogChange struct {
+	DB *gorm.DB
+}
+
+// CreateBatch
+func (d *DogChange) CreateBatch(ctx context.Context, itemList schema.DogChangeList) error {
+	if len(itemList) == 0 {
+		return nil
+	}
+	db := schema.GetDogChangeDB(ctx, d.DB)
+	if err := db.Create(&itemList).Error; err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// ListByDogId newest first
+func (d *DogChange) ListByDogId(ctx context.Context, dogId uint, limit int) (schema.DogChangeList, error) {
+	db := schema.GetDogChangeDB(ctx, d.DB).Where("dog_id = ?", dogId).Order("id DESC")
+	if limit > 0 {
+		db = db.Limit(limit)
+	}
+
+	changeList := schema.DogChangeList{}
+	if err := db.Find(&changeList).Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return changeList, nil
+}
@@ -0,0 +1,113 @@
+package model
+
+import (
+	"context"
+	"time"
+
+	"github.com/yiff028/comp90018-mobile-project/backend/app/model/schema"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/errors"
+	"gorm.io/gorm"
+)
+
+type DogGrant struct {
+	DB *gorm.DB
+}
+
+// Create
+func (g *DogGrant) Create(ctx context.Context, item *schema.DogGrant) error {
+	db := schema.GetDogGrantDB(ctx, g.DB)
+	result := db.Create(item)
+	if err := result.Error; err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// GetById
+func (g *DogGrant) GetById(ctx context.Context, grantId uint) (*schema.DogGrant, error) {
+	db := schema.GetDogGrantDB(ctx, g.DB).Where("id = ?", grantId)
+
+	item := schema.DogGrant{}
+	ok, err := schema.FindOne(ctx, db, &item)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	} else if !ok {
+		return nil, nil
+	}
+	return &item, nil
+}
+
+// Revoke only by the owner who gave the grant
+func (g *DogGrant) Revoke(ctx context.Context, grantId uint, ownerId uint) (int64, error) {
+	db := schema.GetDogGrantDB(ctx, g.DB).Where("id = ?", grantId).Where("owner_id = ?", ownerId).Where("revoked_time IS NULL")
+	result := db.Update("revoked_time", time.Now())
+	if err := result.Error; err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return result.RowsAffected, nil
+}
+
+// ListByOwnerId grants given by the owner, newest first
+func (g *DogGrant) ListByOwnerId(ctx context.Context, ownerId uint) (schema.DogGrantList, error) {
+	db := schema.GetDogGrantDB(ctx, g.DB).Where("owner_id = ?", ownerId).Order("id DESC")
+
+	grantList := schema.DogGrantList{}
+	if err := db.Find(&grantList).Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return grantList, nil
+}
+
+// ListByGranteeId grants received which are not over yet, current and upcoming
+func (g *DogGrant) ListByGranteeId(ctx context.Context, granteeId uint, now time.Time) (schema.DogGrantList, error) {
+	db := schema.GetDogGrantDB(ctx, g.DB).Where("grantee_id = ?", granteeId).
+		Where("revoked_time IS NULL").Where("end_time > ?", now).Order("start_time ASC")
+
+	grantList := schema.DogGrantList{}
+	if err := db.Find(&grantList).Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return grantList, nil
+}
+
+// ListActive grants of the grantee valid at the given time, restricted to dogIds unless empty
+func (g *DogGrant) ListActive(ctx context.Context, granteeId uint, dogIds []uint, at time.Time) (schema.DogGrantList, error) {
+	db := schema.GetDogGrantDB(ctx, g.DB).Where("grantee_id = ?", granteeId).Scopes(activeAt(at))
+	if len(dogIds) > 0 {
+		db = db.Where("dog_id IN ?", dogIds)
+	}
+
+	grantList := schema.DogGrantList{}
+	if err := db.Find(&grantList).Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return grantList, nil
+}
+
+// ListEnded grants past their end time which were not revoked yet
+func (g *DogGrant) ListEnded(ctx context.Context, now time.Time) (schema.DogGrantList, error) {
+	db := schema.GetDogGrantDB(ctx, g.DB).Where("revoked_time IS NULL").Where("end_time <= ?", now)
+
+	grantList := schema.DogGrantList{}
+	if err := db.Find(&grantList).Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return grantList, nil
+}
+
+// Expire revoke an ended grant at its end time, 0 rows when revoked in the meantime
+func (g *DogGrant) Expire(ctx context.Context, grantId uint) (int64, error) {
+	db := schema.GetDogGrantDB(ctx, g.DB).Where("id = ?", grantId).Where("revoked_time IS NULL")
+	result := db.Update("revoked_time", gorm.Expr("end_time"))
+	if err := result.Error; err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return result.RowsAffected, nil
+}
+
+// activeAt grants whose window contains the time and which were not revoked by then
+func activeAt(at time.Time) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("revoked_time IS NULL OR revoked_time > ?", at).Where("start_time <= ?", at).Where("end_time > ?", at)
+	}
+}
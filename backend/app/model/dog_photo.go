@@ -0,0 +1,103 @@
+package model
+
+import (
+	"context"
+
+	"github.com/yiff028/comp90018-mobile-project/backend/app/model/schema"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/errors"
+	"gorm.io/gorm"
+)
+
+type DogPhoto struct {
+	DB *gorm.DB
+}
+
+// Create
+func (d *DogPhoto) Create(ctx context.Context, item *schema.DogPhoto) error {
+	db := schema.GetDogPhotoDB(ctx, d.DB)
+	result := db.Create(item)
+	if err := result.Error; err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// GetById
+func (d *DogPhoto) GetById(ctx context.Context, photoId uint) (*schema.DogPhoto, error) {
+	db := schema.GetDogPhotoDB(ctx, d.DB).Where("id = ?", photoId)
+
+	item := schema.DogPhoto{}
+	ok, err := schema.FindOne(ctx, db, &item)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	} else if !ok {
+		return nil, nil
+	}
+	return &item, nil
+}
+
+// Delete
+func (d *DogPhoto) Delete(ctx context.Context, photoId uint) (int64, error) {
+	db := schema.GetDogPhotoDB(ctx, d.DB)
+	result := db.Where("id = ?", photoId).Delete(&schema.DogPhoto{})
+	if err := result.Error; err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return result.RowsAffected, nil
+}
+
+// ListByDogId in gallery order
+func (d *DogPhoto) ListByDogId(ctx context.Context, dogId uint) (schema.DogPhotoList, error) {
+	db := schema.GetDogPhotoDB(ctx, d.DB).Where("dog_id = ?", dogId).Order("position ASC").Order("id ASC")
+
+	photoList := schema.DogPhotoList{}
+	if err := db.Find(&photoList).Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return photoList, nil
+}
+
+// SetPosition
+func (d *DogPhoto) SetPosition(ctx context.Context, photoId uint, position int) error {
+	db := schema.GetDogPhotoDB(ctx, d.DB).Where("id = ?", photoId)
+	if err := db.Update("position", position).Error; err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// SetPrimary flag one photo of the dog as primary and unflag the others
+func (d *DogPhoto) SetPrimary(ctx context.Context, dogId uint, photoId uint) error {
+	db := schema.GetDogPhotoDB(ctx, d.DB).Where("dog_id = ?", dogId)
+	if err := db.Update("is_primary", gorm.Expr("id = ?", photoId)).Error; err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// ListUrl urls of every photo, used to find unreferenced uploads
+func (d *DogPhoto) ListUrl(ctx context.Context) ([]string, error) {
+	db := schema.GetDogPhotoDB(ctx, d.DB)
+
+	var urlList []string
+	if err := db.Pluck("url", &urlList).Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return urlList, nil
+}
+
+// BackfillFromDog one primary photo for each dog created before the gallery existed
+func (d *DogPhoto) BackfillFromDog(ctx context.Context) (int64, error) {
+	db := schema.GetDogPhotoDB(ctx, d.DB)
+
+	raw := "INSERT INTO dog_photo (dog_id, user_id, url, position, is_primary, created_time) " +
+		"SELECT dog.id, dog.user_id, dog.img, 0, 1, dog.created_time FROM dog " +
+		"WHERE dog.img <> '' AND dog.deleted_at IS NULL " +
+		"AND NOT EXISTS (SELECT 1 FROM dog_photo WHERE dog_photo.dog_id = dog.id)"
+
+	result := db.Exec(raw)
+	if err := result.Error; err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,57 @@
+package model
+
+import (
+	"context"
+
+	"github.com/yiff028/comp90018-mobile-project/backend/app/model/schema"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Friend struct {
+	DB *gorm.DB
+}
+
+// Add friendId as a friend of userId, adding twice is a no-op
+func (f *Friend) Add(ctx context.Context, userId uint, friendId uint) error {
+	db := schema.GetFriendDB(ctx, f.DB)
+	item := schema.Friend{UserId: userId, FriendId: friendId}
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&item)
+	if err := result.Error; err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// Remove
+func (f *Friend) Remove(ctx context.Context, userId uint, friendId uint) error {
+	db := schema.GetFriendDB(ctx, f.DB)
+	result := db.Where("user_id = ?", userId).Where("friend_id = ?", friendId).Delete(&schema.Friend{})
+	if err := result.Error; err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// List
+func (f *Friend) List(ctx context.Context, userId uint) (schema.FriendList, error) {
+	db := schema.GetFriendDB(ctx, f.DB).Where("user_id = ?", userId)
+
+	friendList := schema.FriendList{}
+	if err := db.Find(&friendList).Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return friendList, nil
+}
+
+// ListByFriendId users who added friendId as a friend
+func (f *Friend) ListByFriendId(ctx context.Context, friendId uint) (schema.FriendList, error) {
+	db := schema.GetFriendDB(ctx, f.DB).Where("friend_id = ?", friendId)
+
+	friendList := schema.FriendList{}
+	if err := db.Find(&friendList).Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return friendList, nil
+}
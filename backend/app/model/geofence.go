@@ -0,0 +1,96 @@
+package model
+
+import (
+	"context"
+
+	"github.com/yiff028/comp90018-mobile-project/backend/app/model/schema"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/errors"
+	"gorm.io/gorm"
+)
+
+type Geofence struct {
+	DB *gorm.DB
+}
+
+// Create
+func (g *Geofence) Create(ctx context.Context, item *schema.Geofence) error {
+	db := schema.GetGeofenceDB(ctx, g.DB)
+	result := db.Create(item)
+	if err := result.Error; err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// Update the geometry and name of one of the user's geofences
+func (g *Geofence) Update(ctx context.Context, item *schema.Geofence) (int64, error) {
+	db := schema.GetGeofenceDB(ctx, g.DB).Where("id = ?", item.ID).Where("user_id = ?", item.UserId)
+	updateMap := map[string]interface{}{}
+	updateMap["dog_id"] = item.DogId
+	updateMap["name"] = item.Name
+	updateMap["kind"] = item.Kind
+	updateMap["longitude"] = item.Longitude
+	updateMap["latitude"] = item.Latitude
+	updateMap["radius"] = item.Radius
+	updateMap["polygon"] = item.Polygon
+	updateMap["updated_time"] = gorm.Expr("CURRENT_TIMESTAMP")
+
+	result := db.Updates(updateMap)
+	if err := result.Error; err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return result.RowsAffected, nil
+}
+
+// Delete
+func (g *Geofence) Delete(ctx context.Context, id uint, userId uint) error {
+	db := schema.GetGeofenceDB(ctx, g.DB)
+	result := db.Where("id = ?", id).Where("user_id = ?", userId).Delete(&schema.Geofence{})
+	if err := result.Error; err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// ListByUserId
+func (g *Geofence) ListByUserId(ctx context.Context, userId uint) (schema.GeofenceList, error) {
+	db := schema.GetGeofenceDB(ctx, g.DB).Where("user_id = ?", userId)
+
+	geofenceList := schema.GeofenceList{}
+	if err := db.Find(&geofenceList).Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return geofenceList, nil
+}
+
+// CreateEvents
+func (g *Geofence) CreateEvents(ctx context.Context, events schema.GeofenceEventList) error {
+	if len(events) == 0 {
+		return nil
+	}
+	db := schema.GetGeofenceEventDB(ctx, g.DB)
+	result := db.Create(&events)
+	if err := result.Error; err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// ListEvent newest first, dogId and geofenceId 0 do not filter
+func (g *Geofence) ListEvent(ctx context.Context, userId uint, dogId uint, geofenceId uint, page schema.PaginationParam) (schema.GeofenceEventList, *schema.PaginationResult, error) {
+	db := schema.GetGeofenceEventDB(ctx, g.DB).Where("user_id = ?", userId)
+	if dogId > 0 {
+		db = db.Where("dog_id = ?", dogId)
+	}
+	if geofenceId > 0 {
+		db = db.Where("geofence_id = ?", geofenceId)
+	}
+	db = db.Order("created_time DESC").Order("id DESC")
+
+	eventList := schema.GeofenceEventList{}
+	pageResult, err := schema.WrapPageQuery(ctx, db, page, &eventList)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	return eventList, pageResult, nil
+}
@@ -0,0 +1,267 @@
+package model
+
+import (
+	"context"
+	"time"
+
+	"github.com/yiff028/comp90018-mobile-project/backend/app/model/schema"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/errors"
+	"gorm.io/gorm"
+)
+
+type Health struct {
+	DB *gorm.DB
+}
+
+// CreateVaccination
+func (h *Health) CreateVaccination(ctx context.Context, item *schema.Vaccination) error {
+	db := schema.GetVaccinationDB(ctx, h.DB)
+	if err := db.Create(item).Error; err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// UpdateVaccination
+func (h *Health) UpdateVaccination(ctx context.Context, item *schema.Vaccination) (int64, error) {
+	db := schema.GetVaccinationDB(ctx, h.DB).Where("id = ?", item.ID).Where("dog_id = ?", item.DogId)
+	updateMap := map[string]interface{}{}
+	updateMap["name"] = item.Name
+	updateMap["administered_date"] = item.AdministeredDate
+	updateMap["due_date"] = item.DueDate
+	updateMap["vet_name"] = item.VetName
+	updateMap["notes"] = item.Notes
+	updateMap["updated_time"] = gorm.Expr("CURRENT_TIMESTAMP")
+
+	result := db.Updates(updateMap)
+	if err := result.Error; err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return result.RowsAffected, nil
+}
+
+// GetVaccination
+func (h *Health) GetVaccination(ctx context.Context, id uint) (*schema.Vaccination, error) {
+	db := schema.GetVaccinationDB(ctx, h.DB).Where("id = ?", id)
+
+	item := schema.Vaccination{}
+	ok, err := schema.FindOne(ctx, db, &item)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	} else if !ok {
+		return nil, nil
+	}
+	return &item, nil
+}
+
+// DeleteVaccination
+func (h *Health) DeleteVaccination(ctx context.Context, id uint, dogId uint) (int64, error) {
+	db := schema.GetVaccinationDB(ctx, h.DB)
+	result := db.Where("id = ?", id).Where("dog_id = ?", dogId).Delete(&schema.Vaccination{})
+	if err := result.Error; err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return result.RowsAffected, nil
+}
+
+// ListVaccination latest first
+func (h *Health) ListVaccination(ctx context.Context, dogId uint) (schema.VaccinationList, error) {
+	db := schema.GetVaccinationDB(ctx, h.DB).Where("dog_id = ?", dogId).
+		Order("administered_date DESC").Order("id DESC")
+
+	vaccinationList := schema.VaccinationList{}
+	if err := db.Find(&vaccinationList).Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return vaccinationList, nil
+}
+
+// ListDueVaccination due before the given time, a later record of the same vaccine replaces an earlier one
+// nil dogIds for every dog
+func (h *Health) ListDueVaccination(ctx context.Context, dogIds []uint, before time.Time) (schema.VaccinationList, error) {
+	db := schema.GetVaccinationDB(ctx, h.DB).Where("due_date IS NOT NULL").Where("due_date < ?", before).
+		Where("NOT EXISTS (?)", h.DB.Table("dog_vaccination AS newer").Select("1").
+			Where("newer.dog_id = dog_vaccination.dog_id").Where("newer.name = dog_vaccination.name").
+			Where("newer.id > dog_vaccination.id").Where("newer.deleted_at IS NULL"))
+	if dogIds != nil {
+		db = db.Where("dog_id IN ?", dogIds)
+	}
+
+	vaccinationList := schema.VaccinationList{}
+	if err := db.Order("due_date ASC").Find(&vaccinationList).Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return vaccinationList, nil
+}
+
+// CreateMedication
+func (h *Health) CreateMedication(ctx context.Context, item *schema.Medication) error {
+	db := schema.GetMedicationDB(ctx, h.DB)
+	if err := db.Create(item).Error; err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// UpdateMedication
+func (h *Health) UpdateMedication(ctx context.Context, item *schema.Medication) (int64, error) {
+	db := schema.GetMedicationDB(ctx, h.DB).Where("id = ?", item.ID).Where("dog_id = ?", item.DogId)
+	updateMap := map[string]interface{}{}
+	updateMap["name"] = item.Name
+	updateMap["dosage"] = item.Dosage
+	updateMap["interval_hours"] = item.IntervalHours
+	updateMap["start_time"] = item.StartTime
+	updateMap["end_time"] = item.EndTime
+	updateMap["notes"] = item.Notes
+	updateMap["updated_time"] = gorm.Expr("CURRENT_TIMESTAMP")
+
+	result := db.Updates(updateMap)
+	if err := result.Error; err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return result.RowsAffected, nil
+}
+
+// GetMedication
+func (h *Health) GetMedication(ctx context.Context, id uint) (*schema.Medication, error) {
+	db := schema.GetMedicationDB(ctx, h.DB).Where("id = ?", id)
+
+	item := schema.Medication{}
+	ok, err := schema.FindOne(ctx, db, &item)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	} else if !ok {
+		return nil, nil
+	}
+	return &item, nil
+}
+
+// DeleteMedication
+func (h *Health) DeleteMedication(ctx context.Context, id uint, dogId uint) (int64, error) {
+	db := schema.GetMedicationDB(ctx, h.DB)
+	result := db.Where("id = ?", id).Where("dog_id = ?", dogId).Delete(&schema.Medication{})
+	if err := result.Error; err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return result.RowsAffected, nil
+}
+
+// ListMedication latest first
+func (h *Health) ListMedication(ctx context.Context, dogId uint) (schema.MedicationList, error) {
+	db := schema.GetMedicationDB(ctx, h.DB).Where("dog_id = ?", dogId).Order("start_time DESC")
+
+	medicationList := schema.MedicationList{}
+	if err := db.Find(&medicationList).Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return medicationList, nil
+}
+
+// ListActiveMedication courses not ended at the given time, nil dogIds for every dog
+func (h *Health) ListActiveMedication(ctx context.Context, dogIds []uint, at time.Time) (schema.MedicationList, error) {
+	db := schema.GetMedicationDB(ctx, h.DB).Where("end_time IS NULL OR end_time >= ?", at)
+	if dogIds != nil {
+		db = db.Where("dog_id IN ?", dogIds)
+	}
+
+	medicationList := schema.MedicationList{}
+	if err := db.Find(&medicationList).Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return medicationList, nil
+}
+
+// CreateVetVisit
+func (h *Health) CreateVetVisit(ctx context.Context, item *schema.VetVisit) error {
+	db := schema.GetVetVisitDB(ctx, h.DB)
+	if err := db.Create(item).Error; err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// UpdateVetVisit
+func (h *Health) UpdateVetVisit(ctx context.Context, item *schema.VetVisit) (int64, error) {
+	db := schema.GetVetVisitDB(ctx, h.DB).Where("id = ?", item.ID).Where("dog_id = ?", item.DogId)
+	updateMap := map[string]interface{}{}
+	updateMap["visit_time"] = item.VisitTime
+	updateMap["clinic"] = item.Clinic
+	updateMap["reason"] = item.Reason
+	updateMap["notes"] = item.Notes
+	updateMap["attachments"] = item.Attachments
+	updateMap["follow_up_date"] = item.FollowUpDate
+	updateMap["updated_time"] = gorm.Expr("CURRENT_TIMESTAMP")
+
+	result := db.Updates(updateMap)
+	if err := result.Error; err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return result.RowsAffected, nil
+}
+
+// GetVetVisit
+func (h *Health) GetVetVisit(ctx context.Context, id uint) (*schema.VetVisit, error) {
+	db := schema.GetVetVisitDB(ctx, h.DB).Where("id = ?", id)
+
+	item := schema.VetVisit{}
+	ok, err := schema.FindOne(ctx, db, &item)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	} else if !ok {
+		return nil, nil
+	}
+	return &item, nil
+}
+
+// DeleteVetVisit
+func (h *Health) DeleteVetVisit(ctx context.Context, id uint, dogId uint) (int64, error) {
+	db := schema.GetVetVisitDB(ctx, h.DB)
+	result := db.Where("id = ?", id).Where("dog_id = ?", dogId).Delete(&schema.VetVisit{})
+	if err := result.Error; err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return result.RowsAffected, nil
+}
+
+// ListVetVisit latest first
+func (h *Health) ListVetVisit(ctx context.Context, dogId uint) (schema.VetVisitList, error) {
+	db := schema.GetVetVisitDB(ctx, h.DB).Where("dog_id = ?", dogId).Order("visit_time DESC")
+
+	visitList := schema.VetVisitList{}
+	if err := db.Find(&visitList).Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return visitList, nil
+}
+
+// ListDueFollowUp follow ups due before the given time with no later visit of the dog, nil dogIds for every dog
+func (h *Health) ListDueFollowUp(ctx context.Context, dogIds []uint, before time.Time) (schema.VetVisitList, error) {
+	db := schema.GetVetVisitDB(ctx, h.DB).Where("follow_up_date IS NOT NULL").Where("follow_up_date < ?", before).
+		Where("NOT EXISTS (?)", h.DB.Table("dog_vet_visit AS later").Select("1").
+			Where("later.dog_id = dog_vet_visit.dog_id").Where("later.visit_time > dog_vet_visit.visit_time").
+			Where("later.deleted_at IS NULL"))
+	if dogIds != nil {
+		db = db.Where("dog_id IN ?", dogIds)
+	}
+
+	visitList := schema.VetVisitList{}
+	if err := db.Order("follow_up_date ASC").Find(&visitList).Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return visitList, nil
+}
+
+// ListAttachment urls attached to vet visits, used to find unreferenced uploads
+func (h *Health) ListAttachment(ctx context.Context) ([]string, error) {
+	db := schema.GetVetVisitDB(ctx, h.DB).Where("attachments IS NOT NULL")
+
+	visitList := schema.VetVisitList{}
+	if err := db.Select("attachments").Find(&visitList).Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var urlList []string
+	for _, visit := range visitList {
+		urlList = append(urlList, visit.Attachments...)
+	}
+	return urlList, nil
+}
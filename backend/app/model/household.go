@@ -0,0 +1,211 @@
+package model
+
+import (
+	"context"
+	"time"
+
+	"github.com/yiff028/comp90018-mobile-project/backend/app/model/schema"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/errors"
+	"gorm.io/gorm"
+)
+
+type Household struct {
+	DB *gorm.DB
+}
+
+// Create
+func (h *Household) Create(ctx context.Context, item *schema.Household) error {
+	db := schema.GetHouseholdDB(ctx, h.DB)
+	result := db.Create(item)
+	if err := result.Error; err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// GetById
+func (h *Household) GetById(ctx context.Context, householdId uint) (*schema.Household, error) {
+	db := schema.GetHouseholdDB(ctx, h.DB).Where("id = ?", householdId)
+
+	item := schema.Household{}
+	ok, err := schema.FindOne(ctx, db, &item)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	} else if !ok {
+		return nil, nil
+	}
+	return &item, nil
+}
+
+// ListByUserId households the user is a member of
+func (h *Household) ListByUserId(ctx context.Context, userId uint) (schema.HouseholdList, error) {
+	db := schema.GetHouseholdDB(ctx, h.DB).
+		Where("id IN (?)", schema.GetHouseholdMemberDB(ctx, h.DB).Select("household_id").Where("user_id = ?", userId)).
+		Order("id ASC")
+
+	householdList := schema.HouseholdList{}
+	if err := db.Find(&householdList).Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return householdList, nil
+}
+
+// AddMember joining twice keeps the existing role
+func (h *Household) AddMember(ctx context.Context, item *schema.HouseholdMember) error {
+	db := schema.GetHouseholdMemberDB(ctx, h.DB)
+	result := db.Where("household_id = ?", item.HouseholdId).Where("user_id = ?", item.UserId).FirstOrCreate(item)
+	if err := result.Error; err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// GetMember
+func (h *Household) GetMember(ctx context.Context, householdId uint, userId uint) (*schema.HouseholdMember, error) {
+	db := schema.GetHouseholdMemberDB(ctx, h.DB).Where("household_id = ?", householdId).Where("user_id = ?", userId)
+
+	item := schema.HouseholdMember{}
+	ok, err := schema.FindOne(ctx, db, &item)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	} else if !ok {
+		return nil, nil
+	}
+	return &item, nil
+}
+
+// ListMember members of the households
+func (h *Household) ListMember(ctx context.Context, householdIds []uint) (schema.HouseholdMemberList, error) {
+	memberList := schema.HouseholdMemberList{}
+	if len(householdIds) == 0 {
+		return memberList, nil
+	}
+	db := schema.GetHouseholdMemberDB(ctx, h.DB).Where("household_id IN ?", householdIds).Order("id ASC")
+
+	if err := db.Find(&memberList).Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return memberList, nil
+}
+
+// ListRoleByUserId role of the user in each of the households they belong to
+func (h *Household) ListRoleByUserId(ctx context.Context, userId uint) (map[uint]string, error) {
+	db := schema.GetHouseholdMemberDB(ctx, h.DB).Where("user_id = ?", userId)
+
+	memberList := schema.HouseholdMemberList{}
+	if err := db.Find(&memberList).Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	roleMap := make(map[uint]string, len(memberList))
+	for _, member := range memberList {
+		roleMap[member.HouseholdId] = member.Role
+	}
+	return roleMap, nil
+}
+
+// UpdateMemberRole
+func (h *Household) UpdateMemberRole(ctx context.Context, householdId uint, userId uint, role string) (int64, error) {
+	db := schema.GetHouseholdMemberDB(ctx, h.DB).Where("household_id = ?", householdId).Where("user_id = ?", userId)
+	result := db.Updates(map[string]interface{}{
+		"role":         role,
+		"updated_time": gorm.Expr("CURRENT_TIMESTAMP"),
+	})
+	if err := result.Error; err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return result.RowsAffected, nil
+}
+
+// RemoveMember
+func (h *Household) RemoveMember(ctx context.Context, householdId uint, userId uint) (int64, error) {
+	db := schema.GetHouseholdMemberDB(ctx, h.DB)
+	result := db.Where("household_id = ?", householdId).Where("user_id = ?", userId).Delete(&schema.HouseholdMember{})
+	if err := result.Error; err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return result.RowsAffected, nil
+}
+
+// CountOwner
+func (h *Household) CountOwner(ctx context.Context, householdId uint) (int64, error) {
+	db := schema.GetHouseholdMemberDB(ctx, h.DB).Where("household_id = ?", householdId).Where("role = ?", schema.HouseholdRoleOwner)
+
+	var count int64
+	if err := db.Count(&count).Error; err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return count, nil
+}
+
+// CreateInvite
+func (h *Household) CreateInvite(ctx context.Context, item *schema.HouseholdInvite) error {
+	db := schema.GetHouseholdInviteDB(ctx, h.DB)
+	result := db.Create(item)
+	if err := result.Error; err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// GetInviteByToken
+func (h *Household) GetInviteByToken(ctx context.Context, token string) (*schema.HouseholdInvite, error) {
+	db := schema.GetHouseholdInviteDB(ctx, h.DB).Where("token = ?", token)
+
+	item := schema.HouseholdInvite{}
+	ok, err := schema.FindOne(ctx, db, &item)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	} else if !ok {
+		return nil, nil
+	}
+	return &item, nil
+}
+
+// AcceptInvite marks the invite used, 0 rows when someone accepted it first or it expired
+func (h *Household) AcceptInvite(ctx context.Context, inviteId uint, userId uint) (int64, error) {
+	now := time.Now()
+	db := schema.GetHouseholdInviteDB(ctx, h.DB).
+		Where("id = ?", inviteId).
+		Where("accepted_by = 0").
+		Where("expires_time > ?", now)
+	result := db.Updates(map[string]interface{}{
+		"accepted_by":   userId,
+		"accepted_time": now,
+	})
+	if err := result.Error; err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return result.RowsAffected, nil
+}
+
+// GetDogRole role of userId on the dog, empty when the user may not access it
+func (h *Household) GetDogRole(ctx context.Context, dogItem *schema.Dog, userId uint) (string, error) {
+	if dogItem.UserId == userId || dogItem.HouseholdId == 0 {
+		return dogItem.RoleOf(userId, ""), nil
+	}
+	member, err := h.GetMember(ctx, dogItem.HouseholdId, userId)
+	if err != nil {
+		return "", err
+	}
+	if member == nil {
+		return "", nil
+	}
+	return dogItem.RoleOf(userId, member.Role), nil
+}
+
+// withRole dogs userId created or holds one of the roles on, through their household or a current grant
+func withRole(userId uint, roles []string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		query, args := roleCondition(userId, roles)
+		return db.Where(query, args...)
+	}
+}
+
+// roleCondition the withRole condition on the dog table, for callers combining it with others
+func roleCondition(userId uint, roles []string) (string, []interface{}) {
+	now := time.Now()
+	query := "dog.user_id = ? OR " +
+		"dog.household_id IN (SELECT household_id FROM household_member WHERE household_member.user_id = ? AND household_member.role IN ?) OR " +
+		"dog.id IN (SELECT dog_id FROM dog_grant WHERE dog_grant.grantee_id = ? AND dog_grant.role IN ? AND dog_grant.revoked_time IS NULL AND dog_grant.start_time <= ? AND dog_grant.end_time > ?)"
+	return query, []interface{}{userId, userId, roles, userId, roles, now, now}
+}
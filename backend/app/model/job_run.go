@@ -0,0 +1,49 @@
+package model
+
+import (
+	"context"
+	"time"
+
+	"github.com/yiff028/comp90018-mobile-project/backend/app/model/schema"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/errors"
+	"gorm.io/gorm"
+)
+
+type JobRun struct {
+	DB *gorm.DB
+}
+
+// Create
+func (j *JobRun) Create(ctx context.Context, item *schema.JobRun) error {
+	db := schema.GetJobRunDB(ctx, j.DB)
+	result := db.Create(item)
+	if err := result.Error; err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// Finish
+func (j *JobRun) Finish(ctx context.Context, id uint, status string, attempts int, errMsg string, finished time.Time) error {
+	db := schema.GetJobRunDB(ctx, j.DB).Where("id = ?", id)
+	result := db.Updates(map[string]interface{}{
+		"status":        status,
+		"attempts":      attempts,
+		"error":         errMsg,
+		"finished_time": finished,
+	})
+	if err := result.Error; err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// DeleteBefore prune the history
+func (j *JobRun) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+	db := schema.GetJobRunDB(ctx, j.DB).Where("started_time < ?", before)
+	result := db.Delete(&schema.JobRun{})
+	if err := result.Error; err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,122 @@
+package model
+
+import (
+	"context"
+	"time"
+
+	"github.com/yiff028/comp90018-mobile-project/backend/app/model/schema"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/errors"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/util"
+	"gorm.io/gorm"
+)
+
+type LostAlert struct {
+	DB *gorm.DB
+}
+
+// Create
+func (l *LostAlert) Create(ctx context.Context, item *schema.LostAlert) error {
+	db := schema.GetLostAlertDB(ctx, l.DB)
+	result := db.Create(item)
+	if err := result.Error; err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// GetById
+func (l *LostAlert) GetById(ctx context.Context, alertId uint) (*schema.LostAlert, error) {
+	db := schema.GetLostAlertDB(ctx, l.DB).Where("id = ?", alertId)
+
+	item := schema.LostAlert{}
+	ok, err := schema.FindOne(ctx, db, &item)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	} else if !ok {
+		return nil, nil
+	}
+	return &item, nil
+}
+
+// GetOpenByDogId
+func (l *LostAlert) GetOpenByDogId(ctx context.Context, dogId uint) (*schema.LostAlert, error) {
+	db := schema.GetLostAlertDB(ctx, l.DB).Where("dog_id = ?", dogId).Where("status = ?", schema.LostAlertStatusOpen)
+
+	item := schema.LostAlert{}
+	ok, err := schema.FindOne(ctx, db, &item)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	} else if !ok {
+		return nil, nil
+	}
+	return &item, nil
+}
+
+// Resolve returns 0 rows if the alert is not open or not the user's
+func (l *LostAlert) Resolve(ctx context.Context, alertId uint, userId uint) (int64, error) {
+	db := schema.GetLostAlertDB(ctx, l.DB).Where("id = ?", alertId).Where("user_id = ?", userId).Where("status = ?", schema.LostAlertStatusOpen)
+	updateMap := map[string]interface{}{}
+	updateMap["status"] = schema.LostAlertStatusResolved
+	updateMap["resolved_time"] = time.Now()
+
+	result := db.Updates(updateMap)
+	if err := result.Error; err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return result.RowsAffected, nil
+}
+
+// ListNearbyOpen open alerts last seen within radius metres, nearest first
+func (l *LostAlert) ListNearbyOpen(ctx context.Context, latitude float64, longitude float64, radius float64, limit int) (schema.LostAlertWithDogList, error) {
+	box := util.NewBoundingBox(latitude, longitude, radius)
+	distanceSQL := "ST_Distance_Sphere(POINT(lost_alert.longitude, lost_alert.latitude), POINT(?, ?))"
+
+	db := schema.GetLostAlertDB(ctx, l.DB).
+		Select("lost_alert.*, dog.name AS dog_name, dog.breed AS dog_breed, dog.img AS dog_img, "+distanceSQL+" AS distance", longitude, latitude).
+		Joins("JOIN dog ON dog.id = lost_alert.dog_id").
+		Where("lost_alert.status = ?", schema.LostAlertStatusOpen).
+		Where("lost_alert.latitude BETWEEN ? AND ?", box.MinLatitude, box.MaxLatitude).
+		Where("lost_alert.longitude BETWEEN ? AND ?", box.MinLongitude, box.MaxLongitude).
+		Where(distanceSQL+" <= ?", longitude, latitude, radius).
+		Order("distance ASC").
+		Limit(limit)
+
+	alertList := schema.LostAlertWithDogList{}
+	if err := db.Find(&alertList).Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return alertList, nil
+}
+
+// CreateSighting
+func (l *LostAlert) CreateSighting(ctx context.Context, item *schema.LostSighting) error {
+	db := schema.GetLostSightingDB(ctx, l.DB)
+	result := db.Create(item)
+	if err := result.Error; err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// ListSighting newest first
+func (l *LostAlert) ListSighting(ctx context.Context, alertId uint) (schema.LostSightingList, error) {
+	db := schema.GetLostSightingDB(ctx, l.DB).Where("alert_id = ?", alertId).Order("seen_time DESC")
+
+	sightingList := schema.LostSightingList{}
+	if err := db.Find(&sightingList).Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return sightingList, nil
+}
+
+// ListPhotoUrl photo urls of alerts and sightings, used to find unreferenced uploads
+func (l *LostAlert) ListPhotoUrl(ctx context.Context) ([]string, error) {
+	var alertList, sightingList []string
+	if err := schema.GetLostAlertDB(ctx, l.DB).Where("photo_url <> ''").Pluck("photo_url", &alertList).Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err := schema.GetLostSightingDB(ctx, l.DB).Where("photo_url <> ''").Pluck("photo_url", &sightingList).Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return append(alertList, sightingList...), nil
+}
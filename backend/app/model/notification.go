@@ -0,0 +1,155 @@
+package model
+
+import (
+	"context"
+	"time"
+
+	"github.com/yiff028/comp90018-mobile-project/backend/app/model/schema"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Notification struct {
+	DB *gorm.DB
+}
+
+// Create
+func (n *Notification) Create(ctx context.Context, item *schema.Notification) error {
+	db := schema.GetNotificationDB(ctx, n.DB)
+	result := db.Create(item)
+	if err := result.Error; err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// List newest first
+func (n *Notification) List(ctx context.Context, userId uint, unreadOnly bool, page schema.PaginationParam) (schema.NotificationList, *schema.PaginationResult, error) {
+	db := schema.GetNotificationDB(ctx, n.DB).Where("user_id = ?", userId)
+	if unreadOnly {
+		db = db.Where("read_time IS NULL")
+	}
+	db = db.Order("id DESC")
+
+	notificationList := schema.NotificationList{}
+	pageResult, err := schema.WrapPageQuery(ctx, db, page, &notificationList)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	return notificationList, pageResult, nil
+}
+
+// CountUnread
+func (n *Notification) CountUnread(ctx context.Context, userId uint) (int64, error) {
+	db := schema.GetNotificationDB(ctx, n.DB).Where("user_id = ?", userId).Where("read_time IS NULL")
+
+	var count int64
+	if err := db.Count(&count).Error; err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return count, nil
+}
+
+// MarkRead ids empty marks every notification of the user
+func (n *Notification) MarkRead(ctx context.Context, userId uint, ids []uint) (int64, error) {
+	db := schema.GetNotificationDB(ctx, n.DB).Where("user_id = ?", userId).Where("read_time IS NULL")
+	if len(ids) > 0 {
+		db = db.Where("id IN ?", ids)
+	}
+	result := db.Update("read_time", time.Now())
+	if err := result.Error; err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return result.RowsAffected, nil
+}
+
+// ListPreference one entry per category, defaults for the ones never saved
+func (n *Notification) ListPreference(ctx context.Context, userId uint) (schema.NotificationPreferenceList, error) {
+	db := schema.GetNotificationPreferenceDB(ctx, n.DB).Where("user_id = ?", userId)
+
+	saved := schema.NotificationPreferenceList{}
+	if err := db.Find(&saved).Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	savedMap := make(map[string]schema.NotificationPreference, len(saved))
+	for _, pref := range saved {
+		savedMap[pref.Category] = pref
+	}
+
+	preferenceList := make(schema.NotificationPreferenceList, 0, len(schema.NotificationCategories))
+	for _, category := range schema.NotificationCategories {
+		pref, ok := savedMap[category]
+		if !ok {
+			pref = schema.DefaultNotificationPreference(userId, category)
+		}
+		preferenceList = append(preferenceList, pref)
+	}
+	return preferenceList, nil
+}
+
+// GetPreference
+func (n *Notification) GetPreference(ctx context.Context, userId uint, category string) (schema.NotificationPreference, error) {
+	db := schema.GetNotificationPreferenceDB(ctx, n.DB).Where("user_id = ?", userId).Where("category = ?", category)
+
+	item := schema.NotificationPreference{}
+	ok, err := schema.FindOne(ctx, db, &item)
+	if err != nil {
+		return item, errors.WithStack(err)
+	} else if !ok {
+		return schema.DefaultNotificationPreference(userId, category), nil
+	}
+	return item, nil
+}
+
+// SavePreference
+func (n *Notification) SavePreference(ctx context.Context, item *schema.NotificationPreference) error {
+	db := schema.GetNotificationPreferenceDB(ctx, n.DB)
+	result := db.Clauses(clause.OnConflict{
+		DoUpdates: clause.AssignmentColumns([]string{"in_app", "email", "push"}),
+	}).Create(item)
+	if err := result.Error; err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// SaveDevice a token moves to the latest user who registered it
+func (n *Notification) SaveDevice(ctx context.Context, item *schema.PushDevice) error {
+	db := schema.GetPushDeviceDB(ctx, n.DB)
+	result := db.Clauses(clause.OnConflict{
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"user_id":      item.UserId,
+			"platform":     item.Platform,
+			"updated_time": gorm.Expr("CURRENT_TIMESTAMP"),
+		}),
+	}).Create(item)
+	if err := result.Error; err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// DeleteDevice userId 0 deletes the token whoever owns it
+func (n *Notification) DeleteDevice(ctx context.Context, userId uint, token string) error {
+	db := schema.GetPushDeviceDB(ctx, n.DB).Where("token = ?", token)
+	if userId > 0 {
+		db = db.Where("user_id = ?", userId)
+	}
+	result := db.Delete(&schema.PushDevice{})
+	if err := result.Error; err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// ListDevice
+func (n *Notification) ListDevice(ctx context.Context, userId uint) (schema.PushDeviceList, error) {
+	db := schema.GetPushDeviceDB(ctx, n.DB).Where("user_id = ?", userId)
+
+	deviceList := schema.PushDeviceList{}
+	if err := db.Find(&deviceList).Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return deviceList, nil
+}
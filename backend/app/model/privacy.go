@@ -0,0 +1,124 @@
+package model
+
+import (
+	"context"
+
+	"github.com/yiff028/comp90018-mobile-project/backend/app/model/schema"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Privacy struct {
+	DB *gorm.DB
+}
+
+// Get settings of the user, the defaults if never saved
+func (p *Privacy) Get(ctx context.Context, userId uint) (*schema.UserPrivacy, error) {
+	db := schema.GetUserPrivacyDB(ctx, p.DB).Where("user_id = ?", userId)
+
+	item := schema.UserPrivacy{}
+	ok, err := schema.FindOne(ctx, db, &item)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	} else if !ok {
+		return schema.DefaultUserPrivacy(userId), nil
+	}
+	return &item, nil
+}
+
+// Save insert or update the settings of the user
+func (p *Privacy) Save(ctx context.Context, item *schema.UserPrivacy) error {
+	db := schema.GetUserPrivacyDB(ctx, p.DB)
+	result := db.Clauses(clause.OnConflict{
+		DoUpdates: clause.AssignmentColumns([]string{"visibility", "fuzz_metres", "updated_time"}),
+	}).Create(item)
+	if err := result.Error; err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// CreateHomeZone
+func (p *Privacy) CreateHomeZone(ctx context.Context, item *schema.HomeZone) error {
+	db := schema.GetHomeZoneDB(ctx, p.DB)
+	result := db.Create(item)
+	if err := result.Error; err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// DeleteHomeZone
+func (p *Privacy) DeleteHomeZone(ctx context.Context, id uint, userId uint) error {
+	db := schema.GetHomeZoneDB(ctx, p.DB)
+	result := db.Where("id = ?", id).Where("user_id = ?", userId).Delete(&schema.HomeZone{})
+	if err := result.Error; err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// ListHomeZone
+func (p *Privacy) ListHomeZone(ctx context.Context, userId uint) (schema.HomeZoneList, error) {
+	db := schema.GetHomeZoneDB(ctx, p.DB).Where("user_id = ?", userId)
+
+	zoneList := schema.HomeZoneList{}
+	if err := db.Find(&zoneList).Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return zoneList, nil
+}
+
+// ListPolicy location policies of the owners as seen by viewerId, keyed by owner id
+func (p *Privacy) ListPolicy(ctx context.Context, viewerId uint, ownerIds []uint) (map[uint]*schema.LocationPolicy, error) {
+	policyMap := make(map[uint]*schema.LocationPolicy, len(ownerIds))
+	if len(ownerIds) == 0 {
+		return policyMap, nil
+	}
+	for _, ownerId := range ownerIds {
+		policyMap[ownerId] = &schema.LocationPolicy{
+			Privacy: schema.DefaultUserPrivacy(ownerId),
+			Friends: map[uint]bool{},
+		}
+	}
+
+	privacyList := []schema.UserPrivacy{}
+	if err := schema.GetUserPrivacyDB(ctx, p.DB).Where("user_id IN ?", ownerIds).Find(&privacyList).Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	for i := range privacyList {
+		policyMap[privacyList[i].UserId].Privacy = &privacyList[i]
+	}
+
+	zoneList := schema.HomeZoneList{}
+	if err := schema.GetHomeZoneDB(ctx, p.DB).Where("user_id IN ?", ownerIds).Find(&zoneList).Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	for _, zone := range zoneList {
+		policyMap[zone.UserId].HomeZones = append(policyMap[zone.UserId].HomeZones, zone)
+	}
+
+	friendList := schema.FriendList{}
+	db := schema.GetFriendDB(ctx, p.DB).Where("user_id IN ?", ownerIds).Where("friend_id = ?", viewerId)
+	if err := db.Find(&friendList).Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	for _, friend := range friendList {
+		policyMap[friend.UserId].Friends[friend.FriendId] = true
+	}
+	return policyMap, nil
+}
+
+// visibleTo scope hiding dogs whose owners do not share their location with viewerId,
+// dogs viewerId holds a role on are always visible
+func visibleTo(viewerId uint) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		roleQuery, args := roleCondition(viewerId, schema.HouseholdRolesAtLeast(schema.HouseholdRoleViewer))
+		args = append(args, schema.VisibilityEveryone, schema.VisibilityEveryone, schema.VisibilityFriends, viewerId)
+		return db.Joins("LEFT JOIN user_privacy ON user_privacy.user_id = dog.user_id").
+			Where(roleQuery+" OR COALESCE(user_privacy.visibility, ?) = ? OR "+
+				"(user_privacy.visibility = ? AND EXISTS (SELECT 1 FROM user_friend WHERE user_friend.user_id = dog.user_id AND user_friend.friend_id = ?))",
+				args...)
+	}
+}
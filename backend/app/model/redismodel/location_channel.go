@@ -0,0 +1,91 @@
+package redismodel
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/go-redis/redis/v7"
+	"github.com/yiff028/comp90018-mobile-project/backend/api/types/keys"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/driver"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/util"
+)
+
+const (
+	// LocationCellSize degrees covered by one channel, about 1km
+	LocationCellSize = 0.01
+	// MaxLocationCells most channels a single subscriber may listen to
+	MaxLocationCells = 400
+)
+
+// DogLocationEvent location already masked by the owner's privacy settings
+type DogLocationEvent struct {
+	DogId      uint      `json:"dogId"`
+	UserId     uint      `json:"userId"`
+	Longitude  float64   `json:"longitude"`
+	Latitude   float64   `json:"latitude"`
+	Visibility string    `json:"visibility"`
+	Time       time.Time `json:"time"`
+}
+
+// LocationChannel fan out dog locations across instances, one channel per grid cell
+type LocationChannel struct {
+	RedisInstance driver.ClientType
+}
+
+// LocationCell
+func LocationCell(latitude, longitude float64) (int, int) {
+	return int(math.Floor(latitude / LocationCellSize)), int(math.Floor(longitude / LocationCellSize))
+}
+
+// LocationChannels channels of every cell overlapping the box
+func LocationChannels(box util.BoundingBox) ([]string, error) {
+	minLat, minLon := LocationCell(box.MinLatitude, box.MinLongitude)
+	maxLat, maxLon := LocationCell(box.MaxLatitude, box.MaxLongitude)
+	count := (maxLat - minLat + 1) * (maxLon - minLon + 1)
+	if count > MaxLocationCells {
+		return nil, fmt.Errorf("area covers %d cells, at most %d", count, MaxLocationCells)
+	}
+	channels := make([]string, 0, count)
+	for lat := minLat; lat <= maxLat; lat++ {
+		for lon := minLon; lon <= maxLon; lon++ {
+			channels = append(channels, keys.GetDogLocationChannel(lat, lon))
+		}
+	}
+	return channels, nil
+}
+
+// Publish
+func (l *LocationChannel) Publish(event DogLocationEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = l.RedisInstance.Publish(keys.GetDogLocationChannel(LocationCell(event.Latitude, event.Longitude)), payload)
+	return err
+}
+
+// Subscribe to the cells of the box, the caller closes the returned PubSub
+func (l *LocationChannel) Subscribe(box util.BoundingBox) (*redis.PubSub, error) {
+	channels, err := LocationChannels(box)
+	if err != nil {
+		return nil, err
+	}
+	ps := l.RedisInstance.PSubscribe(channels...)
+	// wait for the confirmation so no event is missed after returning
+	if _, err := ps.Receive(); err != nil {
+		ps.Close()
+		return nil, err
+	}
+	return ps, nil
+}
+
+// ParseDogLocationEvent
+func ParseDogLocationEvent(payload string) (*DogLocationEvent, error) {
+	event := DogLocationEvent{}
+	if err := json.Unmarshal([]byte(payload), &event); err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
@@ -0,0 +1,81 @@
+package redismodel
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/yiff028/comp90018-mobile-project/backend/api/types/keys"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/driver"
+)
+
+const (
+	// MaxNotificationAttempts deliveries tried before a job goes to the dead letter list
+	MaxNotificationAttempts = 5
+	// notificationRetryDelay wait before the first retry, doubled for each further one
+	notificationRetryDelay = 30 * time.Second
+	maxDeadNotifications   = 1000
+)
+
+// NotificationJob one notification to deliver to every user of UserIds
+// Channels left to deliver when retried, empty for every channel the user opted in
+type NotificationJob struct {
+	UserIds   []uint            `json:"userIds"`
+	Category  string            `json:"category"`
+	Title     string            `json:"title"`
+	Body      string            `json:"body"`
+	RefId     uint              `json:"refId"`
+	Data      map[string]string `json:"data"`
+	Created   time.Time         `json:"created"`
+	Channels  []string          `json:"channels,omitempty"`
+	Attempt   int               `json:"attempt"`
+	RetryTime time.Time         `json:"retryTime"`
+}
+
+// NotificationQueue controllers enqueue, the notification workers deliver
+type NotificationQueue struct {
+	RedisInstance driver.ClientType
+}
+
+// Enqueue
+func (q *NotificationQueue) Enqueue(job NotificationJob) error {
+	if job.Created.IsZero() {
+		job.Created = time.Now()
+	}
+	payload, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	_, err = q.RedisInstance.LPush(keys.NotificationQueueKey, payload)
+	return err
+}
+
+// Dequeue waits up to timeout, nil job if the queue stayed empty
+func (q *NotificationQueue) Dequeue(timeout time.Duration) (*NotificationJob, error) {
+	res, err := q.RedisInstance.BRPop(timeout, keys.NotificationQueueKey)
+	if err != nil || len(res) < 2 {
+		return nil, err
+	}
+	job := NotificationJob{}
+	if err := json.Unmarshal([]byte(res[1]), &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Retry queue the job again after a backoff, or move it to the dead letter list once it ran out of attempts
+func (q *NotificationQueue) Retry(job NotificationJob) error {
+	job.Attempt++
+	if job.Attempt >= MaxNotificationAttempts {
+		payload, err := json.Marshal(job)
+		if err != nil {
+			return err
+		}
+		if _, err := q.RedisInstance.LPush(keys.NotificationDeadKey, payload); err != nil {
+			return err
+		}
+		_, err = q.RedisInstance.LTrim(keys.NotificationDeadKey, 0, maxDeadNotifications-1)
+		return err
+	}
+	job.RetryTime = time.Now().Add(notificationRetryDelay << (job.Attempt - 1))
+	return q.Enqueue(job)
+}
@@ -3,9 +3,11 @@ package model
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/yiff028/comp90018-mobile-project/backend/app/model/schema"
 	"github.com/yiff028/comp90018-mobile-project/backend/pkg/errors"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/util"
 	"gorm.io/gorm"
 )
 
@@ -36,6 +38,41 @@ func (r *Route) AddPointCount(ctx context.Context, userId uint, routeId uint) er
 	return nil
 }
 
+// AddStats accumulate a new segment onto the route stats
+func (r *Route) AddStats(ctx context.Context, routeId uint, seg util.WalkStats) error {
+	db := schema.GetRouteDB(ctx, r.DB)
+
+	// mysql evaluates the assignments left to right, avg_speed sees the new totals
+	raw := "UPDATE route SET distance = distance + ?, active_duration = active_duration + ?, idle_duration = idle_duration + ?, " +
+		"max_speed = GREATEST(max_speed, ?), avg_speed = IF(active_duration > 0, distance / active_duration, 0), " +
+		"last_point_time = IF(last_point_time IS NULL OR last_point_time < ?, ?, last_point_time) where id = ?"
+
+	result := db.Exec(raw, seg.Distance, uint(seg.ActiveDuration), uint(seg.IdleDuration), seg.MaxSpeed, seg.LastPointTime, seg.LastPointTime, routeId)
+	if err := result.Error; err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// SetStats overwrite the route stats, used when they are recomputed from all points
+func (r *Route) SetStats(ctx context.Context, routeId uint, stats util.WalkStats) error {
+	db := schema.GetRouteDB(ctx, r.DB).Where("id = ?", routeId)
+	updateMap := map[string]interface{}{}
+	updateMap["point_count"] = stats.PointCount
+	updateMap["distance"] = stats.Distance
+	updateMap["active_duration"] = uint(stats.ActiveDuration)
+	updateMap["idle_duration"] = uint(stats.IdleDuration)
+	updateMap["avg_speed"] = stats.AvgSpeed()
+	updateMap["max_speed"] = stats.MaxSpeed
+	updateMap["last_point_time"] = stats.LastPointTime
+
+	result := db.Updates(updateMap)
+	if err := result.Error; err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
 // ListRoute
 func (r *Route) ListRoute(ctx context.Context, userId *uint) (*schema.RouteList, error) {
 	db := schema.GetRouteDB(ctx, r.DB)
@@ -54,6 +91,22 @@ func (r *Route) ListRoute(ctx context.Context, userId *uint) (*schema.RouteList,
 	return &routeList, nil
 }
 
+// ListRouteByTime routes of the user created in [from, to)
+func (r *Route) ListRouteByTime(ctx context.Context, userId uint, from time.Time, to time.Time) (schema.RouteList, error) {
+	db := schema.GetRouteDB(ctx, r.DB)
+
+	routeList := schema.RouteList{}
+
+	db = db.Where("user_id = ?", userId).Where("created_time >= ?", from).Where("created_time < ?", to).Order("created_time ASC")
+
+	result := db.Find(&routeList)
+
+	if err := result.Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return routeList, nil
+}
+
 // GetRouteById
 func (r *Route) GetRouteById(ctx context.Context, routeId uint) (*schema.Route, error) {
 	db := schema.GetRouteDB(ctx, r.DB).Where("id = ?", routeId)
@@ -68,3 +121,84 @@ func (r *Route) GetRouteById(ctx context.Context, routeId uint) (*schema.Route,
 	}
 	return &item, nil
 }
+
+// UpdateStatus move the route from one status to another, returns 0 rows if the route is no longer in the from status
+func (r *Route) UpdateStatus(ctx context.Context, routeId uint, userId uint, from string, to string) (int64, error) {
+	db := schema.GetRouteDB(ctx, r.DB)
+	db = db.Where("id = ?", routeId).Where("user_id = ?", userId).Where("status = ?", from)
+	updateMap := map[string]interface{}{}
+	updateMap["status"] = to
+	if to == schema.RouteStatusFinished || to == schema.RouteStatusAbandoned {
+		updateMap["finished_time"] = time.Now()
+	}
+
+	result := db.Updates(updateMap)
+	if err := result.Error; err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return result.RowsAffected, nil
+}
+
+// FinishStale finish the open routes whose last point is older than before, paused ones count from when they were paused
+func (r *Route) FinishStale(ctx context.Context, before time.Time) (int64, error) {
+	db := schema.GetRouteDB(ctx, r.DB)
+	pausedSQL := "(SELECT MAX(route_pause.paused_time) FROM route_pause WHERE route_pause.route_id = route.id AND route_pause.resumed_time IS NULL)"
+	db = db.Where("status IN ?", []string{schema.RouteStatusActive, schema.RouteStatusPaused}).
+		Where("GREATEST(COALESCE(last_point_time, created_time), COALESCE("+pausedSQL+", created_time)) < ?", before)
+	updateMap := map[string]interface{}{}
+	updateMap["status"] = schema.RouteStatusFinished
+	updateMap["finished_time"] = gorm.Expr("COALESCE(last_point_time, created_time)")
+
+	result := db.Updates(updateMap)
+	if err := result.Error; err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return result.RowsAffected, nil
+}
+
+// ListOpenByUserAndDog active or paused walks of the user which include the dog
+func (r *Route) ListOpenByUserAndDog(ctx context.Context, userId uint, dogId uint) (schema.RouteList, error) {
+	routeIds := r.DB.Table("route_dog").Select("route_id").Where("dog_id = ?", dogId)
+	db := schema.GetRouteDB(ctx, r.DB).Where("user_id = ?", userId).
+		Where("status IN ?", []string{schema.RouteStatusActive, schema.RouteStatusPaused}).
+		Where("dog_id = ? OR id IN (?)", dogId, routeIds)
+
+	routeList := schema.RouteList{}
+	if err := db.Find(&routeList).Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return routeList, nil
+}
+
+// ListByDogAndTime walks of one dog started in [from, to), including walks shared with other dogs
+func (r *Route) ListByDogAndTime(ctx context.Context, dogId uint, from time.Time, to time.Time) (schema.RouteList, error) {
+	routeIds := r.DB.Table("route_dog").Select("route_id").Where("dog_id = ?", dogId)
+	db := schema.GetRouteDB(ctx, r.DB).Where("dog_id = ? OR id IN (?)", dogId, routeIds).
+		Where("created_time >= ?", from).Where("created_time < ?", to).Order("created_time ASC")
+
+	routeList := schema.RouteList{}
+	if err := db.Find(&routeList).Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return routeList, nil
+}
+
+// SumStatsByDog walks of each dog started in [from, to), abandoned walks do not count
+// relies on route_dog, routes from before it need the route-dog backfill
+func (r *Route) SumStatsByDog(ctx context.Context, dogIds []uint, from time.Time, to time.Time) (schema.DogStatsList, error) {
+	statsList := schema.DogStatsList{}
+	if len(dogIds) == 0 {
+		return statsList, nil
+	}
+	db := schema.GetRouteDB(ctx, r.DB).
+		Select("route_dog.dog_id, COUNT(*) AS route_count, SUM(route.distance) AS distance, SUM(route.active_duration) AS active_duration").
+		Joins("JOIN route_dog ON route_dog.route_id = route.id").
+		Where("route_dog.dog_id IN ?", dogIds).Where("route.status <> ?", schema.RouteStatusAbandoned).
+		Where("route.created_time >= ?", from).Where("route.created_time < ?", to).
+		Group("route_dog.dog_id")
+
+	if err := db.Scan(&statsList).Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return statsList, nil
+}
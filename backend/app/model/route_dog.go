@@ -0,0 +1,62 @@
+package model
+
+import (
+	"context"
+
+	"github.com/yiff028/comp90018-mobile-project/backend/app/model/schema"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/errors"
+	"gorm.io/gorm"
+)
+
+type RouteDog struct {
+	DB *gorm.DB
+}
+
+// CreateBatch
+func (r *RouteDog) CreateBatch(ctx context.Context, routeId uint, dogIds []uint) error {
+	if len(dogIds) == 0 {
+		return nil
+	}
+	itemList := make(schema.RouteDogList, 0, len(dogIds))
+	for _, dogId := range dogIds {
+		itemList = append(itemList, schema.RouteDog{RouteId: routeId, DogId: dogId})
+	}
+	db := schema.GetRouteDogDB(ctx, r.DB)
+	if err := db.Create(&itemList).Error; err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// ListByRouteIds dog ids of each route in insertion order, routes without rows are left out
+func (r *RouteDog) ListByRouteIds(ctx context.Context, routeIds []uint) (map[uint][]uint, error) {
+	res := map[uint][]uint{}
+	if len(routeIds) == 0 {
+		return res, nil
+	}
+	db := schema.GetRouteDogDB(ctx, r.DB).Where("route_id IN ?", routeIds).Order("id ASC")
+
+	itemList := schema.RouteDogList{}
+	if err := db.Find(&itemList).Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	for _, item := range itemList {
+		res[item.RouteId] = append(res[item.RouteId], item.DogId)
+	}
+	return res, nil
+}
+
+// BackfillFromRoute one row for each route created before route_dog existed
+func (r *RouteDog) BackfillFromRoute(ctx context.Context) (int64, error) {
+	db := schema.GetRouteDogDB(ctx, r.DB)
+
+	raw := "INSERT INTO route_dog (route_id, dog_id, created_time) " +
+		"SELECT route.id, route.dog_id, route.created_time FROM route " +
+		"WHERE NOT EXISTS (SELECT 1 FROM route_dog WHERE route_dog.route_id = route.id)"
+
+	result := db.Exec(raw)
+	if err := result.Error; err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,44 @@
+package model
+
+import (
+	"context"
+	"time"
+
+	"github.com/yiff028/comp90018-mobile-project/backend/app/model/schema"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/errors"
+	"gorm.io/gorm"
+)
+
+type RoutePause struct {
+	DB *gorm.DB
+}
+
+// Pause start a pause of the walk
+func (r *RoutePause) Pause(ctx context.Context, routeId uint, at time.Time) error {
+	db := schema.GetRoutePauseDB(ctx, r.DB)
+	item := schema.RoutePause{RouteId: routeId, PausedTime: &at}
+	if err := db.Create(&item).Error; err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// Resume end the open pause of the walk, if any
+func (r *RoutePause) Resume(ctx context.Context, routeId uint, at time.Time) error {
+	db := schema.GetRoutePauseDB(ctx, r.DB).Where("route_id = ?", routeId).Where("resumed_time IS NULL")
+	if err := db.Update("resumed_time", at).Error; err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// ListByRouteId oldest first
+func (r *RoutePause) ListByRouteId(ctx context.Context, routeId uint) (schema.RoutePauseList, error) {
+	db := schema.GetRoutePauseDB(ctx, r.DB).Where("route_id = ?", routeId).Order("paused_time ASC")
+
+	pauseList := schema.RoutePauseList{}
+	if err := db.Find(&pauseList).Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return pauseList, nil
+}
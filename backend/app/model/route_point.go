@@ -2,6 +2,7 @@ package model
 
 import (
 	"context"
+	"time"
 
 	"github.com/yiff028/comp90018-mobile-project/backend/app/model/schema"
 	"github.com/yiff028/comp90018-mobile-project/backend/pkg/errors"
@@ -22,13 +23,41 @@ func (r *RoutePoint) Create(ctx context.Context, item schema.RoutePoint) error {
 	return nil
 }
 
+// CreateBatch
+func (r *RoutePoint) CreateBatch(ctx context.Context, items schema.RoutePointList) error {
+	db := schema.GetRoutePointDB(ctx, r.DB)
+	result := db.CreateInBatches(&items, 200)
+	if err := result.Error; err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// ListSeqByRouteId the seqs which already exist on the route
+func (r *RoutePoint) ListSeqByRouteId(ctx context.Context, routeId uint, seqs []uint64) ([]uint64, error) {
+	db := schema.GetRoutePointDB(ctx, r.DB)
+
+	existSeqs := []uint64{}
+	if len(seqs) == 0 {
+		return existSeqs, nil
+	}
+
+	db = db.Where("route_id = ?", routeId).Where("seq IN ?", seqs)
+
+	result := db.Pluck("seq", &existSeqs)
+	if err := result.Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return existSeqs, nil
+}
+
 // ListByRouteId
 func (r *RoutePoint) ListByRouteId(ctx context.Context, routeId uint) (*schema.RoutePointList, error) {
 	db := schema.GetRoutePointDB(ctx, r.DB)
 
 	var pointList schema.RoutePointList = schema.RoutePointList{}
 
-	db = db.Where("route_id = ?", routeId)
+	db = db.Where("route_id = ?", routeId).Where("rejected = ?", false).Order("created_time ASC, id ASC")
 
 	result := db.Find(&pointList)
 
@@ -37,3 +66,42 @@ func (r *RoutePoint) ListByRouteId(ctx context.Context, routeId uint) (*schema.R
 	}
 	return &pointList, nil
 }
+
+// ListRejectedByRouteId points dropped by the gps filter
+func (r *RoutePoint) ListRejectedByRouteId(ctx context.Context, routeId uint) (*schema.RoutePointList, error) {
+	db := schema.GetRoutePointDB(ctx, r.DB)
+
+	var pointList schema.RoutePointList = schema.RoutePointList{}
+
+	db = db.Where("route_id = ?", routeId).Where("rejected = ?", true).Order("created_time ASC, id ASC")
+
+	result := db.Find(&pointList)
+
+	if err := result.Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &pointList, nil
+}
+
+// ListRecentByRouteId the last accepted points up to before (nil for no bound), oldest first
+func (r *RoutePoint) ListRecentByRouteId(ctx context.Context, routeId uint, before *time.Time, limit int) (schema.RoutePointList, error) {
+	db := schema.GetRoutePointDB(ctx, r.DB)
+
+	pointList := schema.RoutePointList{}
+
+	db = db.Where("route_id = ?", routeId).Where("rejected = ?", false)
+	if before != nil {
+		db = db.Where("created_time <= ?", before)
+	}
+	db = db.Order("created_time DESC, id DESC").Limit(limit)
+
+	result := db.Find(&pointList)
+
+	if err := result.Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	for i, j := 0, len(pointList)-1; i < j; i, j = i+1, j-1 {
+		pointList[i], pointList[j] = pointList[j], pointList[i]
+	}
+	return pointList, nil
+}
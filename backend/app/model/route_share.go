@@ -0,0 +1,83 @@
+package model
+
+import (
+	"context"
+	"time"
+
+	"github.com/yiff028/comp90018-mobile-project/backend/app/model/schema"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/errors"
+	"gorm.io/gorm"
+)
+
+type RouteShare struct {
+	DB *gorm.DB
+}
+
+// Create
+func (r *RouteShare) Create(ctx context.Context, item *schema.RouteShare) error {
+	db := schema.GetRouteShareDB(ctx, r.DB)
+	result := db.Create(item)
+	if err := result.Error; err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// GetByToken
+func (r *RouteShare) GetByToken(ctx context.Context, token string) (*schema.RouteShare, error) {
+	db := schema.GetRouteShareDB(ctx, r.DB).Where("token = ?", token)
+
+	item := schema.RouteShare{}
+	ok, err := schema.FindOne(ctx, db, &item)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	} else if !ok {
+		return nil, nil
+	}
+	return &item, nil
+}
+
+// ListByRouteId
+func (r *RouteShare) ListByRouteId(ctx context.Context, routeId uint, userId uint) (schema.RouteShareList, error) {
+	db := schema.GetRouteShareDB(ctx, r.DB).Where("route_id = ?", routeId).Where("user_id = ?", userId).Order("id DESC")
+
+	shareList := schema.RouteShareList{}
+	if err := db.Find(&shareList).Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return shareList, nil
+}
+
+// Revoke
+func (r *RouteShare) Revoke(ctx context.Context, id uint, userId uint) (int64, error) {
+	db := schema.GetRouteShareDB(ctx, r.DB).Where("id = ?", id).Where("user_id = ?", userId).Where("revoked_time IS NULL")
+	result := db.Update("revoked_time", time.Now())
+	if err := result.Error; err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return result.RowsAffected, nil
+}
+
+// ExpireByRouteId end every share of the route now
+func (r *RouteShare) ExpireByRouteId(ctx context.Context, routeId uint) error {
+	now := time.Now()
+	db := schema.GetRouteShareDB(ctx, r.DB).Where("route_id = ?", routeId).Where("expires_time > ?", now)
+	result := db.Update("expires_time", now)
+	if err := result.Error; err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// ExpireClosed end the shares of routes which were finished or abandoned, e.g. by the stale sweeper
+func (r *RouteShare) ExpireClosed(ctx context.Context) (int64, error) {
+	now := time.Now()
+	db := schema.GetRouteShareDB(ctx, r.DB).
+		Where("expires_time > ?", now).
+		Where("route_id IN (?)", schema.GetRouteDB(ctx, r.DB).Select("id").Where("status IN ?", []string{schema.RouteStatusFinished, schema.RouteStatusAbandoned}))
+	result := db.Update("expires_time", now)
+	if err := result.Error; err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return result.RowsAffected, nil
+}
@@ -18,6 +18,19 @@ type Bin struct {
 	Latitude    float64        `gorm:"column:latitude;not null" json:"latitude"`
 	CreatedTime *time.Time     `gorm:"column:created_time;default:current_time" json:"createdTime"`
 	DeletedAt   gorm.DeletedAt `gorm:"column:deleted_at;" json:"-"`
+
+	// Location write only copy of longitude/latitude for the spatial index
+	Location MysqlPoint `gorm:"column:location;type:point srid 4326;not null;index:idx_bin_location,type:SPATIAL;<-:create;->:false" json:"-"`
+}
+
+// BinWithDistanceList
+type BinWithDistanceList []BinWithDistance
+
+// BinWithDistance
+type BinWithDistance struct {
+	Bin
+	// Distance metres from the query point
+	Distance float64 `gorm:"column:distance;->" json:"distance"`
 }
 
 // TableName
@@ -27,10 +27,33 @@ type Dog struct {
 	UpdatedTime *time.Time     `gorm:"column:updated_time;default:current_time" json:"-"`
 	DeletedAt   gorm.DeletedAt `gorm:"column:deleted_at;" json:"-"`
 
-	Longitude           *float64       `gorm:"column:longitude;not null" json:"longitude"`
-	Latitude            *float64       `gorm:"column:latitude;not null" json:"latitude"`
+	Longitude           *float64       `gorm:"column:longitude;not null;index:idx_dog_location,priority:2" json:"longitude"`
+	Latitude            *float64       `gorm:"column:latitude;not null;index:idx_dog_location,priority:1" json:"latitude"`
 	LocationUpdatedTime *time.Time     `gorm:"column:location_updated_time;" json:"locationUpdatedTime"`
 	Personality         MysqlJSONArray `gorm:"column:personality;" json:"personality"`
+
+	IsLost    bool       `gorm:"column:is_lost;not null;default:0" json:"isLost"`
+	LostSince *time.Time `gorm:"column:lost_since;" json:"lostSince"`
+
+	// GoalReminder "HH:MM" in GoalTimezone, empty for no reminder
+	GoalType     string  `gorm:"column:goal_type;not null;default:''" json:"goalType"`
+	GoalValue    float64 `gorm:"column:goal_value;not null;default:0" json:"goalValue"`
+	GoalReminder string  `gorm:"column:goal_reminder;type:varchar(5);not null;default:'';index:idx_dog_goal_reminder" json:"goalReminder"`
+	GoalTimezone string  `gorm:"column:goal_timezone;type:varchar(64);not null;default:''" json:"goalTimezone"`
+
+	// HouseholdId 0 when the dog is not shared, Role is the caller's role on the dog
+	HouseholdId uint   `gorm:"column:household_id;not null;default:0;index:idx_dog_household" json:"householdId"`
+	Role        string `gorm:"-" json:"role,omitempty"`
+}
+
+// DogWithDistanceList
+type DogWithDistanceList []DogWithDistance
+
+// DogWithDistance
+type DogWithDistance struct {
+	Dog
+	// Distance metres from the query point
+	Distance float64 `gorm:"column:distance;->" json:"distance"`
 }
 
 // TableName
@@ -0,0 +1,65 @@
+package schema
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DogChangeList
+type DogChangeList []DogChange
+
+// DogChange one edited profile field, values as shown to the user
+type DogChange struct {
+	ID          uint       `gorm:"primary_key" json:"id"`
+	DogId       uint       `gorm:"column:dog_id;not null;index:idx_dog_change_dog" json:"dogId"`
+	UserId      uint       `gorm:"column:user_id;not null" json:"userId"`
+	Field       string     `gorm:"column:field;type:varchar(32);not null" json:"field"`
+	OldValue    string     `gorm:"column:old_value;type:text;not null" json:"oldValue"`
+	NewValue    string     `gorm:"column:new_value;type:text;not null" json:"newValue"`
+	CreatedTime *time.Time `gorm:"column:created_time;default:current_time" json:"createdTime"`
+}
+
+// TableName
+func (DogChange) TableName() string {
+	return "dog_change"
+}
+
+// GetDogChangeDB
+func GetDogChangeDB(ctx context.Context, defDB *gorm.DB) *gorm.DB {
+	return GetDBWithModel(ctx, defDB, new(DogChange))
+}
+
+// DiffDog changes of the key profile fields from old to updated, named after their json fields
+func DiffDog(old *Dog, updated *Dog, userId uint) DogChangeList {
+	fields := []struct {
+		name     string
+		oldValue string
+		newValue string
+	}{
+		{"name", old.Name, updated.Name},
+		{"breed", old.Breed, updated.Breed},
+		{"customizedBreed", old.CustomizedBreed, updated.CustomizedBreed},
+		{"gender", old.Gender, updated.Gender},
+		{"age", old.Age, updated.Age},
+		{"healthCondition", old.HealthCondition, updated.HealthCondition},
+		{"personality", strings.Join(old.Personality, ", "), strings.Join(updated.Personality, ", ")},
+	}
+
+	changes := DogChangeList{}
+	for _, field := range fields {
+		if field.oldValue == field.newValue {
+			continue
+		}
+		changes = append(changes, DogChange{
+			DogId:    old.ID,
+			UserId:   userId,
+			Field:    field.name,
+			OldValue: field.oldValue,
+			NewValue: field.newValue,
+		})
+	}
+	return changes
+}
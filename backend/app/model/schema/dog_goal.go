@@ -0,0 +1,137 @@
+package schema
+
+import (
+	"fmt"
+	"time"
+)
+
+// daily goal types
+const (
+	DogGoalMinutes = "minutes"
+	DogGoalKm      = "km"
+)
+
+// DogGoalDateLayout key of a day in the dog's timezone
+const DogGoalDateLayout = "2006-01-02"
+
+// DailyActivity walking done on one local day, Progress in the unit of the goal
+type DailyActivity struct {
+	Date       string  `json:"date"`
+	Progress   float64 `json:"progress"`
+	RouteCount int     `json:"routeCount"`
+	Met        bool    `json:"met"`
+}
+
+// HasGoal
+func (d Dog) HasGoal() bool {
+	return (d.GoalType == DogGoalMinutes || d.GoalType == DogGoalKm) && d.GoalValue > 0
+}
+
+// GoalLocation timezone the goal days are counted in, fallback when unset or unknown
+func (d Dog) GoalLocation(fallback *time.Location) *time.Location {
+	if d.GoalTimezone == "" {
+		return fallback
+	}
+	loc, err := time.LoadLocation(d.GoalTimezone)
+	if err != nil {
+		return fallback
+	}
+	return loc
+}
+
+// GoalAmount what one walk contributes to the goal
+func (d Dog) GoalAmount(route Route) float64 {
+	if route.Status == RouteStatusAbandoned {
+		return 0
+	}
+	switch d.GoalType {
+	case DogGoalMinutes:
+		return float64(route.ActiveDuration) / 60
+	case DogGoalKm:
+		return route.Distance / 1000
+	}
+	return 0
+}
+
+// ReminderDue the reminder time has passed on the local day of now
+func (d Dog) ReminderDue(now time.Time) bool {
+	minutes, ok := ParseGoalReminder(d.GoalReminder)
+	if !ok {
+		return false
+	}
+	return now.Hour()*60+now.Minute() >= minutes
+}
+
+// ParseGoalReminder "HH:MM" to minutes since midnight
+func ParseGoalReminder(reminder string) (int, bool) {
+	var hour, minute int
+	if len(reminder) != 5 {
+		return 0, false
+	}
+	if _, err := fmt.Sscanf(reminder, "%02d:%02d", &hour, &minute); err != nil {
+		return 0, false
+	}
+	if hour < 0 || hour > 23 || minute < 0 || minute > 59 {
+		return 0, false
+	}
+	return hour*60 + minute, true
+}
+
+// DailyActivities one entry per local day from..to inclusive, routes are counted on the day they started
+func (d Dog) DailyActivities(routes RouteList, from time.Time, to time.Time, loc *time.Location) []DailyActivity {
+	byDate := map[string]*DailyActivity{}
+	for _, route := range routes {
+		if route.CreatedTime == nil {
+			continue
+		}
+		date := route.CreatedTime.In(loc).Format(DogGoalDateLayout)
+		activity, ok := byDate[date]
+		if !ok {
+			activity = &DailyActivity{Date: date}
+			byDate[date] = activity
+		}
+		activity.Progress += d.GoalAmount(route)
+		activity.RouteCount++
+	}
+
+	var activities []DailyActivity
+	day := time.Date(from.In(loc).Year(), from.In(loc).Month(), from.In(loc).Day(), 0, 0, 0, 0, loc)
+	last := to.In(loc).Format(DogGoalDateLayout)
+	for {
+		date := day.Format(DogGoalDateLayout)
+		activity := DailyActivity{Date: date}
+		if found, ok := byDate[date]; ok {
+			activity = *found
+		}
+		activity.Met = d.HasGoal() && activity.Progress >= d.GoalValue
+		activities = append(activities, activity)
+		if date >= last {
+			break
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	return activities
+}
+
+// GoalStreak consecutive met days up to the last day, the last day does not break the streak while it is still open
+func GoalStreak(activities []DailyActivity) (current int, best int) {
+	run := 0
+	for _, activity := range activities {
+		if activity.Met {
+			run++
+		} else {
+			run = 0
+		}
+		if run > best {
+			best = run
+		}
+	}
+	current = run
+	if n := len(activities); n > 0 && !activities[n-1].Met {
+		current = 0
+		for i := n - 2; i >= 0 && activities[i].Met; i-- {
+			current++
+		}
+	}
+	return current, best
+}
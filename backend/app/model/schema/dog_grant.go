@@ -0,0 +1,49 @@
+package schema
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DogGrantList
+type DogGrantList []DogGrant
+
+// DogGrant time-boxed access to one dog for someone outside the household, e.g. a hired walker
+// Role is what the grantee may do, walker or viewer
+type DogGrant struct {
+	ID          uint       `gorm:"primary_key" json:"id"`
+	DogId       uint       `gorm:"column:dog_id;not null;index:idx_dog_grant_dog" json:"dogId"`
+	OwnerId     uint       `gorm:"column:owner_id;not null;index:idx_dog_grant_owner" json:"ownerId"`
+	GranteeId   uint       `gorm:"column:grantee_id;not null;index:idx_dog_grant_grantee" json:"granteeId"`
+	Role        string     `gorm:"column:role;type:varchar(16);not null" json:"role"`
+	StartTime   *time.Time `gorm:"column:start_time;not null" json:"startTime"`
+	EndTime     *time.Time `gorm:"column:end_time;not null;index:idx_dog_grant_end" json:"endTime"`
+	RevokedTime *time.Time `gorm:"column:revoked_time;" json:"revokedTime"`
+	CreatedTime *time.Time `gorm:"column:created_time;default:current_time" json:"createdTime"`
+}
+
+// TableName
+func (DogGrant) TableName() string {
+	return "dog_grant"
+}
+
+// GetDogGrantDB
+func GetDogGrantDB(ctx context.Context, defDB *gorm.DB) *gorm.DB {
+	return GetDBWithModel(ctx, defDB, new(DogGrant))
+}
+
+// IsActive inside its time window and not revoked
+func (g DogGrant) IsActive(now time.Time) bool {
+	return g.RevokedTime == nil && g.StartTime != nil && g.EndTime != nil &&
+		!g.StartTime.After(now) && g.EndTime.After(now)
+}
+
+// MaxRole the role granting more
+func MaxRole(a string, b string) string {
+	if householdRoleRank[b] > householdRoleRank[a] {
+		return b
+	}
+	return a
+}
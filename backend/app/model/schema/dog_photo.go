@@ -0,0 +1,46 @@
+package schema
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DogPhotoList
+type DogPhotoList []DogPhoto
+
+// DogPhoto one picture of the dog's gallery, the primary one is mirrored into Dog.Img
+type DogPhoto struct {
+	ID          uint           `gorm:"primary_key" json:"id"`
+	DogId       uint           `gorm:"column:dog_id;not null;index:idx_dog_photo_dog" json:"dogId"`
+	UserId      uint           `gorm:"column:user_id;not null" json:"userId"`
+	Url         string         `gorm:"column:url;type:varchar(255);not null" json:"url"`
+	Position    int            `gorm:"column:position;not null;default:0" json:"position"`
+	IsPrimary   bool           `gorm:"column:is_primary;not null;default:0" json:"isPrimary"`
+	CreatedTime *time.Time     `gorm:"column:created_time;default:current_time" json:"createdTime"`
+	DeletedAt   gorm.DeletedAt `gorm:"column:deleted_at;" json:"-"`
+}
+
+// TableName
+func (DogPhoto) TableName() string {
+	return "dog_photo"
+}
+
+// GetDogPhotoDB
+func GetDogPhotoDB(ctx context.Context, defDB *gorm.DB) *gorm.DB {
+	return GetDBWithModel(ctx, defDB, new(DogPhoto))
+}
+
+// Primary the primary photo, the first one when none is flagged, nil for an empty gallery
+func (l DogPhotoList) Primary() *DogPhoto {
+	if len(l) == 0 {
+		return nil
+	}
+	for i := range l {
+		if l[i].IsPrimary {
+			return &l[i]
+		}
+	}
+	return &l[0]
+}
@@ -0,0 +1,352 @@
+package schema
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// exercise intensities
+const (
+	IntensityLow      = "low"
+	IntensityModerate = "moderate"
+	IntensityHigh     = "high"
+)
+
+var intensityOrder = []string{IntensityLow, IntensityModerate, IntensityHigh}
+
+// dog age groups, as chosen on registration
+const (
+	DogAgeYoung = "young"
+	DogAgeAdult = "adult"
+	DogAgeOld   = "old"
+)
+
+// BreedGroup recommended daily exercise of a healthy adult
+// WeightAlertPercent weight change over 30 days worth an alert, small dogs react to less
+type BreedGroup struct {
+	Name               string
+	Minutes            float64
+	Intensity          string
+	WeightAlertPercent float64
+}
+
+var (
+	breedGroupHerding   = BreedGroup{Name: "herding", Minutes: 120, Intensity: IntensityHigh, WeightAlertPercent: 7}
+	breedGroupSporting  = BreedGroup{Name: "sporting", Minutes: 90, Intensity: IntensityHigh, WeightAlertPercent: 7}
+	breedGroupWorking   = BreedGroup{Name: "working", Minutes: 75, Intensity: IntensityModerate, WeightAlertPercent: 8}
+	breedGroupGiant     = BreedGroup{Name: "giant", Minutes: 45, Intensity: IntensityLow, WeightAlertPercent: 8}
+	breedGroupHound     = BreedGroup{Name: "hound", Minutes: 60, Intensity: IntensityModerate, WeightAlertPercent: 7}
+	breedGroupTerrier   = BreedGroup{Name: "terrier", Minutes: 60, Intensity: IntensityModerate, WeightAlertPercent: 6}
+	breedGroupCompanion = BreedGroup{Name: "companion", Minutes: 40, Intensity: IntensityModerate, WeightAlertPercent: 6}
+	breedGroupToy       = BreedGroup{Name: "toy", Minutes: 30, Intensity: IntensityLow, WeightAlertPercent: 5}
+)
+
+// breedGroups keyed by the breed ids of the app
+var breedGroups = map[string]BreedGroup{
+	"border_collie":                 breedGroupHerding,
+	"australian_shepherd":           breedGroupHerding,
+	"german_shepherd_dog":           breedGroupHerding,
+	"belgian_malinois":              breedGroupHerding,
+	"collie":                        breedGroupHerding,
+	"shetland_sheepdog":             breedGroupHerding,
+	"pembroke_welsh_corgi":          breedGroupHerding,
+	"labrador_retriever":            breedGroupSporting,
+	"golden_retriever":              breedGroupSporting,
+	"german_shorthaired_pointer":    breedGroupSporting,
+	"vizsla":                        breedGroupSporting,
+	"weimaraner":                    breedGroupSporting,
+	"brittany":                      breedGroupSporting,
+	"english_springer_spaniel":      breedGroupSporting,
+	"cocker_spaniel":                breedGroupSporting,
+	"siberian_husky":                breedGroupWorking,
+	"boxer":                         breedGroupWorking,
+	"doberman_pinscher":             breedGroupWorking,
+	"rottweiler":                    breedGroupWorking,
+	"akita":                         breedGroupWorking,
+	"great_dane":                    breedGroupGiant,
+	"mastiff":                       breedGroupGiant,
+	"newfoundland":                  breedGroupGiant,
+	"saint_bernard":                 breedGroupGiant,
+	"bernese_mountain_dog":          breedGroupGiant,
+	"beagle":                        breedGroupHound,
+	"dachshund":                     breedGroupHound,
+	"basset_hound":                  breedGroupHound,
+	"bloodhound":                    breedGroupHound,
+	"rhodesian_ridgeback":           breedGroupHound,
+	"airedale_terrier":              breedGroupTerrier,
+	"miniature_schnauzer":           breedGroupTerrier,
+	"yorkshire_terrier":             breedGroupTerrier,
+	"boston_terrier":                breedGroupTerrier,
+	"poodle":                        breedGroupCompanion,
+	"french_bulldog":                breedGroupCompanion,
+	"bulldog":                       breedGroupCompanion,
+	"cavalier_king_charles_spaniel": breedGroupCompanion,
+	"pug":                           breedGroupToy,
+	"chihuahua":                     breedGroupToy,
+	"pomeranian":                    breedGroupToy,
+	"maltese":                       breedGroupToy,
+	"shih_tzu":                      breedGroupToy,
+	"havanese":                      breedGroupToy,
+}
+
+// brachycephalicBreeds short nosed, struggle in heat
+var brachycephalicBreeds = map[string]bool{
+	"pug":            true,
+	"bulldog":        true,
+	"french_bulldog": true,
+	"boston_terrier": true,
+	"shih_tzu":       true,
+	"boxer":          true,
+}
+
+// healthAdjustment keywords in the free text health condition, each matching the start of a whole word
+type healthAdjustment struct {
+	Keywords  []string
+	Factor    float64
+	Intensity string
+	Reason    string
+}
+
+// healthNegations words that rule out their whole clause, as in "no heart problems" or "seizure free"
+var healthNegations = map[string]bool{
+	"no":      true,
+	"not":     true,
+	"never":   true,
+	"without": true,
+	"free":    true,
+	"healed":  true,
+}
+
+var healthAdjustments = []healthAdjustment{
+	{[]string{"surgery", "injur", "recover", "fracture"}, 0.4, IntensityLow, "recovering, keep walks short and gentle"},
+	{[]string{"heart", "cardiac", "breath", "respiratory", "asthma"}, 0.5, IntensityLow, "heart or breathing condition, avoid exertion"},
+	{[]string{"arthritis", "joint", "hip", "elbow", "dysplasia", "luxat"}, 0.6, IntensityLow, "joint condition, prefer several short walks on soft ground"},
+	{[]string{"pregnan"}, 0.6, IntensityLow, "pregnant, moderate gentle walks"},
+	{[]string{"diabet", "epilep", "seizure"}, 0.8, IntensityModerate, "chronic condition, keep the routine steady"},
+	{[]string{"obes", "overweight"}, 1.1, IntensityModerate, "overweight, a bit more walking at an easy pace"},
+}
+
+// WeatherCondition current weather at the walk location
+type WeatherCondition struct {
+	TempC     float64 `json:"tempC"`
+	Humidity  float64 `json:"humidity"`
+	WindSpeed float64 `json:"windSpeed"`
+	Uvi       float64 `json:"uvi"`
+	Main      string  `json:"main"`
+}
+
+// ExerciseRecommendation suggested walking for today
+type ExerciseRecommendation struct {
+	BreedGroup  string            `json:"breedGroup"`
+	AgeGroup    string            `json:"ageGroup"`
+	Minutes     int               `json:"minutes"`
+	DistanceKm  float64           `json:"distanceKm"`
+	Intensity   string            `json:"intensity"`
+	WalksPerDay int               `json:"walksPerDay"`
+	Reasons     []string          `json:"reasons"`
+	Weather     *WeatherCondition `json:"weather"`
+}
+
+// BreedGroupOf known breeds by table, unknown or mixed ones by weight
+func BreedGroupOf(breed string, weight float64) BreedGroup {
+	if group, ok := breedGroups[breed]; ok {
+		return group
+	}
+	switch {
+	case weight >= 40:
+		return breedGroupGiant
+	case weight >= 25:
+		return breedGroupWorking
+	case weight >= 10:
+		return breedGroupTerrier
+	case weight > 0:
+		return breedGroupToy
+	}
+	return breedGroupCompanion
+}
+
+// RecommendExercise breed group scaled by age, health condition and weather, weather may be nil
+func RecommendExercise(dog Dog, weather *WeatherCondition) ExerciseRecommendation {
+	group := BreedGroupOf(dog.Breed, dog.Weight)
+	minutes := group.Minutes
+	intensity := group.Intensity
+	rec := ExerciseRecommendation{
+		BreedGroup: group.Name,
+		AgeGroup:   dog.Age,
+		Weather:    weather,
+		Reasons:    []string{fmt.Sprintf("%s breeds need about %.0f minutes a day", group.Name, group.Minutes)},
+	}
+
+	switch dog.Age {
+	case DogAgeYoung:
+		// growing joints, short bursts rather than long walks
+		minutes = math.Min(minutes*0.5, 30)
+		intensity = capIntensity(intensity, IntensityModerate)
+		rec.Reasons = append(rec.Reasons, "young dog, short walks to protect growing joints")
+	case DogAgeOld:
+		minutes *= 0.7
+		intensity = lowerIntensity(intensity)
+		rec.Reasons = append(rec.Reasons, "senior dog, gentler and shorter walks")
+	}
+
+	clauses := healthClauses(dog.HealthCondition)
+	for _, adjustment := range healthAdjustments {
+		if !clausesMatch(clauses, adjustment.Keywords) {
+			continue
+		}
+		minutes *= adjustment.Factor
+		intensity = capIntensity(intensity, adjustment.Intensity)
+		rec.Reasons = append(rec.Reasons, adjustment.Reason)
+	}
+
+	if weather != nil {
+		minutes, intensity = adjustForWeather(dog, weather, minutes, intensity, &rec.Reasons)
+	}
+
+	rec.Minutes = int(math.Round(math.Max(minutes, 10)))
+	rec.Intensity = intensity
+	rec.DistanceKm = math.Round(float64(rec.Minutes)/60*walkingSpeedKmh(intensity)*10) / 10
+	rec.WalksPerDay = 1
+	if rec.Minutes > 45 || dog.Age == DogAgeYoung || dog.Age == DogAgeOld {
+		rec.WalksPerDay = 2
+	}
+	if rec.Minutes > 90 {
+		rec.WalksPerDay = 3
+	}
+	return rec
+}
+
+func adjustForWeather(dog Dog, weather *WeatherCondition, minutes float64, intensity string, reasons *[]string) (float64, string) {
+	hotThreshold := 28.0
+	if brachycephalicBreeds[dog.Breed] {
+		hotThreshold = 24
+	}
+	switch {
+	case weather.TempC >= hotThreshold+4:
+		minutes *= 0.4
+		intensity = IntensityLow
+		*reasons = append(*reasons, fmt.Sprintf("%.0f°C is too hot, walk early morning or late evening", weather.TempC))
+	case weather.TempC >= hotThreshold:
+		minutes *= 0.7
+		intensity = capIntensity(intensity, IntensityModerate)
+		*reasons = append(*reasons, fmt.Sprintf("%.0f°C is warm, bring water and keep to shade", weather.TempC))
+	case weather.TempC <= 0:
+		minutes *= 0.7
+		if BreedGroupOf(dog.Breed, dog.Weight) == breedGroupToy {
+			minutes *= 0.8
+		}
+		*reasons = append(*reasons, fmt.Sprintf("%.0f°C is cold, shorter walks", weather.TempC))
+	}
+
+	switch weather.Main {
+	case "Thunderstorm":
+		minutes *= 0.5
+		intensity = IntensityLow
+		*reasons = append(*reasons, "thunderstorm, keep it to quick outings")
+	case "Rain", "Drizzle", "Snow":
+		minutes *= 0.8
+		*reasons = append(*reasons, strings.ToLower(weather.Main)+", a shorter walk is fine")
+	}
+	if weather.WindSpeed >= 12 {
+		minutes *= 0.9
+		*reasons = append(*reasons, "strong wind")
+	}
+	if weather.Uvi >= 8 {
+		intensity = capIntensity(intensity, IntensityModerate)
+		*reasons = append(*reasons, "very high UV, avoid midday and hot pavement")
+	}
+	return minutes, intensity
+}
+
+// walkingSpeedKmh typical pace for an intensity
+func walkingSpeedKmh(intensity string) float64 {
+	switch intensity {
+	case IntensityLow:
+		return 3
+	case IntensityHigh:
+		return 5.5
+	}
+	return 4.5
+}
+
+func intensityRank(intensity string) int {
+	for i, name := range intensityOrder {
+		if name == intensity {
+			return i
+		}
+	}
+	return 1
+}
+
+// capIntensity at most limit
+func capIntensity(intensity string, limit string) string {
+	if intensityRank(intensity) > intensityRank(limit) {
+		return limit
+	}
+	return intensity
+}
+
+// lowerIntensity one step down, low stays low
+func lowerIntensity(intensity string) string {
+	rank := intensityRank(intensity)
+	if rank > 0 {
+		rank--
+	}
+	return intensityOrder[rank]
+}
+
+// healthClauses lower cased words of each clause of the condition, negated clauses left out
+func healthClauses(condition string) [][]string {
+	condition = strings.ReplaceAll(strings.ToLower(condition), "n't", " not")
+	parts := strings.FieldsFunc(condition, func(r rune) bool {
+		return strings.ContainsRune(",;.!?\n", r)
+	})
+
+	clauses := [][]string{}
+	for _, part := range parts {
+		words := []string{}
+		for _, word := range strings.FieldsFunc(part, func(r rune) bool { return !unicode.IsLetter(r) }) {
+			// "arthritis but no heart problems" holds two clauses
+			if word == "but" {
+				clauses = appendHealthClause(clauses, words)
+				words = []string{}
+				continue
+			}
+			words = append(words, word)
+		}
+		clauses = appendHealthClause(clauses, words)
+	}
+	return clauses
+}
+
+func appendHealthClause(clauses [][]string, words []string) [][]string {
+	for i, word := range words {
+		if healthNegations[word] {
+			return clauses
+		}
+		// "fully recovered from surgery"
+		if word == "fully" && i+1 < len(words) && strings.HasPrefix(words[i+1], "recover") {
+			return clauses
+		}
+	}
+	if len(words) == 0 {
+		return clauses
+	}
+	return append(clauses, words)
+}
+
+// clausesMatch a word of any clause starts with one of the keywords
+func clausesMatch(clauses [][]string, keywords []string) bool {
+	for _, words := range clauses {
+		for _, word := range words {
+			for _, keyword := range keywords {
+				if strings.HasPrefix(word, keyword) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,29 @@
+package schema
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// FriendList
+type FriendList []Friend
+
+// Friend one way, FriendId may see what UserId shares with friends
+type Friend struct {
+	ID          uint       `gorm:"primary_key" json:"id"`
+	UserId      uint       `gorm:"column:user_id;not null;uniqueIndex:idx_friend_user" json:"userId"`
+	FriendId    uint       `gorm:"column:friend_id;not null;uniqueIndex:idx_friend_user;index:idx_friend_friend" json:"friendId"`
+	CreatedTime *time.Time `gorm:"column:created_time;default:current_time" json:"createdTime"`
+}
+
+// TableName
+func (Friend) TableName() string {
+	return "user_friend"
+}
+
+// GetFriendDB
+func GetFriendDB(ctx context.Context, defDB *gorm.DB) *gorm.DB {
+	return GetDBWithModel(ctx, defDB, new(Friend))
+}
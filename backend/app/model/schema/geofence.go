@@ -0,0 +1,106 @@
+package schema
+
+import (
+	"context"
+	"time"
+
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/util"
+	"gorm.io/gorm"
+)
+
+const (
+	GeofenceKindCircle  = "circle"
+	GeofenceKindPolygon = "polygon"
+
+	GeofenceEventEnter = "enter"
+	GeofenceEventExit  = "exit"
+
+	// MaxGeofenceVertices
+	MaxGeofenceVertices = 100
+)
+
+// GeofenceList
+type GeofenceList []Geofence
+
+// Geofence named circle or polygon, DogId nil applies to every dog of the user
+type Geofence struct {
+	ID          uint         `gorm:"primary_key" json:"id"`
+	UserId      uint         `gorm:"column:user_id;not null;index:idx_geofence_user" json:"userId"`
+	DogId       *uint        `gorm:"column:dog_id;" json:"dogId"`
+	Name        string       `gorm:"column:name;not null" json:"name"`
+	Kind        string       `gorm:"column:kind;not null" json:"kind"`
+	Longitude   float64      `gorm:"column:longitude;not null;default:0" json:"longitude"`
+	Latitude    float64      `gorm:"column:latitude;not null;default:0" json:"latitude"`
+	Radius      float64      `gorm:"column:radius;not null;default:0" json:"radius"`
+	Polygon     MysqlPolygon `gorm:"column:polygon;type:json" json:"polygon"`
+	CreatedTime *time.Time   `gorm:"column:created_time;default:current_time" json:"createdTime"`
+	UpdatedTime *time.Time   `gorm:"column:updated_time;default:current_time" json:"updatedTime"`
+}
+
+// TableName
+func (Geofence) TableName() string {
+	return "geofence"
+}
+
+// GetGeofenceDB
+func GetGeofenceDB(ctx context.Context, defDB *gorm.DB) *gorm.DB {
+	return GetDBWithModel(ctx, defDB, new(Geofence))
+}
+
+// Valid circle needs a radius, polygon at least 3 vertices
+func (g Geofence) Valid() bool {
+	switch g.Kind {
+	case GeofenceKindCircle:
+		return g.Radius > 0 && g.Latitude >= -90 && g.Latitude <= 90 && g.Longitude >= -180 && g.Longitude <= 180
+	case GeofenceKindPolygon:
+		if len(g.Polygon) < 3 || len(g.Polygon) > MaxGeofenceVertices {
+			return false
+		}
+		for _, p := range g.Polygon {
+			if p.Latitude < -90 || p.Latitude > 90 || p.Longitude < -180 || p.Longitude > 180 {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// AppliesTo
+func (g Geofence) AppliesTo(dogId uint) bool {
+	return g.DogId == nil || *g.DogId == dogId
+}
+
+// Contains
+func (g Geofence) Contains(latitude, longitude float64) bool {
+	if g.Kind == GeofenceKindCircle {
+		return util.Haversine(g.Latitude, g.Longitude, latitude, longitude) <= g.Radius
+	}
+	return util.PointInPolygon(latitude, longitude, g.Polygon)
+}
+
+// GeofenceEventList
+type GeofenceEventList []GeofenceEvent
+
+// GeofenceEvent a dog entering or leaving a geofence
+type GeofenceEvent struct {
+	ID          uint       `gorm:"primary_key" json:"id"`
+	GeofenceId  uint       `gorm:"column:geofence_id;not null;index:idx_geofence_event_fence" json:"geofenceId"`
+	UserId      uint       `gorm:"column:user_id;not null;index:idx_geofence_event_user" json:"userId"`
+	DogId       uint       `gorm:"column:dog_id;not null" json:"dogId"`
+	RouteId     uint       `gorm:"column:route_id;not null" json:"routeId"`
+	Type        string     `gorm:"column:type;not null" json:"type"`
+	Longitude   float64    `gorm:"column:longitude;not null" json:"longitude"`
+	Latitude    float64    `gorm:"column:latitude;not null" json:"latitude"`
+	CreatedTime *time.Time `gorm:"column:created_time;default:current_time" json:"createdTime"`
+}
+
+// TableName
+func (GeofenceEvent) TableName() string {
+	return "geofence_event"
+}
+
+// GetGeofenceEventDB
+func GetGeofenceEventDB(ctx context.Context, defDB *gorm.DB) *gorm.DB {
+	return GetDBWithModel(ctx, defDB, new(GeofenceEvent))
+}
@@ -0,0 +1,132 @@
+package schema
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// health record kinds, used by the upcoming-due list
+const (
+	HealthKindVaccination = "vaccination"
+	HealthKindMedication  = "medication"
+	HealthKindVetFollowUp = "vet_follow_up"
+)
+
+// VaccinationList
+type VaccinationList []Vaccination
+
+// Vaccination DueDate is when the next shot is due
+type Vaccination struct {
+	ID               uint           `gorm:"primary_key" json:"id"`
+	DogId            uint           `gorm:"column:dog_id;not null;index:idx_vaccination_dog" json:"dogId"`
+	UserId           uint           `gorm:"column:user_id;not null" json:"userId"`
+	Name             string         `gorm:"column:name;not null" json:"name"`
+	AdministeredDate *time.Time     `gorm:"column:administered_date;" json:"administeredDate"`
+	DueDate          *time.Time     `gorm:"column:due_date;index:idx_vaccination_due" json:"dueDate"`
+	VetName          string         `gorm:"column:vet_name;not null" json:"vetName"`
+	Notes            string         `gorm:"column:notes;type:text" json:"notes"`
+	CreatedTime      *time.Time     `gorm:"column:created_time;default:current_time" json:"createdTime"`
+	UpdatedTime      *time.Time     `gorm:"column:updated_time;default:current_time" json:"updatedTime"`
+	DeletedAt        gorm.DeletedAt `gorm:"column:deleted_at;" json:"-"`
+}
+
+// TableName
+func (Vaccination) TableName() string {
+	return "dog_vaccination"
+}
+
+// GetVaccinationDB
+func GetVaccinationDB(ctx context.Context, defDB *gorm.DB) *gorm.DB {
+	return GetDBWithModel(ctx, defDB, new(Vaccination))
+}
+
+// MedicationList
+type MedicationList []Medication
+
+// Medication one dose every IntervalHours from StartTime, until EndTime when set
+type Medication struct {
+	ID            uint           `gorm:"primary_key" json:"id"`
+	DogId         uint           `gorm:"column:dog_id;not null;index:idx_medication_dog" json:"dogId"`
+	UserId        uint           `gorm:"column:user_id;not null" json:"userId"`
+	Name          string         `gorm:"column:name;not null" json:"name"`
+	Dosage        string         `gorm:"column:dosage;not null" json:"dosage"`
+	IntervalHours int            `gorm:"column:interval_hours;not null" json:"intervalHours"`
+	StartTime     *time.Time     `gorm:"column:start_time;not null" json:"startTime"`
+	EndTime       *time.Time     `gorm:"column:end_time;" json:"endTime"`
+	Notes         string         `gorm:"column:notes;type:text" json:"notes"`
+	CreatedTime   *time.Time     `gorm:"column:created_time;default:current_time" json:"createdTime"`
+	UpdatedTime   *time.Time     `gorm:"column:updated_time;default:current_time" json:"updatedTime"`
+	DeletedAt     gorm.DeletedAt `gorm:"column:deleted_at;" json:"-"`
+}
+
+// TableName
+func (Medication) TableName() string {
+	return "dog_medication"
+}
+
+// GetMedicationDB
+func GetMedicationDB(ctx context.Context, defDB *gorm.DB) *gorm.DB {
+	return GetDBWithModel(ctx, defDB, new(Medication))
+}
+
+// NextDose first dose at or after t, nil once the course has ended
+func (m Medication) NextDose(t time.Time) *time.Time {
+	if m.StartTime == nil || m.IntervalHours <= 0 {
+		return nil
+	}
+	next := *m.StartTime
+	if t.After(next) {
+		interval := time.Duration(m.IntervalHours) * time.Hour
+		steps := (t.Sub(next) + interval - 1) / interval
+		next = next.Add(steps * interval)
+	}
+	if m.EndTime != nil && next.After(*m.EndTime) {
+		return nil
+	}
+	return &next
+}
+
+// VetVisitList
+type VetVisitList []VetVisit
+
+// VetVisit Attachments are urls of uploaded images
+type VetVisit struct {
+	ID           uint           `gorm:"primary_key" json:"id"`
+	DogId        uint           `gorm:"column:dog_id;not null;index:idx_vet_visit_dog" json:"dogId"`
+	UserId       uint           `gorm:"column:user_id;not null" json:"userId"`
+	VisitTime    *time.Time     `gorm:"column:visit_time;not null" json:"visitTime"`
+	Clinic       string         `gorm:"column:clinic;not null" json:"clinic"`
+	Reason       string         `gorm:"column:reason;not null" json:"reason"`
+	Notes        string         `gorm:"column:notes;type:text" json:"notes"`
+	Attachments  MysqlJSONArray `gorm:"column:attachments;type:json" json:"attachments"`
+	FollowUpDate *time.Time     `gorm:"column:follow_up_date;index:idx_vet_visit_follow_up" json:"followUpDate"`
+	CreatedTime  *time.Time     `gorm:"column:created_time;default:current_time" json:"createdTime"`
+	UpdatedTime  *time.Time     `gorm:"column:updated_time;default:current_time" json:"updatedTime"`
+	DeletedAt    gorm.DeletedAt `gorm:"column:deleted_at;" json:"-"`
+}
+
+// TableName
+func (VetVisit) TableName() string {
+	return "dog_vet_visit"
+}
+
+// GetVetVisitDB
+func GetVetVisitDB(ctx context.Context, defDB *gorm.DB) *gorm.DB {
+	return GetDBWithModel(ctx, defDB, new(VetVisit))
+}
+
+// HealthDueList
+type HealthDueList []HealthDue
+
+// HealthDue something that is due for a dog, overdue when DueTime has passed
+type HealthDue struct {
+	Kind     string     `json:"kind"`
+	RecordId uint       `json:"recordId"`
+	DogId    uint       `json:"dogId"`
+	UserId   uint       `json:"userId"`
+	Name     string     `json:"name"`
+	DueTime  *time.Time `json:"dueTime"`
+	Overdue  bool       `json:"overdue"`
+}
@@ -0,0 +1,130 @@
+package schema
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// household roles, each one grants everything the lower ones do
+const (
+	HouseholdRoleOwner  = "owner"
+	HouseholdRoleWalker = "walker"
+	HouseholdRoleViewer = "viewer"
+)
+
+// householdRoleRank
+var householdRoleRank = map[string]int{
+	HouseholdRoleViewer: 1,
+	HouseholdRoleWalker: 2,
+	HouseholdRoleOwner:  3,
+}
+
+// ValidHouseholdRole
+func ValidHouseholdRole(role string) bool {
+	_, ok := householdRoleRank[role]
+	return ok
+}
+
+// RoleAtLeast whether role grants what min does, the empty role grants nothing
+func RoleAtLeast(role string, min string) bool {
+	return householdRoleRank[role] > 0 && householdRoleRank[role] >= householdRoleRank[min]
+}
+
+// HouseholdRolesAtLeast roles granting what min does
+func HouseholdRolesAtLeast(min string) []string {
+	roles := []string{}
+	for _, role := range []string{HouseholdRoleOwner, HouseholdRoleWalker, HouseholdRoleViewer} {
+		if RoleAtLeast(role, min) {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
+
+// HouseholdList
+type HouseholdList []Household
+
+// Household users sharing their dogs, a dog belongs to at most one household
+type Household struct {
+	ID          uint           `gorm:"primary_key" json:"id"`
+	Name        string         `gorm:"column:name;type:varchar(64);not null" json:"name"`
+	CreatorId   uint           `gorm:"column:creator_id;not null" json:"creatorId"`
+	CreatedTime *time.Time     `gorm:"column:created_time;default:current_time" json:"createdTime"`
+	UpdatedTime *time.Time     `gorm:"column:updated_time;default:current_time" json:"-"`
+	DeletedAt   gorm.DeletedAt `gorm:"column:deleted_at;" json:"-"`
+}
+
+// TableName
+func (Household) TableName() string {
+	return "household"
+}
+
+// GetHouseholdDB
+func GetHouseholdDB(ctx context.Context, defDB *gorm.DB) *gorm.DB {
+	return GetDBWithModel(ctx, defDB, new(Household))
+}
+
+// HouseholdMemberList
+type HouseholdMemberList []HouseholdMember
+
+// HouseholdMember
+type HouseholdMember struct {
+	ID          uint       `gorm:"primary_key" json:"id"`
+	HouseholdId uint       `gorm:"column:household_id;not null;uniqueIndex:idx_household_member" json:"householdId"`
+	UserId      uint       `gorm:"column:user_id;not null;uniqueIndex:idx_household_member;index:idx_household_member_user" json:"userId"`
+	Role        string     `gorm:"column:role;type:varchar(16);not null" json:"role"`
+	CreatedTime *time.Time `gorm:"column:created_time;default:current_time" json:"createdTime"`
+	UpdatedTime *time.Time `gorm:"column:updated_time;default:current_time" json:"-"`
+}
+
+// TableName
+func (HouseholdMember) TableName() string {
+	return "household_member"
+}
+
+// GetHouseholdMemberDB
+func GetHouseholdMemberDB(ctx context.Context, defDB *gorm.DB) *gorm.DB {
+	return GetDBWithModel(ctx, defDB, new(HouseholdMember))
+}
+
+// HouseholdInvite single use token joining a household with the given role
+type HouseholdInvite struct {
+	ID           uint       `gorm:"primary_key" json:"id"`
+	HouseholdId  uint       `gorm:"column:household_id;not null;index:idx_household_invite_household" json:"householdId"`
+	InviterId    uint       `gorm:"column:inviter_id;not null" json:"inviterId"`
+	Role         string     `gorm:"column:role;type:varchar(16);not null" json:"role"`
+	Token        string     `gorm:"column:token;type:varchar(64);not null;uniqueIndex:idx_household_invite_token" json:"token"`
+	ExpiresTime  *time.Time `gorm:"column:expires_time;not null" json:"expiresTime"`
+	AcceptedBy   uint       `gorm:"column:accepted_by;not null;default:0" json:"acceptedBy"`
+	AcceptedTime *time.Time `gorm:"column:accepted_time;" json:"acceptedTime"`
+	CreatedTime  *time.Time `gorm:"column:created_time;default:current_time" json:"createdTime"`
+}
+
+// TableName
+func (HouseholdInvite) TableName() string {
+	return "household_invite"
+}
+
+// GetHouseholdInviteDB
+func GetHouseholdInviteDB(ctx context.Context, defDB *gorm.DB) *gorm.DB {
+	return GetDBWithModel(ctx, defDB, new(HouseholdInvite))
+}
+
+// IsValid not accepted yet and not expired
+func (i HouseholdInvite) IsValid(now time.Time) bool {
+	return i.AcceptedBy == 0 && i.ExpiresTime != nil && i.ExpiresTime.After(now)
+}
+
+// RoleOf role of userId on the dog given the user's role in the dog's household
+// the account the dog was created under is always an owner
+func (d Dog) RoleOf(userId uint, memberRole string) string {
+	if d.UserId == userId {
+		return HouseholdRoleOwner
+	}
+	if d.HouseholdId == 0 {
+		return ""
+	}
+	return memberRole
+}
@@ -0,0 +1,34 @@
+package schema
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// JobRunList
+type JobRunList []JobRun
+
+// JobRun one execution of a scheduled job, Slot is the scheduled time shared by every instance
+type JobRun struct {
+	ID           uint       `gorm:"primary_key" json:"id"`
+	Job          string     `gorm:"column:job;type:varchar(64);not null;index:idx_job_run_job" json:"job"`
+	Instance     string     `gorm:"column:instance;type:varchar(128);not null" json:"instance"`
+	Slot         *time.Time `gorm:"column:slot;not null" json:"slot"`
+	Status       string     `gorm:"column:status;type:varchar(16);not null" json:"status"`
+	Attempts     int        `gorm:"column:attempts;not null" json:"attempts"`
+	Error        string     `gorm:"column:error;type:text" json:"error"`
+	StartedTime  *time.Time `gorm:"column:started_time;not null" json:"startedTime"`
+	FinishedTime *time.Time `gorm:"column:finished_time;" json:"finishedTime"`
+}
+
+// TableName
+func (JobRun) TableName() string {
+	return "job_run"
+}
+
+// GetJobRunDB
+func GetJobRunDB(ctx context.Context, defDB *gorm.DB) *gorm.DB {
+	return GetDBWithModel(ctx, defDB, new(JobRun))
+}
@@ -0,0 +1,79 @@
+package schema
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	LostAlertStatusOpen     = "open"
+	LostAlertStatusResolved = "resolved"
+)
+
+// LostAlertList
+type LostAlertList []LostAlert
+
+// LostAlert a missing dog broadcast to the users around its last seen location
+type LostAlert struct {
+	ID           uint       `gorm:"primary_key" json:"id"`
+	DogId        uint       `gorm:"column:dog_id;not null;index:idx_lost_alert_dog" json:"dogId"`
+	UserId       uint       `gorm:"column:user_id;not null" json:"userId"`
+	Description  string     `gorm:"column:description;not null" json:"description"`
+	PhotoUrl     string     `gorm:"column:photo_url;not null" json:"photoUrl"`
+	Longitude    float64    `gorm:"column:longitude;not null;index:idx_lost_alert_location,priority:2" json:"longitude"`
+	Latitude     float64    `gorm:"column:latitude;not null;index:idx_lost_alert_location,priority:1" json:"latitude"`
+	LastSeenTime *time.Time `gorm:"column:last_seen_time;not null" json:"lastSeenTime"`
+	Status       string     `gorm:"column:status;not null;default:'open'" json:"status"`
+	ResolvedTime *time.Time `gorm:"column:resolved_time;" json:"resolvedTime"`
+	CreatedTime  *time.Time `gorm:"column:created_time;default:current_time" json:"createdTime"`
+}
+
+// TableName
+func (LostAlert) TableName() string {
+	return "lost_alert"
+}
+
+// GetLostAlertDB
+func GetLostAlertDB(ctx context.Context, defDB *gorm.DB) *gorm.DB {
+	return GetDBWithModel(ctx, defDB, new(LostAlert))
+}
+
+// LostAlertWithDogList
+type LostAlertWithDogList []LostAlertWithDog
+
+// LostAlertWithDog
+type LostAlertWithDog struct {
+	LostAlert
+	DogName  string  `gorm:"column:dog_name;->" json:"dogName"`
+	DogBreed string  `gorm:"column:dog_breed;->" json:"dogBreed"`
+	DogImg   string  `gorm:"column:dog_img;->" json:"dogImg"`
+	Distance float64 `gorm:"column:distance;->" json:"distance"`
+}
+
+// LostSightingList
+type LostSightingList []LostSighting
+
+// LostSighting a report from another user who saw the dog
+type LostSighting struct {
+	ID          uint       `gorm:"primary_key" json:"id"`
+	AlertId     uint       `gorm:"column:alert_id;not null;index:idx_lost_sighting_alert" json:"alertId"`
+	UserId      uint       `gorm:"column:user_id;not null" json:"userId"`
+	Longitude   float64    `gorm:"column:longitude;not null" json:"longitude"`
+	Latitude    float64    `gorm:"column:latitude;not null" json:"latitude"`
+	Note        string     `gorm:"column:note;not null" json:"note"`
+	PhotoUrl    string     `gorm:"column:photo_url;not null" json:"photoUrl"`
+	SeenTime    *time.Time `gorm:"column:seen_time;not null" json:"seenTime"`
+	CreatedTime *time.Time `gorm:"column:created_time;default:current_time" json:"createdTime"`
+}
+
+// TableName
+func (LostSighting) TableName() string {
+	return "lost_sighting"
+}
+
+// GetLostSightingDB
+func GetLostSightingDB(ctx context.Context, defDB *gorm.DB) *gorm.DB {
+	return GetDBWithModel(ctx, defDB, new(LostSighting))
+}
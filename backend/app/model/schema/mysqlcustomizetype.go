@@ -1,11 +1,16 @@
 package schema
 
 import (
+	"context"
 	"database/sql/driver"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"strings"
+
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/util"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type MYSQLSET []string
@@ -104,3 +109,56 @@ func (p MysqlPoint) Value() (driver.Value, error) {
 
 	return fmt.Sprintf("POINT(%f %f)", p.Longitude, p.Latitude), nil
 }
+
+// SRID4326 wkt of srid 4326 is read as longitude latitude
+const SRID4326 = "ST_GeomFromText(?, 4326, 'axis-order=long-lat')"
+
+// GormValue write as a geometry of srid 4326 so the spatial index can be used
+func (p MysqlPoint) GormValue(ctx context.Context, db *gorm.DB) clause.Expr {
+	return clause.Expr{SQL: SRID4326, Vars: []interface{}{fmt.Sprintf("POINT(%f %f)", p.Longitude, p.Latitude)}}
+}
+
+// MysqlPolygon vertices stored as a json array
+type MysqlPolygon []util.GeoPoint
+
+func (m *MysqlPolygon) Scan(value interface{}) error {
+	if value == nil {
+		*m = nil
+		return nil
+	}
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New(fmt.Sprint("Failed to unmarshal polygon value:", value))
+	}
+	vertices := [][2]float64{}
+	if err := json.Unmarshal(bytes, &vertices); err != nil {
+		return err
+	}
+	polygon := make(MysqlPolygon, 0, len(vertices))
+	for _, v := range vertices {
+		polygon = append(polygon, util.GeoPoint{Longitude: v[0], Latitude: v[1]})
+	}
+	*m = polygon
+	return nil
+}
+
+// Value [[longitude, latitude], ...] as in geojson
+func (m MysqlPolygon) Value() (driver.Value, error) {
+	if m == nil {
+		return nil, nil
+	}
+	return json.Marshal(m.vertices())
+}
+
+// MarshalJSON
+func (m MysqlPolygon) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.vertices())
+}
+
+func (m MysqlPolygon) vertices() [][2]float64 {
+	vertices := make([][2]float64, 0, len(m))
+	for _, p := range m {
+		vertices = append(vertices, [2]float64{p.Longitude, p.Latitude})
+	}
+	return vertices
+}
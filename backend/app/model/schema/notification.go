@@ -0,0 +1,121 @@
+package schema
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// notification categories
+const (
+	NotificationCategoryLostAlert    = "lost_alert"
+	NotificationCategoryLostSighting = "lost_sighting"
+	NotificationCategoryGeofence     = "geofence"
+	NotificationCategoryWalkGoal     = "walk_goal"
+	NotificationCategoryHealth       = "health"
+	NotificationCategoryDelegation   = "delegation"
+	NotificationCategorySystem       = "system"
+)
+
+// NotificationCategories every category a user can set preferences for
+var NotificationCategories = []string{
+	NotificationCategoryLostAlert,
+	NotificationCategoryLostSighting,
+	NotificationCategoryGeofence,
+	NotificationCategoryWalkGoal,
+	NotificationCategoryHealth,
+	NotificationCategoryDelegation,
+	NotificationCategorySystem,
+}
+
+// ValidNotificationCategory
+func ValidNotificationCategory(category string) bool {
+	for _, item := range NotificationCategories {
+		if item == category {
+			return true
+		}
+	}
+	return false
+}
+
+// NotificationList
+type NotificationList []Notification
+
+// Notification in-app inbox entry
+type Notification struct {
+	ID          uint       `gorm:"primary_key" json:"id"`
+	UserId      uint       `gorm:"column:user_id;not null;index:idx_notification_user,priority:1" json:"userId"`
+	Category    string     `gorm:"column:category;not null" json:"category"`
+	Title       string     `gorm:"column:title;not null" json:"title"`
+	Body        string     `gorm:"column:body;not null" json:"body"`
+	RefId       uint       `gorm:"column:ref_id;not null;default:0" json:"refId"`
+	ReadTime    *time.Time `gorm:"column:read_time;index:idx_notification_user,priority:2" json:"readTime"`
+	CreatedTime *time.Time `gorm:"column:created_time;default:current_time" json:"createdTime"`
+}
+
+// TableName
+func (Notification) TableName() string {
+	return "notification"
+}
+
+// GetNotificationDB
+func GetNotificationDB(ctx context.Context, defDB *gorm.DB) *gorm.DB {
+	return GetDBWithModel(ctx, defDB, new(Notification))
+}
+
+// NotificationPreferenceList
+type NotificationPreferenceList []NotificationPreference
+
+// NotificationPreference channels a user wants for one category
+type NotificationPreference struct {
+	ID       uint   `gorm:"primary_key" json:"-"`
+	UserId   uint   `gorm:"column:user_id;not null;uniqueIndex:idx_notification_preference" json:"userId"`
+	Category string `gorm:"column:category;not null;uniqueIndex:idx_notification_preference" json:"category"`
+	InApp    bool   `gorm:"column:in_app;not null;default:1" json:"inApp"`
+	Email    bool   `gorm:"column:email;not null;default:0" json:"email"`
+	Push     bool   `gorm:"column:push;not null;default:1" json:"push"`
+}
+
+// TableName
+func (NotificationPreference) TableName() string {
+	return "notification_preference"
+}
+
+// GetNotificationPreferenceDB
+func GetNotificationPreferenceDB(ctx context.Context, defDB *gorm.DB) *gorm.DB {
+	return GetDBWithModel(ctx, defDB, new(NotificationPreference))
+}
+
+// DefaultNotificationPreference inbox and push, no email
+func DefaultNotificationPreference(userId uint, category string) NotificationPreference {
+	return NotificationPreference{
+		UserId:   userId,
+		Category: category,
+		InApp:    true,
+		Push:     true,
+	}
+}
+
+// PushDeviceList
+type PushDeviceList []PushDevice
+
+// PushDevice push token of one app install
+type PushDevice struct {
+	ID          uint       `gorm:"primary_key" json:"id"`
+	UserId      uint       `gorm:"column:user_id;not null;index:idx_push_device_user" json:"userId"`
+	Token       string     `gorm:"column:token;type:varchar(255);not null;uniqueIndex:idx_push_device_token" json:"token"`
+	Platform    string     `gorm:"column:platform;not null" json:"platform"`
+	CreatedTime *time.Time `gorm:"column:created_time;default:current_time" json:"createdTime"`
+	UpdatedTime *time.Time `gorm:"column:updated_time;default:current_time" json:"updatedTime"`
+}
+
+// TableName
+func (PushDevice) TableName() string {
+	return "push_device"
+}
+
+// GetPushDeviceDB
+func GetPushDeviceDB(ctx context.Context, defDB *gorm.DB) *gorm.DB {
+	return GetDBWithModel(ctx, defDB, new(PushDevice))
+}
@@ -0,0 +1,203 @@
+package schema
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// personality tags, as offered on registration
+const (
+	PersonalityFriendly     = "friendly"
+	PersonalityOutgoing     = "outgoing"
+	PersonalityPlayful      = "playful"
+	PersonalityLoyal        = "loyal"
+	PersonalityGentle       = "gentle"
+	PersonalityCurious      = "curious"
+	PersonalityEnergetic    = "energetic"
+	PersonalityAffectionate = "affectionate"
+	PersonalityIndependent  = "independent"
+	PersonalityCalm         = "calm"
+)
+
+// weight of each part of the playmate score, summing to 1
+const (
+	playmateWeightPersonality = 0.35
+	playmateWeightEnergy      = 0.25
+	playmateWeightSize        = 0.25
+	playmateWeightAge         = 0.15
+)
+
+// sociableTags dogs with one of them enjoy meeting other dogs
+var sociableTags = []string{PersonalityFriendly, PersonalityOutgoing, PersonalityPlayful}
+
+// PlaymateScore each part between 0 and 1
+type PlaymateScore struct {
+	Personality float64 `json:"personality"`
+	Energy      float64 `json:"energy"`
+	Size        float64 `json:"size"`
+	Age         float64 `json:"age"`
+}
+
+// PlaymateMatch Score between 0 and 100, Reasons explain it from the point of view of the other dog
+type PlaymateMatch struct {
+	Dog       DogWithDistance `json:"dog"`
+	Score     int             `json:"score"`
+	Breakdown PlaymateScore   `json:"breakdown"`
+	Reasons   []string        `json:"reasons"`
+}
+
+// EnergyLevel breed group intensity, moved by age and by the energetic / calm tags
+func EnergyLevel(dog Dog) string {
+	rank := intensityRank(BreedGroupOf(dog.Breed, dog.Weight).Intensity)
+	switch dog.Age {
+	case DogAgeYoung:
+		rank++
+	case DogAgeOld:
+		rank--
+	}
+	tags := personalityTags(dog)
+	if tags[PersonalityEnergetic] || tags[PersonalityPlayful] {
+		rank++
+	}
+	if tags[PersonalityCalm] || tags[PersonalityGentle] {
+		rank--
+	}
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(intensityOrder) {
+		rank = len(intensityOrder) - 1
+	}
+	return intensityOrder[rank]
+}
+
+// MatchPlaymate how well other would play with dog
+func MatchPlaymate(dog Dog, other DogWithDistance) PlaymateMatch {
+	match := PlaymateMatch{Dog: other, Reasons: []string{}}
+	match.Breakdown.Personality = matchPersonality(dog, other.Dog, &match.Reasons)
+	match.Breakdown.Energy = matchEnergy(dog, other.Dog, &match.Reasons)
+	match.Breakdown.Size = matchSize(dog, other.Dog, &match.Reasons)
+	match.Breakdown.Age = matchAge(dog, other.Dog, &match.Reasons)
+
+	score := match.Breakdown.Personality*playmateWeightPersonality +
+		match.Breakdown.Energy*playmateWeightEnergy +
+		match.Breakdown.Size*playmateWeightSize +
+		match.Breakdown.Age*playmateWeightAge
+	match.Score = int(math.Round(score * 100))
+	return match
+}
+
+// matchPersonality shared tags, bonus when both are sociable, neutral when a dog has no tags
+func matchPersonality(dog Dog, other Dog, reasons *[]string) float64 {
+	tags, otherTags := personalityTags(dog), personalityTags(other)
+	if len(tags) == 0 || len(otherTags) == 0 {
+		return 0.5
+	}
+
+	shared := []string{}
+	for tag := range tags {
+		if otherTags[tag] {
+			shared = append(shared, tag)
+		}
+	}
+	sort.Strings(shared)
+	union := len(tags) + len(otherTags) - len(shared)
+	score := float64(len(shared)) / float64(union)
+	if len(shared) > 0 {
+		*reasons = append(*reasons, fmt.Sprintf("both are %s", strings.Join(shared, ", ")))
+	}
+
+	sociable, otherSociable := hasAnyTag(tags, sociableTags), hasAnyTag(otherTags, sociableTags)
+	switch {
+	case sociable && otherSociable:
+		score += 0.3
+		*reasons = append(*reasons, fmt.Sprintf("%s enjoys meeting other dogs", other.Name))
+	case !sociable && !otherSociable && (tags[PersonalityIndependent] || otherTags[PersonalityIndependent]):
+		score -= 0.2
+		*reasons = append(*reasons, "neither is keen on company")
+	}
+	return math.Max(0, math.Min(1, score))
+}
+
+// matchEnergy 1 for the same level, 0 for low against high
+func matchEnergy(dog Dog, other Dog, reasons *[]string) float64 {
+	level, otherLevel := EnergyLevel(dog), EnergyLevel(other)
+	diff := intensityRank(level) - intensityRank(otherLevel)
+	switch diff {
+	case 0:
+		*reasons = append(*reasons, fmt.Sprintf("same %s energy", level))
+		return 1
+	case 1, -1:
+		return 0.5
+	}
+	*reasons = append(*reasons, fmt.Sprintf("%s has %s energy, much different", other.Name, otherLevel))
+	return 0
+}
+
+// matchSize by weight ratio, dogs within 60% of each other are alike, unknown weights are neutral
+func matchSize(dog Dog, other Dog, reasons *[]string) float64 {
+	if dog.Weight <= 0 || other.Weight <= 0 {
+		return 0.5
+	}
+	ratio := math.Min(dog.Weight, other.Weight) / math.Max(dog.Weight, other.Weight)
+	if ratio >= 0.6 {
+		*reasons = append(*reasons, fmt.Sprintf("similar size, %.0f kg and %.0f kg", dog.Weight, other.Weight))
+		return 1
+	}
+	if ratio < 0.3 {
+		*reasons = append(*reasons, fmt.Sprintf("big size difference, %.0f kg and %.0f kg", dog.Weight, other.Weight))
+	}
+	return ratio / 0.6
+}
+
+// matchAge same age group 1, young against old 0.2
+func matchAge(dog Dog, other Dog, reasons *[]string) float64 {
+	rank, otherRank := ageRank(dog.Age), ageRank(other.Age)
+	if rank < 0 || otherRank < 0 {
+		return 0.5
+	}
+	switch math.Abs(float64(rank - otherRank)) {
+	case 0:
+		*reasons = append(*reasons, fmt.Sprintf("both %s", dog.Age))
+		return 1
+	case 1:
+		return 0.6
+	}
+	*reasons = append(*reasons, fmt.Sprintf("%s is %s", other.Name, other.Age))
+	return 0.2
+}
+
+func ageRank(age string) int {
+	switch age {
+	case DogAgeYoung:
+		return 0
+	case DogAgeAdult:
+		return 1
+	case DogAgeOld:
+		return 2
+	}
+	return -1
+}
+
+// personalityTags lower cased set
+func personalityTags(dog Dog) map[string]bool {
+	tags := make(map[string]bool, len(dog.Personality))
+	for _, tag := range dog.Personality {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag != "" {
+			tags[tag] = true
+		}
+	}
+	return tags
+}
+
+func hasAnyTag(tags map[string]bool, want []string) bool {
+	for _, tag := range want {
+		if tags[tag] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,131 @@
+package schema
+
+import (
+	"context"
+	"time"
+
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/util"
+	"gorm.io/gorm"
+)
+
+const (
+	VisibilityEveryone = "everyone"
+	VisibilityFriends  = "friends"
+	VisibilityNobody   = "nobody"
+)
+
+// MaxFuzzMetres largest fuzz grid a user may pick, a masked location is never further than this from the true one
+const MaxFuzzMetres = 5000
+
+// UserPrivacy who may see the location of a user's dogs
+type UserPrivacy struct {
+	UserId     uint   `gorm:"column:user_id;primary_key" json:"userId"`
+	Visibility string `gorm:"column:visibility;not null;default:'everyone'" json:"visibility"`
+	// FuzzMetres grid size the shared coordinates are snapped to, 0 shares exact coordinates
+	FuzzMetres  uint       `gorm:"column:fuzz_metres;not null;default:0" json:"fuzzMetres"`
+	CreatedTime *time.Time `gorm:"column:created_time;default:current_time" json:"createdTime"`
+	UpdatedTime *time.Time `gorm:"column:updated_time;default:current_time" json:"updatedTime"`
+}
+
+// TableName
+func (UserPrivacy) TableName() string {
+	return "user_privacy"
+}
+
+// GetUserPrivacyDB
+func GetUserPrivacyDB(ctx context.Context, defDB *gorm.DB) *gorm.DB {
+	return GetDBWithModel(ctx, defDB, new(UserPrivacy))
+}
+
+// DefaultUserPrivacy used when the user never saved any setting
+func DefaultUserPrivacy(userId uint) *UserPrivacy {
+	return &UserPrivacy{
+		UserId:     userId,
+		Visibility: VisibilityEveryone,
+	}
+}
+
+// HomeZoneList
+type HomeZoneList []HomeZone
+
+// HomeZone circle where a user's points are never shared
+type HomeZone struct {
+	ID          uint       `gorm:"primary_key" json:"id"`
+	UserId      uint       `gorm:"column:user_id;not null;index:idx_home_zone_user" json:"userId"`
+	Name        string     `gorm:"column:name;not null" json:"name"`
+	Longitude   float64    `gorm:"column:longitude;not null" json:"longitude"`
+	Latitude    float64    `gorm:"column:latitude;not null" json:"latitude"`
+	Radius      float64    `gorm:"column:radius;not null" json:"radius"`
+	CreatedTime *time.Time `gorm:"column:created_time;default:current_time" json:"createdTime"`
+}
+
+// TableName
+func (HomeZone) TableName() string {
+	return "home_zone"
+}
+
+// GetHomeZoneDB
+func GetHomeZoneDB(ctx context.Context, defDB *gorm.DB) *gorm.DB {
+	return GetDBWithModel(ctx, defDB, new(HomeZone))
+}
+
+// Contains
+func (z HomeZone) Contains(latitude, longitude float64) bool {
+	return util.Haversine(z.Latitude, z.Longitude, latitude, longitude) <= z.Radius
+}
+
+// Contains
+func (list HomeZoneList) Contains(latitude, longitude float64) bool {
+	for _, zone := range list {
+		if zone.Contains(latitude, longitude) {
+			return true
+		}
+	}
+	return false
+}
+
+// Clip drop the points inside any zone
+func (list HomeZoneList) Clip(points []util.GeoPoint) []util.GeoPoint {
+	if len(list) == 0 {
+		return points
+	}
+	clipped := make([]util.GeoPoint, 0, len(points))
+	for _, p := range points {
+		if !list.Contains(p.Latitude, p.Longitude) {
+			clipped = append(clipped, p)
+		}
+	}
+	return clipped
+}
+
+// LocationPolicy privacy settings and home zones of one user
+type LocationPolicy struct {
+	Privacy   *UserPrivacy
+	HomeZones HomeZoneList
+	// Friends users the owner marked as friend
+	Friends map[uint]bool
+}
+
+// Mask the location as seen by viewerId holding role on the dog, ok is false when it must not be shared
+// the owner and anyone with a role on the dog see it unmasked
+func (p *LocationPolicy) Mask(viewerId uint, role string, latitude, longitude float64) (float64, float64, bool) {
+	if p.Privacy.UserId == viewerId || role != "" {
+		return latitude, longitude, true
+	}
+	if p.Privacy.Visibility == VisibilityFriends && !p.Friends[viewerId] {
+		return 0, 0, false
+	}
+	return p.MaskShared(latitude, longitude)
+}
+
+// MaskShared apply the rules which do not depend on the viewer, the friends only check is left to the caller
+func (p *LocationPolicy) MaskShared(latitude, longitude float64) (float64, float64, bool) {
+	if p.Privacy.Visibility == VisibilityNobody {
+		return 0, 0, false
+	}
+	if p.HomeZones.Contains(latitude, longitude) {
+		return 0, 0, false
+	}
+	lat, lon := util.SnapToGrid(latitude, longitude, float64(p.Privacy.FuzzMetres))
+	return lat, lon, true
+}
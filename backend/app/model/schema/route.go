@@ -12,15 +12,66 @@ type RouteList []Route
 
 // Route
 type Route struct {
-	ID          uint           `gorm:"primary_key" json:"ID"`
-	UserId      uint           `gorm:"column:user_id;not null" json:"userId"`
-	DogId       uint           `gorm:"column:dog_id;not null" json:"dogId"`
-	PointCount  uint           `gorm:"column:point_count;not null" json:"pointCount"`
+	ID         uint `gorm:"primary_key" json:"ID"`
+	UserId     uint `gorm:"column:user_id;not null" json:"userId"`
+	DogId      uint `gorm:"column:dog_id;not null" json:"dogId"`
+	PointCount uint `gorm:"column:point_count;not null" json:"pointCount"`
+
+	// DogIds every dog on the walk, from route_dog
+	DogIds []uint `gorm:"-" json:"dogIds"`
+
+	Distance       float64    `gorm:"column:distance;not null;default:0" json:"distance"`
+	ActiveDuration uint       `gorm:"column:active_duration;not null;default:0" json:"activeDuration"`
+	IdleDuration   uint       `gorm:"column:idle_duration;not null;default:0" json:"idleDuration"`
+	AvgSpeed       float64    `gorm:"column:avg_speed;not null;default:0" json:"avgSpeed"`
+	MaxSpeed       float64    `gorm:"column:max_speed;not null;default:0" json:"maxSpeed"`
+	LastPointTime  *time.Time `gorm:"column:last_point_time;" json:"lastPointTime"`
+
+	Status       string     `gorm:"column:status;not null;default:'active'" json:"status"`
+	FinishedTime *time.Time `gorm:"column:finished_time;" json:"finishedTime"`
+
 	CreatedTime *time.Time     `gorm:"column:created_time;default:current_time" json:"createdTime"`
 	UpdatedTime *time.Time     `gorm:"column:updated_time;default:current_time" json:"updatedTime"`
 	DeletedAt   gorm.DeletedAt `gorm:"column:deleted_at;" json:"-"`
 }
 
+// route status
+const (
+	RouteStatusActive    = "active"
+	RouteStatusPaused    = "paused"
+	RouteStatusFinished  = "finished"
+	RouteStatusAbandoned = "abandoned"
+)
+
+// routeTransitions allowed next status for each status, finished and abandoned are terminal
+var routeTransitions = map[string][]string{
+	RouteStatusActive: {RouteStatusPaused, RouteStatusFinished, RouteStatusAbandoned},
+	RouteStatusPaused: {RouteStatusActive, RouteStatusFinished, RouteStatusAbandoned},
+}
+
+// CanTransitTo
+func (r Route) CanTransitTo(status string) bool {
+	for _, next := range routeTransitions[r.Status] {
+		if next == status {
+			return true
+		}
+	}
+	return false
+}
+
+// AllDogIds routes from before route_dog only have DogId
+func (r Route) AllDogIds() []uint {
+	if len(r.DogIds) > 0 {
+		return r.DogIds
+	}
+	return []uint{r.DogId}
+}
+
+// IsClosed finished or abandoned
+func (r Route) IsClosed() bool {
+	return r.Status == RouteStatusFinished || r.Status == RouteStatusAbandoned
+}
+
 // TableName
 func (Route) TableName() string {
 	return "route"
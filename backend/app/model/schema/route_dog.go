@@ -0,0 +1,40 @@
+package schema
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RouteDogList
+type RouteDogList []RouteDog
+
+// RouteDog dogs taken on a walk, Route.DogId stays the first of them
+type RouteDog struct {
+	ID          uint       `gorm:"primary_key" json:"id"`
+	RouteId     uint       `gorm:"column:route_id;not null;uniqueIndex:idx_route_dog,priority:1" json:"routeId"`
+	DogId       uint       `gorm:"column:dog_id;not null;uniqueIndex:idx_route_dog,priority:2;index:idx_route_dog_dog" json:"dogId"`
+	CreatedTime *time.Time `gorm:"column:created_time;default:current_time" json:"createdTime"`
+}
+
+// TableName
+func (RouteDog) TableName() string {
+	return "route_dog"
+}
+
+// GetRouteDogDB
+func GetRouteDogDB(ctx context.Context, defDB *gorm.DB) *gorm.DB {
+	return GetDBWithModel(ctx, defDB, new(RouteDog))
+}
+
+// DogStatsList
+type DogStatsList []DogStats
+
+// DogStats walking of one dog, every dog on a walk is credited with the whole walk
+type DogStats struct {
+	DogId          uint    `gorm:"column:dog_id" json:"dogId"`
+	RouteCount     uint    `gorm:"column:route_count" json:"routeCount"`
+	Distance       float64 `gorm:"column:distance" json:"distance"`
+	ActiveDuration uint    `gorm:"column:active_duration" json:"activeDuration"`
+}
@@ -0,0 +1,44 @@
+package schema
+
+import (
+	"context"
+	"time"
+
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/util"
+	"gorm.io/gorm"
+)
+
+// RoutePauseList
+type RoutePauseList []RoutePause
+
+// RoutePause one pause of a walk, ResumedTime is nil while it lasts
+type RoutePause struct {
+	ID          uint       `gorm:"primary_key" json:"id"`
+	RouteId     uint       `gorm:"column:route_id;not null;index:idx_route_pause_route" json:"routeId"`
+	PausedTime  *time.Time `gorm:"column:paused_time;not null" json:"pausedTime"`
+	ResumedTime *time.Time `gorm:"column:resumed_time;" json:"resumedTime"`
+	CreatedTime *time.Time `gorm:"column:created_time;default:current_time" json:"createdTime"`
+}
+
+// TableName
+func (RoutePause) TableName() string {
+	return "route_pause"
+}
+
+// GetRoutePauseDB
+func GetRoutePauseDB(ctx context.Context, defDB *gorm.DB) *gorm.DB {
+	return GetDBWithModel(ctx, defDB, new(RoutePause))
+}
+
+// Pauses for the walk stats
+func (list RoutePauseList) Pauses() util.PauseList {
+	pauses := make(util.PauseList, 0, len(list))
+	for _, item := range list {
+		pause := util.Pause{Start: *item.PausedTime}
+		if item.ResumedTime != nil {
+			pause.End = *item.ResumedTime
+		}
+		pauses = append(pauses, pause)
+	}
+	return pauses
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/util"
 	"gorm.io/gorm"
 )
 
@@ -13,10 +14,18 @@ type RoutePointList []RoutePoint
 // RoutePoint
 type RoutePoint struct {
 	ID          uint       `gorm:"primary_key" json:"ID"`
-	RouteId     uint       `gorm:"column:route_id;not null" json:"routeId"`
+	RouteId     uint       `gorm:"column:route_id;not null;uniqueIndex:idx_route_seq,priority:1" json:"routeId"`
 	Longitude   float64    `gorm:"column:longitude;not null" json:"longitude"`
 	Latitude    float64    `gorm:"column:latitude;not null" json:"latitude"`
 	CreatedTime *time.Time `gorm:"column:created_time;default:current_time" json:"createdTime"`
+	// Seq client side sequence number, only set by the batch upload
+	Seq *uint64 `gorm:"column:seq;uniqueIndex:idx_route_seq,priority:2" json:"seq,omitempty"`
+
+	// RawLongitude, RawLatitude as reported by the device before smoothing
+	RawLongitude *float64 `gorm:"column:raw_longitude;" json:"-"`
+	RawLatitude  *float64 `gorm:"column:raw_latitude;" json:"-"`
+	Rejected     bool     `gorm:"column:rejected;not null;default:0" json:"rejected"`
+	RejectReason string   `gorm:"column:reject_reason;not null;default:''" json:"rejectReason,omitempty"`
 }
 
 // TableName
@@ -24,6 +33,55 @@ func (RoutePoint) TableName() string {
 	return "route_point"
 }
 
+// GeoPoints
+func (list RoutePointList) GeoPoints() []util.GeoPoint {
+	points := make([]util.GeoPoint, 0, len(list))
+	for _, item := range list {
+		points = append(points, item.GeoPoint())
+	}
+	return points
+}
+
+// GeoPoint
+func (p RoutePoint) GeoPoint() util.GeoPoint {
+	point := util.GeoPoint{Longitude: p.Longitude, Latitude: p.Latitude}
+	if p.CreatedTime != nil {
+		point.Time = *p.CreatedTime
+	}
+	return point
+}
+
+// RawGeoPoint coordinates before smoothing
+func (p RoutePoint) RawGeoPoint() util.GeoPoint {
+	point := p.GeoPoint()
+	if p.RawLongitude != nil && p.RawLatitude != nil {
+		point.Longitude, point.Latitude = *p.RawLongitude, *p.RawLatitude
+	}
+	return point
+}
+
+// RawGeoPoints
+func (list RoutePointList) RawGeoPoints() []util.GeoPoint {
+	points := make([]util.GeoPoint, 0, len(list))
+	for _, item := range list {
+		points = append(points, item.RawGeoPoint())
+	}
+	return points
+}
+
+// ApplyFilter run the raw point through the filter, storing either the smoothed or the rejected point
+func (p *RoutePoint) ApplyFilter(filter *util.GeoFilter) {
+	raw := p.GeoPoint()
+	p.RawLongitude, p.RawLatitude = &raw.Longitude, &raw.Latitude
+	smoothed, reason := filter.Apply(raw)
+	if reason != "" {
+		p.Rejected = true
+		p.RejectReason = reason
+		return
+	}
+	p.Longitude, p.Latitude = smoothed.Longitude, smoothed.Latitude
+}
+
 // GetRoutePointDB
 func GetRoutePointDB(ctx context.Context, defDB *gorm.DB) *gorm.DB {
 	return GetDBWithModel(ctx, defDB, new(RoutePoint))
@@ -0,0 +1,37 @@
+package schema
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RouteShareList
+type RouteShareList []RouteShare
+
+// RouteShare read-only link to one walk for people without an account
+type RouteShare struct {
+	ID          uint       `gorm:"primary_key" json:"id"`
+	RouteId     uint       `gorm:"column:route_id;not null;index:idx_route_share_route" json:"routeId"`
+	UserId      uint       `gorm:"column:user_id;not null" json:"userId"`
+	Token       string     `gorm:"column:token;type:varchar(64);not null;uniqueIndex:idx_route_share_token" json:"token"`
+	ExpiresTime *time.Time `gorm:"column:expires_time;not null" json:"expiresTime"`
+	RevokedTime *time.Time `gorm:"column:revoked_time;" json:"revokedTime"`
+	CreatedTime *time.Time `gorm:"column:created_time;default:current_time" json:"createdTime"`
+}
+
+// TableName
+func (RouteShare) TableName() string {
+	return "route_share"
+}
+
+// GetRouteShareDB
+func GetRouteShareDB(ctx context.Context, defDB *gorm.DB) *gorm.DB {
+	return GetDBWithModel(ctx, defDB, new(RouteShare))
+}
+
+// IsValid not revoked and not expired
+func (s RouteShare) IsValid(now time.Time) bool {
+	return s.RevokedTime == nil && s.ExpiresTime != nil && s.ExpiresTime.After(now)
+}
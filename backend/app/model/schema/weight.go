@@ -0,0 +1,206 @@
+package schema
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/util"
+	"gorm.io/gorm"
+)
+
+// weight alert directions
+const (
+	WeightAlertGain = "gain"
+	WeightAlertLoss = "loss"
+)
+
+// WeightMovingAverageWindow trailing window of the moving average
+const WeightMovingAverageWindow = 7 * 24 * time.Hour
+
+// the monthly rate is only projected from at least this many logs spanning this long
+const (
+	WeightTrendMinLogs = 3
+	WeightTrendMinSpan = 14 * 24 * time.Hour
+)
+
+// WeightLogList
+type WeightLogList []WeightLog
+
+// WeightLog one measurement in kg
+type WeightLog struct {
+	ID           uint       `gorm:"primary_key" json:"id"`
+	DogId        uint       `gorm:"column:dog_id;not null;index:idx_weight_log_dog,priority:1" json:"dogId"`
+	UserId       uint       `gorm:"column:user_id;not null" json:"userId"`
+	Weight       float64    `gorm:"column:weight;not null" json:"weight"`
+	MeasuredTime *time.Time `gorm:"column:measured_time;not null;index:idx_weight_log_dog,priority:2" json:"measuredTime"`
+	Notes        string     `gorm:"column:notes;not null" json:"notes"`
+	CreatedTime  *time.Time `gorm:"column:created_time;default:current_time" json:"createdTime"`
+}
+
+// TableName
+func (WeightLog) TableName() string {
+	return "dog_weight_log"
+}
+
+// GetWeightLogDB
+func GetWeightLogDB(ctx context.Context, defDB *gorm.DB) *gorm.DB {
+	return GetDBWithModel(ctx, defDB, new(WeightLog))
+}
+
+// WeightPoint a measurement with the trailing moving average
+type WeightPoint struct {
+	MeasuredTime  *time.Time `json:"measuredTime"`
+	Weight        float64    `json:"weight"`
+	MovingAverage float64    `json:"movingAverage"`
+}
+
+// WeightAlert
+type WeightAlert struct {
+	Direction string  `json:"direction"`
+	Percent   float64 `json:"percent"`
+	Message   string  `json:"message"`
+}
+
+// WeeklyWeight walking and weight of one week, weeks start on monday
+type WeeklyWeight struct {
+	WeekStart    string   `json:"weekStart"`
+	DistanceKm   float64  `json:"distanceKm"`
+	AvgWeight    *float64 `json:"avgWeight"`
+	WeightChange *float64 `json:"weightChange"`
+}
+
+// WeightTrend RatePerWeek in kg from a linear fit, MonthlyChangePercent the fitted change over 30 days, both 0 without enough data
+// Correlation between the weekly distance and the weekly weight change, nil without enough weeks
+type WeightTrend struct {
+	Points               []WeightPoint  `json:"points"`
+	Latest               *float64       `json:"latest"`
+	RatePerWeek          float64        `json:"ratePerWeek"`
+	MonthlyChangePercent float64        `json:"monthlyChangePercent"`
+	ThresholdPercent     float64        `json:"thresholdPercent"`
+	Alert                *WeightAlert   `json:"alert"`
+	Weeks                []WeeklyWeight `json:"weeks"`
+	Correlation          *float64       `json:"correlation"`
+}
+
+// AnalyzeWeight logs and routes of the dog in [from, to], logs sorted by measured time
+func AnalyzeWeight(dog Dog, logs WeightLogList, routes RouteList, from time.Time, to time.Time, loc *time.Location) WeightTrend {
+	group := BreedGroupOf(dog.Breed, dog.Weight)
+	trend := WeightTrend{
+		Points:           []WeightPoint{},
+		ThresholdPercent: group.WeightAlertPercent,
+	}
+
+	var days, weights []float64
+	for i, log := range logs {
+		sum, count := 0.0, 0
+		for j := i; j >= 0 && log.MeasuredTime.Sub(*logs[j].MeasuredTime) < WeightMovingAverageWindow; j-- {
+			sum += logs[j].Weight
+			count++
+		}
+		trend.Points = append(trend.Points, WeightPoint{
+			MeasuredTime:  log.MeasuredTime,
+			Weight:        log.Weight,
+			MovingAverage: roundTo(sum/float64(count), 2),
+		})
+		days = append(days, log.MeasuredTime.Sub(from).Hours()/24)
+		weights = append(weights, log.Weight)
+	}
+	if len(logs) > 0 {
+		latest := logs[len(logs)-1].Weight
+		trend.Latest = &latest
+	}
+
+	enoughData := len(logs) >= WeightTrendMinLogs &&
+		logs[len(logs)-1].MeasuredTime.Sub(*logs[0].MeasuredTime) >= WeightTrendMinSpan
+	if slope, _, ok := util.LinearRegression(days, weights); ok && enoughData && trend.Latest != nil && *trend.Latest > 0 {
+		trend.RatePerWeek = roundTo(slope*7, 3)
+		trend.MonthlyChangePercent = roundTo(slope*30 / *trend.Latest * 100, 2)
+		if math.Abs(trend.MonthlyChangePercent) >= trend.ThresholdPercent {
+			direction, verb := WeightAlertGain, "gaining"
+			if trend.MonthlyChangePercent < 0 {
+				direction, verb = WeightAlertLoss, "losing"
+			}
+			trend.Alert = &WeightAlert{
+				Direction: direction,
+				Percent:   trend.MonthlyChangePercent,
+				Message: fmt.Sprintf("%s is %s about %.1f%% a month, more than the %.0f%% expected for %s breeds",
+					dog.Name, verb, math.Abs(trend.MonthlyChangePercent), trend.ThresholdPercent, group.Name),
+			}
+		}
+	}
+
+	trend.Weeks = weeklyWeights(logs, routes, from, to, loc)
+	var distances, changes []float64
+	for _, week := range trend.Weeks {
+		if week.WeightChange != nil {
+			distances = append(distances, week.DistanceKm)
+			changes = append(changes, *week.WeightChange)
+		}
+	}
+	if correlation, ok := util.PearsonCorrelation(distances, changes); ok {
+		correlation = roundTo(correlation, 3)
+		trend.Correlation = &correlation
+	}
+	return trend
+}
+
+// weeklyWeights change is against the previous week with a measurement
+func weeklyWeights(logs WeightLogList, routes RouteList, from time.Time, to time.Time, loc *time.Location) []WeeklyWeight {
+	weekOf := func(t time.Time) time.Time {
+		local := t.In(loc)
+		offset := (int(local.Weekday()) + 6) % 7
+		return time.Date(local.Year(), local.Month(), local.Day()-offset, 0, 0, 0, 0, loc)
+	}
+
+	type weekSum struct {
+		distance    float64
+		weightSum   float64
+		weightCount int
+	}
+	sums := map[string]*weekSum{}
+	get := func(t time.Time) *weekSum {
+		key := weekOf(t).Format(DogGoalDateLayout)
+		if sums[key] == nil {
+			sums[key] = &weekSum{}
+		}
+		return sums[key]
+	}
+	for _, route := range routes {
+		if route.CreatedTime != nil && route.Status != RouteStatusAbandoned {
+			get(*route.CreatedTime).distance += route.Distance / 1000
+		}
+	}
+	for _, log := range logs {
+		sum := get(*log.MeasuredTime)
+		sum.weightSum += log.Weight
+		sum.weightCount++
+	}
+
+	weeks := []WeeklyWeight{}
+	var prevWeight *float64
+	for week := weekOf(from); !week.After(to); week = week.AddDate(0, 0, 7) {
+		key := week.Format(DogGoalDateLayout)
+		item := WeeklyWeight{WeekStart: key}
+		if sum, ok := sums[key]; ok {
+			item.DistanceKm = roundTo(sum.distance, 2)
+			if sum.weightCount > 0 {
+				avg := roundTo(sum.weightSum/float64(sum.weightCount), 2)
+				item.AvgWeight = &avg
+				if prevWeight != nil {
+					change := roundTo(avg-*prevWeight, 2)
+					item.WeightChange = &change
+				}
+				prevWeight = &avg
+			}
+		}
+		weeks = append(weeks, item)
+	}
+	return weeks
+}
+
+func roundTo(v float64, places int) float64 {
+	factor := math.Pow(10, float64(places))
+	return math.Round(v*factor) / factor
+}
@@ -0,0 +1,73 @@
+package model
+
+import (
+	"context"
+	"time"
+
+	"github.com/yiff028/comp90018-mobile-project/backend/app/model/schema"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/errors"
+	"gorm.io/gorm"
+)
+
+type Weight struct {
+	DB *gorm.DB
+}
+
+// Create
+func (w *Weight) Create(ctx context.Context, item *schema.WeightLog) error {
+	db := schema.GetWeightLogDB(ctx, w.DB)
+	if err := db.Create(item).Error; err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// GetById
+func (w *Weight) GetById(ctx context.Context, id uint) (*schema.WeightLog, error) {
+	db := schema.GetWeightLogDB(ctx, w.DB).Where("id = ?", id)
+
+	item := schema.WeightLog{}
+	ok, err := schema.FindOne(ctx, db, &item)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	} else if !ok {
+		return nil, nil
+	}
+	return &item, nil
+}
+
+// Delete
+func (w *Weight) Delete(ctx context.Context, id uint, dogId uint) (int64, error) {
+	db := schema.GetWeightLogDB(ctx, w.DB)
+	result := db.Where("id = ?", id).Where("dog_id = ?", dogId).Delete(&schema.WeightLog{})
+	if err := result.Error; err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return result.RowsAffected, nil
+}
+
+// ListByDogId measured since the given time, oldest first
+func (w *Weight) ListByDogId(ctx context.Context, dogId uint, since time.Time) (schema.WeightLogList, error) {
+	db := schema.GetWeightLogDB(ctx, w.DB).Where("dog_id = ?", dogId).Where("measured_time >= ?", since).
+		Order("measured_time ASC").Order("id ASC")
+
+	logList := schema.WeightLogList{}
+	if err := db.Find(&logList).Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return logList, nil
+}
+
+// GetLatest most recent measurement of the dog
+func (w *Weight) GetLatest(ctx context.Context, dogId uint) (*schema.WeightLog, error) {
+	db := schema.GetWeightLogDB(ctx, w.DB).Where("dog_id = ?", dogId).Order("measured_time DESC").Order("id DESC")
+
+	item := schema.WeightLog{}
+	ok, err := schema.FindOne(ctx, db, &item)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	} else if !ok {
+		return nil, nil
+	}
+	return &item, nil
+}
@@ -0,0 +1,28 @@
+package app
+
+import (
+	"context"
+
+	configs "github.com/yiff028/comp90018-mobile-project/backend/app/config"
+	"github.com/yiff028/comp90018-mobile-project/backend/app/mixin"
+	"github.com/yiff028/comp90018-mobile-project/backend/app/notification"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/logger"
+)
+
+const defaultNotificationWorkers = 2
+
+// InitNotificationWorker deliver the notifications enqueued by the controllers
+func InitNotificationWorker(ctx context.Context, dep mixin.StoreDepency) func() {
+	cfg := configs.C.Notification
+	workers := cfg.Workers
+	if workers <= 0 {
+		workers = defaultNotificationWorkers
+	}
+
+	service, err := notification.NewService(dep, cfg)
+	if err != nil {
+		logger.Errorf(ctx, "init notification service fail %+v", err)
+		return func() {}
+	}
+	return service.Run(ctx, workers)
+}
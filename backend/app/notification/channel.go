@@ -0,0 +1,24 @@
+package notification
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrInvalidRecipient the address or device token will never work again, e.g. an uninstalled app
+var ErrInvalidRecipient = errors.New("invalid recipient")
+
+// Message rendered notification for one recipient of a channel
+type Message struct {
+	To       string
+	Category string
+	Title    string
+	Body     string
+	Data     map[string]string
+}
+
+// Channel delivers messages outside the app, email or push
+type Channel interface {
+	Name() string
+	Send(ctx context.Context, msg Message) error
+}
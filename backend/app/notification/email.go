@@ -0,0 +1,87 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"net/mail"
+	"net/smtp"
+	"text/template"
+	"time"
+
+	configs "github.com/yiff028/comp90018-mobile-project/backend/app/config"
+)
+
+const defaultEmailTemplate = "{{.Title}}\n\n{{.Body}}\n"
+
+// SendMailFunc same signature as smtp.SendMail
+type SendMailFunc func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+
+// EmailChannel sends plain text emails through SMTP
+type EmailChannel struct {
+	Addr     string
+	Auth     smtp.Auth
+	From     string
+	Template *template.Template
+	SendMail SendMailFunc
+}
+
+// NewEmailChannel nil if no SMTP host is configured
+func NewEmailChannel(cfg configs.SMTP, tmpl configs.EmailTemplate) (*EmailChannel, error) {
+	if cfg.Host == "" {
+		return nil, nil
+	}
+	text := tmpl.Notification
+	if text == "" {
+		text = defaultEmailTemplate
+	}
+	t, err := template.New("notification").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	var auth smtp.Auth
+	if cfg.Username != "" {
+		auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	}
+	return &EmailChannel{
+		Addr:     fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
+		Auth:     auth,
+		From:     cfg.From,
+		Template: t,
+		SendMail: smtp.SendMail,
+	}, nil
+}
+
+// Name
+func (e *EmailChannel) Name() string {
+	return "email"
+}
+
+// Send
+func (e *EmailChannel) Send(ctx context.Context, msg Message) error {
+	if msg.To == "" {
+		return ErrInvalidRecipient
+	}
+	var body bytes.Buffer
+	if err := e.Template.Execute(&body, msg); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", e.From)
+	fmt.Fprintf(&buf, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Title))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("\r\n")
+	buf.Write(body.Bytes())
+
+	// the envelope takes the bare address of "Name <address>"
+	from := e.From
+	if addr, err := mail.ParseAddress(e.From); err == nil {
+		from = addr.Address
+	}
+	return e.SendMail(e.Addr, e.Auth, from, []string{msg.To}, buf.Bytes())
+}
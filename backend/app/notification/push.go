@@ -0,0 +1,118 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	configs "github.com/yiff028/comp90018-mobile-project/backend/app/config"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/logger"
+)
+
+// PushChannel sends to one device token per message
+type PushChannel struct {
+	Sender PushSender
+}
+
+// PushSender talks to the push provider
+type PushSender interface {
+	Push(ctx context.Context, token string, title string, body string, data map[string]string) error
+}
+
+// NewPushChannel logs the messages when no endpoint is configured
+func NewPushChannel(cfg configs.Push) *PushChannel {
+	if cfg.Endpoint == "" {
+		return &PushChannel{Sender: &FakePushSender{}}
+	}
+	return &PushChannel{Sender: &HTTPPushSender{
+		Endpoint:  cfg.Endpoint,
+		ServerKey: cfg.ServerKey,
+		Client:    &http.Client{Timeout: 10 * time.Second},
+	}}
+}
+
+// Name
+func (p *PushChannel) Name() string {
+	return "push"
+}
+
+// Send
+func (p *PushChannel) Send(ctx context.Context, msg Message) error {
+	if msg.To == "" {
+		return ErrInvalidRecipient
+	}
+	return p.Sender.Push(ctx, msg.To, msg.Title, msg.Body, msg.Data)
+}
+
+// HTTPPushSender FCM style json endpoint
+type HTTPPushSender struct {
+	Endpoint  string
+	ServerKey string
+	Client    *http.Client
+}
+
+type pushRequest struct {
+	To           string            `json:"to"`
+	Notification pushNotification  `json:"notification"`
+	Data         map[string]string `json:"data,omitempty"`
+}
+
+type pushNotification struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+// Push 404 and 410 mean the token is gone
+func (h *HTTPPushSender) Push(ctx context.Context, token string, title string, body string, data map[string]string) error {
+	payload, err := json.Marshal(pushRequest{
+		To:           token,
+		Notification: pushNotification{Title: title, Body: body},
+		Data:         data,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.Endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if h.ServerKey != "" {
+		req.Header.Set("Authorization", "key="+h.ServerKey)
+	}
+
+	response, err := h.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	io.Copy(io.Discard, response.Body)
+
+	switch {
+	case response.StatusCode == http.StatusNotFound || response.StatusCode == http.StatusGone:
+		return ErrInvalidRecipient
+	case response.StatusCode >= 300:
+		return fmt.Errorf("push response status %d", response.StatusCode)
+	}
+	return nil
+}
+
+// FakePushSender logs instead of sending, for development and tests
+type FakePushSender struct {
+	mu   sync.Mutex
+	Sent []string
+}
+
+// Push
+func (f *FakePushSender) Push(ctx context.Context, token string, title string, body string, data map[string]string) error {
+	f.mu.Lock()
+	f.Sent = append(f.Sent, token)
+	f.mu.Unlock()
+	logger.Infof(ctx, "fake push to %s: %s %s", token, title, body)
+	return nil
+}
@@ -0,0 +1,196 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	configs "github.com/yiff028/comp90018-mobile-project/backend/app/config"
+	"github.com/yiff028/comp90018-mobile-project/backend/app/mixin"
+	"github.com/yiff028/comp90018-mobile-project/backend/app/model/redismodel"
+	"github.com/yiff028/comp90018-mobile-project/backend/app/model/schema"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/logger"
+)
+
+const (
+	dequeueTimeout = 5 * time.Second
+	// retryPollInterval pause after putting back a retry which is not due yet
+	retryPollInterval = time.Second
+)
+
+// delivery channels, as in NotificationJob.Channels
+const (
+	channelInApp = "inApp"
+	channelEmail = "email"
+	channelPush  = "push"
+)
+
+// Service delivers queued notifications to the inbox and the channels each user opted in
+type Service struct {
+	Dep mixin.StoreDepency
+	// Email nil when SMTP is not configured
+	Email Channel
+	Push  Channel
+}
+
+// NewService
+func NewService(dep mixin.StoreDepency, cfg configs.Notification) (*Service, error) {
+	s := &Service{
+		Dep:  dep,
+		Push: NewPushChannel(cfg.Push),
+	}
+	email, err := NewEmailChannel(cfg.SMTP, cfg.EmailTemplate)
+	if err != nil {
+		return nil, err
+	}
+	if email != nil {
+		s.Email = email
+	}
+	return s, nil
+}
+
+// Deliver one job, failures of a user or channel do not stop the others
+// the channels which failed are retried for that user later
+func (s *Service) Deliver(ctx context.Context, job redismodel.NotificationJob) {
+	for _, userId := range job.UserIds {
+		failed := s.deliverTo(ctx, userId, job)
+		if len(failed) == 0 {
+			continue
+		}
+		retry := job
+		retry.UserIds = []uint{userId}
+		retry.Channels = failed
+		if err := s.Dep.NotificationQueue.Retry(retry); err != nil {
+			logger.Errorf(ctx, "retry notification to user %d fail %+v", userId, err)
+		}
+	}
+}
+
+// deliverTo returns the channels which failed
+func (s *Service) deliverTo(ctx context.Context, userId uint, job redismodel.NotificationJob) []string {
+	wanted := map[string]bool{channelInApp: true, channelEmail: true, channelPush: true}
+	if len(job.Channels) > 0 {
+		wanted = map[string]bool{}
+		for _, channel := range job.Channels {
+			wanted[channel] = true
+		}
+	}
+
+	pref, err := s.Dep.NotificationModel.GetPreference(ctx, userId, job.Category)
+	if err != nil {
+		logger.Errorf(ctx, "get notification preference of user %d fail %+v", userId, err)
+		failed := []string{}
+		for _, channel := range []string{channelInApp, channelEmail, channelPush} {
+			if wanted[channel] {
+				failed = append(failed, channel)
+			}
+		}
+		return failed
+	}
+
+	failed := []string{}
+	if pref.InApp && wanted[channelInApp] {
+		item := schema.Notification{
+			UserId:   userId,
+			Category: job.Category,
+			Title:    job.Title,
+			Body:     job.Body,
+			RefId:    job.RefId,
+		}
+		if err := s.Dep.NotificationModel.Create(ctx, &item); err != nil {
+			logger.Errorf(ctx, "create notification of user %d fail %+v", userId, err)
+			failed = append(failed, channelInApp)
+		}
+	}
+
+	msg := Message{
+		Category: job.Category,
+		Title:    job.Title,
+		Body:     job.Body,
+		Data:     job.Data,
+	}
+
+	if pref.Email && wanted[channelEmail] && s.Email != nil {
+		user, err := s.Dep.UserModel.GetById(ctx, int(userId))
+		if err != nil {
+			logger.Errorf(ctx, "get user %d fail %+v", userId, err)
+			failed = append(failed, channelEmail)
+		} else if user != nil {
+			msg.To = user.Email
+			err := s.Email.Send(ctx, msg)
+			if err != nil && !errors.Is(err, ErrInvalidRecipient) {
+				logger.Errorf(ctx, "send email to user %d fail %+v", userId, err)
+				failed = append(failed, channelEmail)
+			}
+		}
+	}
+
+	if pref.Push && wanted[channelPush] && s.Push != nil {
+		deviceList, err := s.Dep.NotificationModel.ListDevice(ctx, userId)
+		if err != nil {
+			logger.Errorf(ctx, "list push device of user %d fail %+v", userId, err)
+			return append(failed, channelPush)
+		}
+		pushFailed := false
+		for _, device := range deviceList {
+			msg.To = device.Token
+			err := s.Push.Send(ctx, msg)
+			if errors.Is(err, ErrInvalidRecipient) {
+				if err := s.Dep.NotificationModel.DeleteDevice(ctx, 0, device.Token); err != nil {
+					logger.Errorf(ctx, "delete push device fail %+v", err)
+				}
+				continue
+			}
+			if err != nil {
+				logger.Errorf(ctx, "push to user %d fail %+v", userId, err)
+				pushFailed = true
+			}
+		}
+		if pushFailed {
+			failed = append(failed, channelPush)
+		}
+	}
+	return failed
+}
+
+// Run consume the queue with the given number of workers until the returned func is called
+func (s *Service) Run(ctx context.Context, workers int) func() {
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				job, err := s.Dep.NotificationQueue.Dequeue(dequeueTimeout)
+				if err != nil {
+					logger.Errorf(ctx, "dequeue notification fail %+v", err)
+					time.Sleep(dequeueTimeout)
+					continue
+				}
+				if job == nil {
+					continue
+				}
+				if job.RetryTime.After(time.Now()) {
+					// a retry which is not due yet goes back to the end of the queue
+					if err := s.Dep.NotificationQueue.Enqueue(*job); err != nil {
+						logger.Errorf(ctx, "requeue notification fail %+v", err)
+					}
+					time.Sleep(retryPollInterval)
+					continue
+				}
+				s.Deliver(ctx, *job)
+			}
+		}()
+	}
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
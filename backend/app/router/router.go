@@ -16,11 +16,19 @@ type IRouter interface {
 
 // Router
 type Router struct {
-	AuthController   *controller.AuthController
-	CommonController *controller.CommonController
-	DogController    *controller.DogController
-	RouteController  *controller.RouteController
-	BinController    *controller.BinController
+	AuthController         *controller.AuthController
+	CommonController       *controller.CommonController
+	DogController          *controller.DogController
+	RouteController        *controller.RouteController
+	BinController          *controller.BinController
+	PrivacyController      *controller.PrivacyController
+	ShareController        *controller.ShareController
+	GeofenceController     *controller.GeofenceController
+	LostController         *controller.LostController
+	NotificationController *controller.NotificationController
+	HealthController       *controller.HealthController
+	HouseholdController    *controller.HouseholdController
+	GrantController        *controller.GrantController
 }
 
 // Register
@@ -25,7 +25,37 @@ func (r *Router) RegisterAPIRouter(app *gin.Engine, dep mixin.StoreDepency) {
 			dog.POST("/createDog", r.DogController.CreateDog)
 			dog.GET("/listDog", r.DogController.ListDog)
 			dog.GET("/listCurrentDog", r.DogController.ListCurrentDog)
+			dog.GET("/listNearbyDog", r.DogController.ListNearbyDog)
+			dog.GET("/listPlaymates", r.DogController.ListPlaymate)
+			dog.GET("/streamLocation", r.DogController.StreamDogLocation)
 			dog.POST("/deleteDog", r.DogController.DeleteDog)
+			dog.POST("/updateDog", r.DogController.UpdateDog)
+			dog.GET("/listChanges", r.DogController.ListDogChange)
+			dog.POST("/addPhoto", r.DogController.AddDogPhoto)
+			dog.POST("/deletePhoto", r.DogController.DeleteDogPhoto)
+			dog.POST("/setPrimaryPhoto", r.DogController.SetPrimaryDogPhoto)
+			dog.POST("/reorderPhotos", r.DogController.ReorderDogPhoto)
+			dog.GET("/listPhotos", r.DogController.ListDogPhoto)
+			dog.POST("/setGoal", r.DogController.SetDogGoal)
+			dog.GET("/getGoalProgress", r.DogController.GetDogGoalProgress)
+			dog.GET("/getRecommendation", r.DogController.GetDogRecommendation)
+			dog.POST("/addWeight", r.DogController.AddWeight)
+			dog.POST("/deleteWeight", r.DogController.DeleteWeight)
+			dog.GET("/getWeightTrend", r.DogController.GetWeightTrend)
+
+			dog.POST("/createVaccination", r.HealthController.CreateVaccination)
+			dog.POST("/updateVaccination", r.HealthController.UpdateVaccination)
+			dog.POST("/deleteVaccination", r.HealthController.DeleteVaccination)
+			dog.GET("/listVaccination", r.HealthController.ListVaccination)
+			dog.POST("/createMedication", r.HealthController.CreateMedication)
+			dog.POST("/updateMedication", r.HealthController.UpdateMedication)
+			dog.POST("/deleteMedication", r.HealthController.DeleteMedication)
+			dog.GET("/listMedication", r.HealthController.ListMedication)
+			dog.POST("/createVetVisit", r.HealthController.CreateVetVisit)
+			dog.POST("/updateVetVisit", r.HealthController.UpdateVetVisit)
+			dog.POST("/deleteVetVisit", r.HealthController.DeleteVetVisit)
+			dog.GET("/listVetVisit", r.HealthController.ListVetVisit)
+			dog.GET("/listHealthDue", r.HealthController.ListHealthDue)
 		}
 		common := api.Group("/common", middleware.Auth(dep.RedisClient))
 		{
@@ -36,8 +66,49 @@ func (r *Router) RegisterAPIRouter(app *gin.Engine, dep mixin.StoreDepency) {
 		{
 			route.GET("/listAllRoutes", r.RouteController.ListRoute)
 			route.GET("/getRouteDetail", r.RouteController.GetRouteDetail)
+			route.GET("/listDogStats", r.RouteController.ListDogStats)
+			route.GET("/export", r.RouteController.ExportRoute)
+			route.POST("/importRoute", r.RouteController.ImportRoute)
 			route.POST("/createRoute", r.RouteController.CreateRoute)
 			route.POST("/updateRouteLocation", r.RouteController.UpdateRouteLocation)
+			route.POST("/uploadPoints", r.RouteController.UploadPoints)
+			route.POST("/pauseRoute", r.RouteController.PauseRoute)
+			route.POST("/resumeRoute", r.RouteController.ResumeRoute)
+			route.POST("/finishRoute", r.RouteController.FinishRoute)
+			route.POST("/abandonRoute", r.RouteController.AbandonRoute)
+			route.POST("/createShare", r.ShareController.CreateShare)
+			route.POST("/revokeShare", r.ShareController.RevokeShare)
+			route.GET("/listShares", r.ShareController.ListShare)
+		}
+		share := api.Group("/share")
+		{
+			share.GET("/route", r.ShareController.GetSharedRoute)
+		}
+		geofence := api.Group("/geofence", middleware.Auth(dep.RedisClient))
+		{
+			geofence.POST("/createGeofence", r.GeofenceController.CreateGeofence)
+			geofence.POST("/updateGeofence", r.GeofenceController.UpdateGeofence)
+			geofence.POST("/deleteGeofence", r.GeofenceController.DeleteGeofence)
+			geofence.GET("/listGeofences", r.GeofenceController.ListGeofence)
+			geofence.GET("/listEvents", r.GeofenceController.ListGeofenceEvent)
+		}
+		lost := api.Group("/lost", middleware.Auth(dep.RedisClient))
+		{
+			lost.POST("/reportLost", r.LostController.ReportLost)
+			lost.POST("/resolveAlert", r.LostController.ResolveAlert)
+			lost.GET("/listNearbyAlerts", r.LostController.ListNearbyAlert)
+			lost.POST("/reportSighting", r.LostController.ReportSighting)
+			lost.GET("/listSightings", r.LostController.ListSighting)
+		}
+		notification := api.Group("/notification", middleware.Auth(dep.RedisClient))
+		{
+			notification.GET("/listNotifications", r.NotificationController.ListNotification)
+			notification.GET("/unreadCount", r.NotificationController.CountUnread)
+			notification.POST("/markRead", r.NotificationController.MarkRead)
+			notification.GET("/listPreferences", r.NotificationController.ListPreference)
+			notification.POST("/updatePreference", r.NotificationController.UpdatePreference)
+			notification.POST("/registerDevice", r.NotificationController.RegisterDevice)
+			notification.POST("/unregisterDevice", r.NotificationController.UnregisterDevice)
 		}
 		bin := api.Group("/bin", middleware.Auth(dep.RedisClient))
 		{
@@ -45,6 +116,36 @@ func (r *Router) RegisterAPIRouter(app *gin.Engine, dep mixin.StoreDepency) {
 			bin.POST("/deleteBin", r.BinController.DeleteBin)
 			bin.GET("/listMyBins", r.BinController.ListMyBin)
 			bin.GET("/listAllBins", r.BinController.ListAllBin)
+			bin.GET("/nearby", r.BinController.ListNearbyBin)
+			bin.GET("/withinBounds", r.BinController.ListBinWithinBounds)
+		}
+		privacy := api.Group("/privacy", middleware.Auth(dep.RedisClient))
+		{
+			privacy.GET("/getPrivacy", r.PrivacyController.GetPrivacy)
+			privacy.POST("/updatePrivacy", r.PrivacyController.UpdatePrivacy)
+			privacy.GET("/listHomeZones", r.PrivacyController.ListHomeZone)
+			privacy.POST("/createHomeZone", r.PrivacyController.CreateHomeZone)
+			privacy.POST("/deleteHomeZone", r.PrivacyController.DeleteHomeZone)
+			privacy.GET("/listFriends", r.PrivacyController.ListFriend)
+			privacy.POST("/addFriend", r.PrivacyController.AddFriend)
+			privacy.POST("/removeFriend", r.PrivacyController.RemoveFriend)
+		}
+		household := api.Group("/household", middleware.Auth(dep.RedisClient))
+		{
+			household.POST("/createHousehold", r.HouseholdController.CreateHousehold)
+			household.GET("/listHouseholds", r.HouseholdController.ListHousehold)
+			household.POST("/createInvite", r.HouseholdController.CreateInvite)
+			household.POST("/join", r.HouseholdController.JoinHousehold)
+			household.POST("/updateMember", r.HouseholdController.UpdateMember)
+			household.POST("/removeMember", r.HouseholdController.RemoveMember)
+			household.POST("/addDog", r.HouseholdController.AddHouseholdDog)
+			household.POST("/removeDog", r.HouseholdController.RemoveHouseholdDog)
+		}
+		grant := api.Group("/grant", middleware.Auth(dep.RedisClient))
+		{
+			grant.POST("/createGrant", r.GrantController.CreateGrant)
+			grant.POST("/revokeGrant", r.GrantController.RevokeGrant)
+			grant.GET("/listGrants", r.GrantController.ListGrant)
 		}
 	}
 
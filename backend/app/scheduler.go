@@ -0,0 +1,352 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yiff028/comp90018-mobile-project/backend/api/types/keys"
+	configs "github.com/yiff028/comp90018-mobile-project/backend/app/config"
+	"github.com/yiff028/comp90018-mobile-project/backend/app/controller"
+	"github.com/yiff028/comp90018-mobile-project/backend/app/mixin"
+	"github.com/yiff028/comp90018-mobile-project/backend/app/model/redismodel"
+	"github.com/yiff028/comp90018-mobile-project/backend/app/model/schema"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/logger"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/scheduler"
+)
+
+const (
+	defaultRouteStaleTimeout  = 60
+	defaultRouteSweepInterval = 60
+	defaultJobHistoryDays     = 30
+	defaultImageGracePeriod   = 24
+
+	// healthReminderLead how early vaccinations and vet follow ups are reminded
+	healthReminderLead = 72 * time.Hour
+	// medicationReminderLead how early a dose is reminded, more than the job interval
+	medicationReminderLead = 30 * time.Minute
+)
+
+// InitScheduler register the periodic jobs, the returned func waits for the running ones
+func InitScheduler(ctx context.Context, dep mixin.StoreDepency) func() {
+	routeCfg := configs.C.Route
+	staleTimeout := routeCfg.StaleTimeout
+	if staleTimeout <= 0 {
+		staleTimeout = defaultRouteStaleTimeout
+	}
+	sweepInterval := routeCfg.SweepInterval
+	if sweepInterval <= 0 {
+		sweepInterval = defaultRouteSweepInterval
+	}
+	schedulerCfg := configs.C.Scheduler
+	historyDays := schedulerCfg.HistoryDays
+	if historyDays <= 0 {
+		historyDays = defaultJobHistoryDays
+	}
+	imageGracePeriod := schedulerCfg.ImageGracePeriod
+	if imageGracePeriod <= 0 {
+		imageGracePeriod = defaultImageGracePeriod
+	}
+
+	s := scheduler.New(jobLocker{dep: dep}, jobRecorder{dep: dep})
+	s.Add(scheduler.Job{
+		Name:     "finish-stale-route",
+		Schedule: scheduler.MustParseCron("@every " + (time.Duration(sweepInterval) * time.Second).String()),
+		Timeout:  time.Minute,
+		Run: func(ctx context.Context) error {
+			before := time.Now().Add(-time.Duration(staleTimeout) * time.Minute)
+			count, err := dep.RouteModel.FinishStale(ctx, before)
+			if err != nil {
+				return err
+			}
+			if count > 0 {
+				logger.Infof(ctx, "finished %d stale routes", count)
+			}
+			return nil
+		},
+	})
+	s.Add(scheduler.Job{
+		Name:     "expire-route-share",
+		Schedule: scheduler.MustParseCron("*/5 * * * *"),
+		Timeout:  time.Minute,
+		Retries:  2,
+		Run: func(ctx context.Context) error {
+			_, err := dep.RouteShareModel.ExpireClosed(ctx)
+			return err
+		},
+	})
+	s.Add(scheduler.Job{
+		Name:     "expire-dog-grant",
+		Schedule: scheduler.MustParseCron("*/5 * * * *"),
+		Timeout:  2 * time.Minute,
+		Retries:  2,
+		Run: func(ctx context.Context) error {
+			count, err := controller.ExpireDogGrants(ctx, dep, time.Now())
+			if count > 0 {
+				logger.Infof(ctx, "expired %d dog grants", count)
+			}
+			return err
+		},
+	})
+	s.Add(scheduler.Job{
+		Name:     "remind-walk-goal",
+		Schedule: scheduler.MustParseCron("*/5 * * * *"),
+		Timeout:  2 * time.Minute,
+		Run: func(ctx context.Context) error {
+			return remindWalkGoal(ctx, dep, time.Now())
+		},
+	})
+	s.Add(scheduler.Job{
+		Name:     "remind-health-due",
+		Schedule: scheduler.MustParseCron("*/15 * * * *"),
+		Timeout:  5 * time.Minute,
+		Run: func(ctx context.Context) error {
+			return remindHealthDue(ctx, dep, time.Now())
+		},
+	})
+	s.Add(scheduler.Job{
+		Name:     "clean-orphaned-image",
+		Schedule: scheduler.MustParseCron("30 3 * * *"),
+		Timeout:  30 * time.Minute,
+		Retries:  2,
+		Run: func(ctx context.Context) error {
+			return cleanOrphanedImage(ctx, dep, time.Duration(imageGracePeriod)*time.Hour)
+		},
+	})
+	s.Add(scheduler.Job{
+		Name:     "prune-job-run",
+		Schedule: scheduler.MustParseCron("0 4 * * *"),
+		Timeout:  10 * time.Minute,
+		Retries:  2,
+		Run: func(ctx context.Context) error {
+			_, err := dep.JobRunModel.DeleteBefore(ctx, time.Now().AddDate(0, 0, -historyDays))
+			return err
+		},
+	})
+
+	s.Start(ctx)
+	return s.Stop
+}
+
+// remindWalkGoal notify owners whose dog is behind its goal once the reminder time has passed, once per local day
+func remindWalkGoal(ctx context.Context, dep mixin.StoreDepency, now time.Time) error {
+	dogList, err := dep.DogModel.ListWithGoalReminder(ctx)
+	if err != nil {
+		return err
+	}
+	for i := range dogList {
+		dogItem := &dogList[i]
+		localNow := now.In(controller.DogGoalLocation(dogItem))
+		if !dogItem.ReminderDue(localNow) {
+			continue
+		}
+		key := keys.GetGoalReminderKey(dogItem.ID, localNow.Format(schema.DogGoalDateLayout))
+		if dep.RedisClient.IsExist(key) {
+			continue
+		}
+
+		activities, err := controller.ListDailyActivity(ctx, dep, dogItem, 1, now)
+		if err != nil {
+			logger.Errorf(ctx, "list daily activity of dog %d fail %+v", dogItem.ID, err)
+			continue
+		}
+		today := activities[len(activities)-1]
+		// met goals are marked too so they are not computed again today
+		ok, err := dep.RedisClient.SetNx(key, today.Progress, 48*time.Hour)
+		if err != nil {
+			logger.Errorf(ctx, "set goal reminder key of dog %d fail %+v", dogItem.ID, err)
+			continue
+		}
+		if !ok || today.Met {
+			continue
+		}
+
+		err = dep.NotificationQueue.Enqueue(redismodel.NotificationJob{
+			UserIds:  []uint{dogItem.UserId},
+			Category: schema.NotificationCategoryWalkGoal,
+			Title:    fmt.Sprintf("%s still needs a walk today", dogItem.Name),
+			Body:     walkGoalBody(dogItem, today.Progress),
+			RefId:    dogItem.ID,
+			Data:     map[string]string{"dogId": strconv.Itoa(int(dogItem.ID))},
+		})
+		if err != nil {
+			logger.Errorf(ctx, "enqueue goal reminder of dog %d fail %+v", dogItem.ID, err)
+		}
+	}
+	return nil
+}
+
+// walkGoalBody
+func walkGoalBody(dog *schema.Dog, progress float64) string {
+	if dog.GoalType == schema.DogGoalKm {
+		return fmt.Sprintf("%.1f of %.1f km walked so far, %.1f km to go.", progress, dog.GoalValue, dog.GoalValue-progress)
+	}
+	return fmt.Sprintf("%.0f of %.0f minutes walked so far, %.0f minutes to go.", progress, dog.GoalValue, dog.GoalValue-progress)
+}
+
+// remindHealthDue notify owners of upcoming vaccinations, follow ups and medication doses, once per due time
+func remindHealthDue(ctx context.Context, dep mixin.StoreDepency, now time.Time) error {
+	dueList, err := controller.ListHealthDue(ctx, dep, nil, now, now.Add(healthReminderLead))
+	if err != nil {
+		return err
+	}
+
+	dogMap := map[uint]*schema.Dog{}
+	for _, due := range dueList {
+		if due.Kind == schema.HealthKindMedication && due.DueTime.After(now.Add(medicationReminderLead)) {
+			continue
+		}
+		key := keys.GetHealthReminderKey(due.Kind, due.RecordId, due.DueTime.Unix())
+		ok, err := dep.RedisClient.SetNx(key, now.Unix(), healthReminderLead+7*24*time.Hour)
+		if err != nil {
+			logger.Errorf(ctx, "set health reminder key fail %+v", err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		dogItem, found := dogMap[due.DogId]
+		if !found {
+			dogItem, err = dep.DogModel.GetById(ctx, due.DogId)
+			if err != nil {
+				logger.Errorf(ctx, "get dog %d fail %+v", due.DogId, err)
+				continue
+			}
+			if dogItem == nil {
+				continue
+			}
+			dogMap[due.DogId] = dogItem
+		}
+		// whoever owns the dog now, the record's author may have left the household
+		ownerIds, err := controller.DogOwnerIds(ctx, dep, dogItem)
+		if err != nil {
+			logger.Errorf(ctx, "list owners of dog %d fail %+v", dogItem.ID, err)
+			continue
+		}
+
+		err = dep.NotificationQueue.Enqueue(redismodel.NotificationJob{
+			UserIds:  ownerIds,
+			Category: schema.NotificationCategoryHealth,
+			Title:    healthDueTitle(due, dogItem.Name),
+			Body:     fmt.Sprintf("%s is due %s.", due.Name, due.DueTime.In(controller.DogGoalLocation(dogItem)).Format("Mon 2 Jan 15:04")),
+			RefId:    due.RecordId,
+			Data: map[string]string{
+				"kind":  due.Kind,
+				"dogId": strconv.Itoa(int(due.DogId)),
+			},
+		})
+		if err != nil {
+			logger.Errorf(ctx, "enqueue health reminder fail %+v", err)
+		}
+	}
+	return nil
+}
+
+// healthDueTitle
+func healthDueTitle(due schema.HealthDue, dogName string) string {
+	switch due.Kind {
+	case schema.HealthKindVaccination:
+		if due.Overdue {
+			return fmt.Sprintf("%s's vaccination is overdue", dogName)
+		}
+		return fmt.Sprintf("%s's vaccination is coming up", dogName)
+	case schema.HealthKindMedication:
+		return fmt.Sprintf("Time for %s's medication", dogName)
+	}
+	return fmt.Sprintf("%s has a vet follow up", dogName)
+}
+
+// cleanOrphanedImage remove uploads older than the grace period which no row points to
+func cleanOrphanedImage(ctx context.Context, dep mixin.StoreDepency, grace time.Duration) error {
+	imgList, err := dep.DogModel.ListImg(ctx)
+	if err != nil {
+		return err
+	}
+	photoList, err := dep.LostAlertModel.ListPhotoUrl(ctx)
+	if err != nil {
+		return err
+	}
+	attachmentList, err := dep.HealthModel.ListAttachment(ctx)
+	if err != nil {
+		return err
+	}
+	galleryList, err := dep.DogPhotoModel.ListUrl(ctx)
+	if err != nil {
+		return err
+	}
+	referenced := make(map[string]bool, len(imgList)+len(photoList)+len(attachmentList)+len(galleryList))
+	for _, url := range append(append(append(imgList, photoList...), attachmentList...), galleryList...) {
+		if strings.HasPrefix(url, controller.ImageUrlPrefix) {
+			referenced[strings.TrimPrefix(url, controller.ImageUrlPrefix)] = true
+		}
+	}
+
+	entries, err := os.ReadDir(controller.ImageDir)
+	if err != nil {
+		return err
+	}
+	before := time.Now().Add(-grace)
+	removed := 0
+	for _, entry := range entries {
+		if entry.IsDir() || referenced[entry.Name()] {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || info.ModTime().After(before) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(controller.ImageDir, entry.Name())); err != nil {
+			logger.Warnf(ctx, "remove orphaned image %s fail %+v", entry.Name(), err)
+			continue
+		}
+		removed++
+	}
+	if removed > 0 {
+		logger.Infof(ctx, "removed %d orphaned images", removed)
+	}
+	return nil
+}
+
+// jobLocker one SetNx per job slot, the key expires on its own
+type jobLocker struct {
+	dep mixin.StoreDepency
+}
+
+// Acquire
+func (l jobLocker) Acquire(job string, slot time.Time, ttl time.Duration) (bool, error) {
+	return l.dep.RedisClient.SetNx(keys.GetJobLockKey(job, slot.Unix()), time.Now().Unix(), ttl)
+}
+
+// jobRecorder job run history in the job_run table
+type jobRecorder struct {
+	dep mixin.StoreDepency
+}
+
+// Start
+func (r jobRecorder) Start(ctx context.Context, run *scheduler.Run) error {
+	item := schema.JobRun{
+		Job:         run.Job,
+		Instance:    run.Instance,
+		Slot:        &run.Slot,
+		Status:      run.Status,
+		StartedTime: &run.StartedTime,
+	}
+	if err := r.dep.JobRunModel.Create(ctx, &item); err != nil {
+		return err
+	}
+	run.ID = item.ID
+	return nil
+}
+
+// Finish
+func (r jobRecorder) Finish(ctx context.Context, run *scheduler.Run) error {
+	if run.ID == 0 {
+		return nil
+	}
+	return r.dep.JobRunModel.Finish(ctx, run.ID, run.Status, run.Attempts, run.Error, run.FinishedTime)
+}
@@ -3,6 +3,9 @@ package service
 import (
 	"fmt"
 	"net/http"
+	"net/url"
+
+	configs "github.com/yiff028/comp90018-mobile-project/backend/app/config"
 
 	"github.com/yiff028/comp90018-mobile-project/backend/pkg/logger"
 	"github.com/yiff028/comp90018-mobile-project/backend/pkg/util"
@@ -14,7 +17,8 @@ type Weather interface {
 
 // GetSecList
 func (w *webService) GetByLocation(lon float64, lat float64) (interface{}, error) {
-	reqURL := fmt.Sprintf("https://api.openweathermap.org/", lat, lon)
+	reqURL := fmt.Sprintf("https://api.openweathermap.org/data/3.0/onecall?lat=%f&lon=%f&exclude=minutely,daily,alerts&appid=%s",
+		lat, lon, url.QueryEscape(configs.C.Services.WeatherApiKey))
 	req, err := http.NewRequest("GET", reqURL, nil)
 	if err != nil {
 		logger.Errorf(w.Context, "create new request fail %+v", err)
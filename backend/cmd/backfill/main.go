@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"path/filepath"
+
+	configs "github.com/yiff028/comp90018-mobile-project/backend/app/config"
+	"github.com/yiff028/comp90018-mobile-project/backend/app/model"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/driver"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/logger"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/util"
+)
+
+// backfill recomputes data derived from existing rows
+//
+//	route-stats   walk stats of every route from its points
+//	bin-location  spatial column of bins, run after adding the nullable column
+//	              and before making it NOT NULL and adding the spatial index
+//	route-dog     route_dog rows of walks created before multi-dog walks
+//	dog-photo     gallery rows of dogs created before the gallery, from their img
+func main() {
+	dir, err := filepath.Abs(filepath.Dir("."))
+	if err != nil {
+		logger.Fatalf(context.Background(), "load error")
+	}
+	configPath := flag.String("config", dir+"/configs/config.yaml", "config file path")
+	task := flag.String("task", "route-stats", "route-stats | bin-location | route-dog | dog-photo")
+	flag.Parse()
+
+	ctx := logger.NewTraceIDContext(context.Background(), "pawtrack-backfill")
+	configs.LoadYaml(*configPath)
+
+	database := driver.CreateDB(configs.C)
+	defer database.Close()
+
+	switch *task {
+	case "route-stats":
+		backfillRouteStats(ctx, database)
+	case "bin-location":
+		backfillBinLocation(ctx, database)
+	case "route-dog":
+		backfillRouteDog(ctx, database)
+	case "dog-photo":
+		backfillDogPhoto(ctx, database)
+	default:
+		logger.Fatalf(ctx, "unknown task %s", *task)
+	}
+}
+
+func backfillRouteStats(ctx context.Context, database *driver.Database) {
+	routeModel := model.Route{DB: database.Db}
+	routePointModel := model.RoutePoint{DB: database.Db}
+	routePauseModel := model.RoutePause{DB: database.Db}
+
+	routeList, err := routeModel.ListRoute(ctx, nil)
+	if err != nil {
+		logger.Fatalf(ctx, "list route fail %+v", err)
+	}
+
+	failed := 0
+	for _, route := range *routeList {
+		pointList, err := routePointModel.ListByRouteId(ctx, route.ID)
+		if err != nil {
+			logger.Errorf(ctx, "list route point fail, route %d %+v", route.ID, err)
+			failed++
+			continue
+		}
+		pauseList, err := routePauseModel.ListByRouteId(ctx, route.ID)
+		if err != nil {
+			logger.Errorf(ctx, "list route pause fail, route %d %+v", route.ID, err)
+			failed++
+			continue
+		}
+		stats := util.ComputeWalkStats(pointList.GeoPoints(), pauseList.Pauses())
+		if err := routeModel.SetStats(ctx, route.ID, stats); err != nil {
+			logger.Errorf(ctx, "set route stats fail, route %d %+v", route.ID, err)
+			failed++
+			continue
+		}
+	}
+	logger.Infof(ctx, "backfill route stats done, total %d failed %d", len(*routeList), failed)
+}
+
+func backfillBinLocation(ctx context.Context, database *driver.Database) {
+	binModel := model.Bin{DB: database.Db}
+
+	count, err := binModel.BackfillLocation(ctx)
+	if err != nil {
+		logger.Fatalf(ctx, "backfill bin location fail %+v", err)
+	}
+	logger.Infof(ctx, "backfill bin location done, updated %d", count)
+}
+
+func backfillRouteDog(ctx context.Context, database *driver.Database) {
+	routeDogModel := model.RouteDog{DB: database.Db}
+
+	count, err := routeDogModel.BackfillFromRoute(ctx)
+	if err != nil {
+		logger.Fatalf(ctx, "backfill route dog fail %+v", err)
+	}
+	logger.Infof(ctx, "backfill route dog done, inserted %d", count)
+}
+
+func backfillDogPhoto(ctx context.Context, database *driver.Database) {
+	dogPhotoModel := model.DogPhoto{DB: database.Db}
+
+	count, err := dogPhotoModel.BackfillFromDog(ctx)
+	if err != nil {
+		logger.Fatalf(ctx, "backfill dog photo fail %+v", err)
+	}
+	logger.Infof(ctx, "backfill dog photo done, inserted %d", count)
+}
@@ -160,6 +160,18 @@ func (client *ClientType) LPush(key string, values ...interface{}) (int64, error
 	return lres, nil
 }
 
+// BRPop blocks up to timeout, nil result if nothing arrived
+func (client *ClientType) BRPop(timeout time.Duration, keys ...string) ([]string, error) {
+	res, err := (*client).Conn.BRPop(timeout, keys...).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
 // LRange
 func (client *ClientType) LRange(key string, start int64, end int64) ([]string, error) {
 	list, err := (*client).Conn.LRange(key, start, end).Result()
@@ -184,6 +196,15 @@ func (client *ClientType) PSubscribe(channels ...string) *redis.PubSub {
 	return ps
 }
 
+// Publish
+func (client *ClientType) Publish(channel string, message interface{}) (int64, error) {
+	receivers, err := (*client).Conn.Publish(channel, message).Result()
+	if err != nil {
+		return 0, err
+	}
+	return receivers, nil
+}
+
 // SCard
 func (client *ClientType) SCard(key string) (int64, error) {
 	count, err := (*client).Conn.SCard(key).Result()
@@ -169,11 +169,25 @@ var (
 	ErrUserImageResizedFail     = NewResponse(21008, "ErrUserImageResizedFail", http.StatusOK)
 
 	//Dog
-	CreateDogFail      = NewResponse(22000, "CreateDogFail", http.StatusOK)
-	ListDogFail        = NewResponse(22001, "ListDogFail", http.StatusOK)
-	DeleteDogFail      = NewResponse(22002, "DeleteDogFail", http.StatusOK)
-	ErrNickNameTooLong = NewResponse(20745, "Nickname too long - maximum length is 50", http.StatusOK)
-	ErrEmailInvalid    = NewResponse(20746, "Invalid email format", http.StatusOK)
+	CreateDogFail            = NewResponse(22000, "CreateDogFail", http.StatusOK)
+	ListDogFail              = NewResponse(22001, "ListDogFail", http.StatusOK)
+	DeleteDogFail            = NewResponse(22002, "DeleteDogFail", http.StatusOK)
+	ListNearbyDogFail        = NewResponse(22003, "ListNearbyDogFail", http.StatusOK)
+	StreamDogLocationFail    = NewResponse(22004, "StreamDogLocationFail", http.StatusOK)
+	SetDogGoalFail           = NewResponse(22005, "SetDogGoalFail", http.StatusOK)
+	DogGoalIllegal           = NewResponse(22006, "DogGoalIllegal", http.StatusOK)
+	GetDogGoalProgressFail   = NewResponse(22007, "GetDogGoalProgressFail", http.StatusOK)
+	GetDogRecommendationFail = NewResponse(22008, "GetDogRecommendationFail", http.StatusOK)
+	UpdateDogFail            = NewResponse(22009, "UpdateDogFail", http.StatusOK)
+	ListDogChangeFail        = NewResponse(22010, "ListDogChangeFail", http.StatusOK)
+	DogPhotoIllegal          = NewResponse(22011, "DogPhotoIllegal", http.StatusOK)
+	DogPhotoNotExist         = NewResponse(22012, "DogPhotoNotExist", http.StatusOK)
+	UpdateDogPhotoFail       = NewResponse(22013, "UpdateDogPhotoFail", http.StatusOK)
+	ListDogPhotoFail         = NewResponse(22014, "ListDogPhotoFail", http.StatusOK)
+	ListPlaymateFail         = NewResponse(22015, "ListPlaymateFail", http.StatusOK)
+	DogLocationUnknown       = NewResponse(22016, "DogLocationUnknown", http.StatusOK)
+	ErrNickNameTooLong       = NewResponse(20745, "Nickname too long - maximum length is 50", http.StatusOK)
+	ErrEmailInvalid          = NewResponse(20746, "Invalid email format", http.StatusOK)
 
 	//Route
 	CreateRouteFail      = NewResponse(22100, "CreateRouteFail", http.StatusOK)
@@ -182,12 +196,105 @@ var (
 	GetRouteByIdFail     = NewResponse(22103, "GetRouteByIdFail", http.StatusOK)
 	CreateRoutePointFail = NewResponse(22104, "CreateRoutePointFail", http.StatusOK)
 	ListRoutePointFail   = NewResponse(22105, "ListRoutePointFail", http.StatusOK)
+	RouteNotActive       = NewResponse(22106, "RouteNotActive", http.StatusOK)
+	RouteStatusIllegal   = NewResponse(22107, "RouteStatusIllegal", http.StatusOK)
+	UpdateRouteFail      = NewResponse(22108, "UpdateRouteFail", http.StatusOK)
+	UploadRoutePointFail = NewResponse(22109, "UploadRoutePointFail", http.StatusOK)
+	ExportRouteFail      = NewResponse(22110, "ExportRouteFail", http.StatusOK)
+	ExportRangeIllegal   = NewResponse(22111, "ExportRangeIllegal", http.StatusOK)
+	ImportRouteFail      = NewResponse(22112, "ImportRouteFail", http.StatusOK)
+	RouteDogIllegal      = NewResponse(22113, "RouteDogIllegal", http.StatusOK)
+	ListDogStatsFail     = NewResponse(22114, "ListDogStatsFail", http.StatusOK)
 
 	//Bin
-	CreateBinFail  = NewResponse(22200, "CreateBinFail", http.StatusOK)
-	DeleteBinFail  = NewResponse(22201, "DeleteBinFail", http.StatusOK)
-	ListAllBinFail = NewResponse(22202, "ListAllBinFail", http.StatusOK)
+	CreateBinFail     = NewResponse(22200, "CreateBinFail", http.StatusOK)
+	DeleteBinFail     = NewResponse(22201, "DeleteBinFail", http.StatusOK)
+	ListAllBinFail    = NewResponse(22202, "ListAllBinFail", http.StatusOK)
+	ListNearbyBinFail = NewResponse(22203, "ListNearbyBinFail", http.StatusOK)
+	BoundsIllegal     = NewResponse(22204, "BoundsIllegal", http.StatusOK)
+
+	//Privacy
+	GetPrivacyFail         = NewResponse(22300, "GetPrivacyFail", http.StatusOK)
+	UpdatePrivacyFail      = NewResponse(22301, "UpdatePrivacyFail", http.StatusOK)
+	ListHomeZoneFail       = NewResponse(22302, "ListHomeZoneFail", http.StatusOK)
+	CreateHomeZoneFail     = NewResponse(22303, "CreateHomeZoneFail", http.StatusOK)
+	DeleteHomeZoneFail     = NewResponse(22304, "DeleteHomeZoneFail", http.StatusOK)
+	ListFriendFail         = NewResponse(22305, "ListFriendFail", http.StatusOK)
+	AddFriendFail          = NewResponse(22306, "AddFriendFail", http.StatusOK)
+	RemoveFriendFail       = NewResponse(22307, "RemoveFriendFail", http.StatusOK)
+	UserNotExist           = NewResponse(22308, "UserNotExist", http.StatusOK)
+	ListLocationPolicyFail = NewResponse(22309, "ListLocationPolicyFail", http.StatusOK)
+
+	//Share
+	CreateShareFail = NewResponse(22400, "CreateShareFail", http.StatusOK)
+	RevokeShareFail = NewResponse(22401, "RevokeShareFail", http.StatusOK)
+	ListShareFail   = NewResponse(22402, "ListShareFail", http.StatusOK)
+	ShareNotExist   = NewResponse(22403, "ShareNotExist", http.StatusOK)
+
+	//Geofence
+	CreateGeofenceFail    = NewResponse(22500, "CreateGeofenceFail", http.StatusOK)
+	UpdateGeofenceFail    = NewResponse(22501, "UpdateGeofenceFail", http.StatusOK)
+	DeleteGeofenceFail    = NewResponse(22502, "DeleteGeofenceFail", http.StatusOK)
+	ListGeofenceFail      = NewResponse(22503, "ListGeofenceFail", http.StatusOK)
+	ListGeofenceEventFail = NewResponse(22504, "ListGeofenceEventFail", http.StatusOK)
+	GeofenceIllegal       = NewResponse(22505, "GeofenceIllegal", http.StatusOK)
+	GeofenceNotExist      = NewResponse(22506, "GeofenceNotExist", http.StatusOK)
+
+	//Lost
+	ReportLostFail       = NewResponse(22600, "ReportLostFail", http.StatusOK)
+	DogAlreadyLost       = NewResponse(22601, "DogAlreadyLost", http.StatusOK)
+	LostLocationUnknown  = NewResponse(22602, "LostLocationUnknown", http.StatusOK)
+	ListLostAlertFail    = NewResponse(22603, "ListLostAlertFail", http.StatusOK)
+	LostAlertNotExist    = NewResponse(22604, "LostAlertNotExist", http.StatusOK)
+	ResolveLostAlertFail = NewResponse(22605, "ResolveLostAlertFail", http.StatusOK)
+	CreateSightingFail   = NewResponse(22606, "CreateSightingFail", http.StatusOK)
+	ListSightingFail     = NewResponse(22607, "ListSightingFail", http.StatusOK)
+
+	//Notification
+	ListNotificationFail       = NewResponse(22700, "ListNotificationFail", http.StatusOK)
+	MarkNotificationReadFail   = NewResponse(22701, "MarkNotificationReadFail", http.StatusOK)
+	ListNotificationPrefFail   = NewResponse(22702, "ListNotificationPrefFail", http.StatusOK)
+	UpdateNotificationPrefFail = NewResponse(22703, "UpdateNotificationPrefFail", http.StatusOK)
+	RegisterDeviceFail         = NewResponse(22704, "RegisterDeviceFail", http.StatusOK)
+	UnregisterDeviceFail       = NewResponse(22705, "UnregisterDeviceFail", http.StatusOK)
+
+	//Health
+	CreateHealthRecordFail = NewResponse(22800, "CreateHealthRecordFail", http.StatusOK)
+	UpdateHealthRecordFail = NewResponse(22801, "UpdateHealthRecordFail", http.StatusOK)
+	DeleteHealthRecordFail = NewResponse(22802, "DeleteHealthRecordFail", http.StatusOK)
+	ListHealthRecordFail   = NewResponse(22803, "ListHealthRecordFail", http.StatusOK)
+	HealthRecordNotExist   = NewResponse(22804, "HealthRecordNotExist", http.StatusOK)
+	HealthRecordIllegal    = NewResponse(22805, "HealthRecordIllegal", http.StatusOK)
+	ListHealthDueFail      = NewResponse(22806, "ListHealthDueFail", http.StatusOK)
+
+	//Weight
+	AddWeightFail      = NewResponse(22900, "AddWeightFail", http.StatusOK)
+	DeleteWeightFail   = NewResponse(22901, "DeleteWeightFail", http.StatusOK)
+	GetWeightTrendFail = NewResponse(22902, "GetWeightTrendFail", http.StatusOK)
+	WeightLogNotExist  = NewResponse(22903, "WeightLogNotExist", http.StatusOK)
+	WeightLogIllegal   = NewResponse(22904, "WeightLogIllegal", http.StatusOK)
+
+	//Household
+	CreateHouseholdFail    = NewResponse(23000, "CreateHouseholdFail", http.StatusOK)
+	ListHouseholdFail      = NewResponse(23001, "ListHouseholdFail", http.StatusOK)
+	HouseholdNotExist      = NewResponse(23002, "HouseholdNotExist", http.StatusOK)
+	HouseholdRoleDenied    = NewResponse(23003, "HouseholdRoleDenied", http.StatusOK)
+	CreateInviteFail       = NewResponse(23004, "CreateInviteFail", http.StatusOK)
+	InviteNotExist         = NewResponse(23005, "InviteNotExist", http.StatusOK)
+	JoinHouseholdFail      = NewResponse(23006, "JoinHouseholdFail", http.StatusOK)
+	UpdateMemberFail       = NewResponse(23007, "UpdateMemberFail", http.StatusOK)
+	RemoveMemberFail       = NewResponse(23008, "RemoveMemberFail", http.StatusOK)
+	MemberNotExist         = NewResponse(23009, "MemberNotExist", http.StatusOK)
+	HouseholdOwnerMissing  = NewResponse(23010, "HouseholdOwnerMissing", http.StatusOK)
+	UpdateHouseholdDogFail = NewResponse(23011, "UpdateHouseholdDogFail", http.StatusOK)
 
 	//Weather
 	GetWeatherUsingApiFail = NewResponse(23100, "GetWeatherUsingApiFail", http.StatusOK)
+
+	//Grant
+	CreateGrantFail = NewResponse(23200, "CreateGrantFail", http.StatusOK)
+	RevokeGrantFail = NewResponse(23201, "RevokeGrantFail", http.StatusOK)
+	ListGrantFail   = NewResponse(23202, "ListGrantFail", http.StatusOK)
+	GrantIllegal    = NewResponse(23203, "GrantIllegal", http.StatusOK)
+	GrantNotExist   = NewResponse(23204, "GrantNotExist", http.StatusOK)
 )
@@ -0,0 +1,57 @@
+package geoformat
+
+import (
+	"io"
+
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/util"
+)
+
+type geoJSONFeatureCollection struct {
+	Type     string           `json:"type"`
+	Features []geoJSONFeature `json:"features"`
+}
+
+type geoJSONFeature struct {
+	Type       string                 `json:"type"`
+	Geometry   geoJSONGeometry        `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+type geoJSONGeometry struct {
+	Type        string      `json:"type"`
+	Coordinates [][]float64 `json:"coordinates"`
+}
+
+// WriteGeoJSON FeatureCollection with one LineString feature per track
+// timestamps go to the coordTimes property, the convention used by most trackers
+func WriteGeoJSON(w io.Writer, tracks []Track) error {
+	collection := geoJSONFeatureCollection{
+		Type:     "FeatureCollection",
+		Features: []geoJSONFeature{},
+	}
+	for _, track := range tracks {
+		coordinates := make([][]float64, 0, len(track.Points))
+		coordTimes := make([]string, 0, len(track.Points))
+		for _, p := range track.Points {
+			coordinates = append(coordinates, []float64{p.Longitude, p.Latitude})
+			coordTimes = append(coordTimes, formatTime(p.Time))
+		}
+		collection.Features = append(collection.Features, geoJSONFeature{
+			Type: "Feature",
+			Geometry: geoJSONGeometry{
+				Type:        "LineString",
+				Coordinates: coordinates,
+			},
+			Properties: map[string]interface{}{
+				"routeId":    track.ID,
+				"name":       track.Name,
+				"dogName":    track.DogName(),
+				"time":       formatTime(track.Start),
+				"coordTimes": coordTimes,
+			},
+		})
+	}
+
+	encoder := util.JSONNewEncoder(w)
+	return encoder.Encode(collection)
+}
@@ -0,0 +1,98 @@
+package geoformat
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+)
+
+type gpxFile struct {
+	XMLName  xml.Name    `xml:"gpx"`
+	Version  string      `xml:"version,attr"`
+	Creator  string      `xml:"creator,attr"`
+	Xmlns    string      `xml:"xmlns,attr,omitempty"`
+	Metadata gpxMetadata `xml:"metadata"`
+	Tracks   []gpxTrack  `xml:"trk"`
+}
+
+type gpxMetadata struct {
+	Name string `xml:"name,omitempty"`
+	Desc string `xml:"desc,omitempty"`
+	Time string `xml:"time,omitempty"`
+}
+
+type gpxTrack struct {
+	Name     string       `xml:"name,omitempty"`
+	Desc     string       `xml:"desc,omitempty"`
+	Segments []gpxSegment `xml:"trkseg"`
+}
+
+type gpxSegment struct {
+	Points []gpxPoint `xml:"trkpt"`
+}
+
+type gpxPoint struct {
+	Lat  float64 `xml:"lat,attr"`
+	Lon  float64 `xml:"lon,attr"`
+	Time string  `xml:"time,omitempty"`
+}
+
+// WriteGPX GPX 1.1, one trk per track
+func WriteGPX(w io.Writer, tracks []Track) error {
+	file := gpxFile{
+		Version: "1.1",
+		Creator: creator,
+		Xmlns:   "http://www.topografix.com/GPX/1/1",
+		Metadata: gpxMetadata{
+			Name: documentName(tracks),
+			Desc: "Dog: " + dogNames(tracks),
+		},
+	}
+	if len(tracks) > 0 {
+		file.Metadata.Time = formatTime(tracks[0].Start)
+	}
+	for _, track := range tracks {
+		segment := gpxSegment{}
+		for _, p := range track.Points {
+			point := gpxPoint{Lat: p.Latitude, Lon: p.Longitude}
+			if !p.Time.IsZero() {
+				point.Time = formatTime(p.Time)
+			}
+			segment.Points = append(segment.Points, point)
+		}
+		file.Tracks = append(file.Tracks, gpxTrack{
+			Name:     track.Name,
+			Desc:     "Dog: " + track.DogName(),
+			Segments: []gpxSegment{segment},
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	return encoder.Encode(file)
+}
+
+// dogNames distinct dog names of the tracks
+func dogNames(tracks []Track) string {
+	names := []string{}
+	for _, track := range tracks {
+		for _, name := range track.DogNames {
+			if name != "" && !containsString(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+func containsString(list []string, str string) bool {
+	for _, item := range list {
+		if item == str {
+			return true
+		}
+	}
+	return false
+}
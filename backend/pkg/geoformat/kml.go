@@ -0,0 +1,79 @@
+package geoformat
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+type kmlFile struct {
+	XMLName  xml.Name    `xml:"kml"`
+	Xmlns    string      `xml:"xmlns,attr"`
+	XmlnsGx  string      `xml:"xmlns:gx,attr"`
+	Document kmlDocument `xml:"Document"`
+}
+
+type kmlDocument struct {
+	Name       string         `xml:"name"`
+	Placemarks []kmlPlacemark `xml:"Placemark"`
+}
+
+type kmlPlacemark struct {
+	Name         string          `xml:"name"`
+	TimeStamp    *kmlTimeStamp   `xml:"TimeStamp,omitempty"`
+	ExtendedData kmlExtendedData `xml:"ExtendedData"`
+	Track        kmlTrack        `xml:"gx:Track"`
+}
+
+type kmlTimeStamp struct {
+	When string `xml:"when"`
+}
+
+type kmlExtendedData struct {
+	Data []kmlData `xml:"Data"`
+}
+
+type kmlData struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value"`
+}
+
+type kmlTrack struct {
+	When  []string `xml:"when"`
+	Coord []string `xml:"gx:coord"`
+}
+
+// WriteKML KML 2.2 with one gx:Track placemark per track so timestamps are kept
+func WriteKML(w io.Writer, tracks []Track) error {
+	file := kmlFile{
+		Xmlns:   "http://www.opengis.net/kml/2.2",
+		XmlnsGx: "http://www.google.com/kml/ext/2.2",
+		Document: kmlDocument{
+			Name: documentName(tracks),
+		},
+	}
+	for _, track := range tracks {
+		placemark := kmlPlacemark{
+			Name:      track.Name,
+			TimeStamp: &kmlTimeStamp{When: formatTime(track.Start)},
+			ExtendedData: kmlExtendedData{
+				Data: []kmlData{
+					{Name: "routeId", Value: fmt.Sprintf("%d", track.ID)},
+					{Name: "dogName", Value: track.DogName()},
+				},
+			},
+		}
+		for _, p := range track.Points {
+			placemark.Track.When = append(placemark.Track.When, formatTime(p.Time))
+			placemark.Track.Coord = append(placemark.Track.Coord, fmt.Sprintf("%f %f 0", p.Longitude, p.Latitude))
+		}
+		file.Document.Placemarks = append(file.Document.Placemarks, placemark)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	return encoder.Encode(file)
+}
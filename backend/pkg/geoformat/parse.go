@@ -0,0 +1,193 @@
+package geoformat
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/util"
+)
+
+// ParseGPX tracks of a GPX file, the segments of a trk are joined, routes (rte) are read as tracks too
+func ParseGPX(r io.Reader) ([]Track, error) {
+	var file struct {
+		Metadata gpxMetadata `xml:"metadata"`
+		Tracks   []struct {
+			Name     string `xml:"name"`
+			Segments []struct {
+				Points []gpxPoint `xml:"trkpt"`
+			} `xml:"trkseg"`
+		} `xml:"trk"`
+		Routes []struct {
+			Name   string     `xml:"name"`
+			Points []gpxPoint `xml:"rtept"`
+		} `xml:"rte"`
+	}
+	if err := xml.NewDecoder(r).Decode(&file); err != nil {
+		return nil, fmt.Errorf("invalid gpx: %w", err)
+	}
+
+	tracks := []Track{}
+	for _, trk := range file.Tracks {
+		track := Track{Name: trk.Name}
+		for _, segment := range trk.Segments {
+			for _, p := range segment.Points {
+				point, err := gpxToGeoPoint(p)
+				if err != nil {
+					return nil, err
+				}
+				track.Points = append(track.Points, point)
+			}
+		}
+		tracks = append(tracks, track)
+	}
+	for _, rte := range file.Routes {
+		track := Track{Name: rte.Name}
+		for _, p := range rte.Points {
+			point, err := gpxToGeoPoint(p)
+			if err != nil {
+				return nil, err
+			}
+			track.Points = append(track.Points, point)
+		}
+		tracks = append(tracks, track)
+	}
+	return tracks, nil
+}
+
+func gpxToGeoPoint(p gpxPoint) (util.GeoPoint, error) {
+	point := util.GeoPoint{Longitude: p.Lon, Latitude: p.Lat}
+	if p.Time != "" {
+		t, err := time.Parse(time.RFC3339, p.Time)
+		if err != nil {
+			return point, fmt.Errorf("invalid gpx time %q", p.Time)
+		}
+		point.Time = t
+	}
+	return point, nil
+}
+
+type geoJSONInput struct {
+	Type        string                 `json:"type"`
+	Features    []geoJSONInput         `json:"features"`
+	Geometry    *geoJSONInput          `json:"geometry"`
+	Coordinates interface{}            `json:"coordinates"`
+	Properties  map[string]interface{} `json:"properties"`
+}
+
+// ParseGeoJSON LineString and MultiLineString geometries, bare or wrapped in features or a FeatureCollection
+// timestamps are read from the coordTimes property when present
+func ParseGeoJSON(r io.Reader) ([]Track, error) {
+	var input geoJSONInput
+	if err := util.JSONNewDecoder(r).Decode(&input); err != nil {
+		return nil, fmt.Errorf("invalid geojson: %w", err)
+	}
+
+	tracks := []Track{}
+	var walk func(item geoJSONInput, properties map[string]interface{}) error
+	walk = func(item geoJSONInput, properties map[string]interface{}) error {
+		switch item.Type {
+		case "FeatureCollection":
+			for _, feature := range item.Features {
+				if err := walk(feature, nil); err != nil {
+					return err
+				}
+			}
+		case "Feature":
+			if item.Geometry != nil {
+				return walk(*item.Geometry, item.Properties)
+			}
+		case "LineString", "MultiLineString":
+			lines, err := geoJSONLines(item)
+			if err != nil {
+				return err
+			}
+			times := geoJSONCoordTimes(properties)
+			track := Track{}
+			if name, ok := properties["name"].(string); ok {
+				track.Name = name
+			}
+			index := 0
+			for _, line := range lines {
+				for _, coordinate := range line {
+					point := util.GeoPoint{Longitude: coordinate[0], Latitude: coordinate[1]}
+					if index < len(times) {
+						point.Time = times[index]
+					}
+					track.Points = append(track.Points, point)
+					index++
+				}
+			}
+			tracks = append(tracks, track)
+		}
+		return nil
+	}
+	if err := walk(input, nil); err != nil {
+		return nil, err
+	}
+	return tracks, nil
+}
+
+// geoJSONLines coordinates of a LineString or MultiLineString as lines of [lon, lat]
+func geoJSONLines(item geoJSONInput) ([][][2]float64, error) {
+	raw, err := util.JSONMarshal(item.Coordinates)
+	if err != nil {
+		return nil, err
+	}
+	if item.Type == "LineString" {
+		var line [][]float64
+		if err := util.JSONUnmarshal(raw, &line); err != nil {
+			return nil, fmt.Errorf("invalid LineString coordinates: %w", err)
+		}
+		converted, err := toLonLat(line)
+		if err != nil {
+			return nil, err
+		}
+		return [][][2]float64{converted}, nil
+	}
+	var lines [][][]float64
+	if err := util.JSONUnmarshal(raw, &lines); err != nil {
+		return nil, fmt.Errorf("invalid MultiLineString coordinates: %w", err)
+	}
+	result := [][][2]float64{}
+	for _, line := range lines {
+		converted, err := toLonLat(line)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, converted)
+	}
+	return result, nil
+}
+
+func toLonLat(line [][]float64) ([][2]float64, error) {
+	result := make([][2]float64, 0, len(line))
+	for _, coordinate := range line {
+		if len(coordinate) < 2 {
+			return nil, fmt.Errorf("invalid coordinate %v", coordinate)
+		}
+		result = append(result, [2]float64{coordinate[0], coordinate[1]})
+	}
+	return result, nil
+}
+
+func geoJSONCoordTimes(properties map[string]interface{}) []time.Time {
+	raw, ok := properties["coordTimes"].([]interface{})
+	if !ok {
+		return nil
+	}
+	times := make([]time.Time, 0, len(raw))
+	for _, item := range raw {
+		str, ok := item.(string)
+		if !ok {
+			return nil
+		}
+		t, err := time.Parse(time.RFC3339, str)
+		if err != nil {
+			return nil
+		}
+		times = append(times, t)
+	}
+	return times
+}
@@ -0,0 +1,64 @@
+package geoformat
+
+import (
+	"io"
+	"strings"
+	"time"
+
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/util"
+)
+
+// creator written into exported files
+const creator = "PawTrack"
+
+// Track one walk, points ordered by time
+type Track struct {
+	ID       uint
+	Name     string
+	DogNames []string
+	Start    time.Time
+	Points   []util.GeoPoint
+}
+
+// DogName every dog of the walk, comma separated
+func (t Track) DogName() string {
+	return strings.Join(t.DogNames, ", ")
+}
+
+// Format
+type Format struct {
+	Ext         string
+	ContentType string
+	Write       func(w io.Writer, tracks []Track) error
+	// Parse nil when importing the format is not supported
+	Parse func(r io.Reader) ([]Track, error)
+}
+
+// supported formats
+var (
+	GPX     = Format{Ext: "gpx", ContentType: "application/gpx+xml", Write: WriteGPX, Parse: ParseGPX}
+	GeoJSON = Format{Ext: "geojson", ContentType: "application/geo+json", Write: WriteGeoJSON, Parse: ParseGeoJSON}
+	KML     = Format{Ext: "kml", ContentType: "application/vnd.google-earth.kml+xml", Write: WriteKML}
+)
+
+// GetFormat by extension name
+func GetFormat(ext string) (Format, bool) {
+	for _, format := range []Format{GPX, GeoJSON, KML} {
+		if format.Ext == ext {
+			return format, true
+		}
+	}
+	return Format{}, false
+}
+
+// documentName shared title of an export
+func documentName(tracks []Track) string {
+	if len(tracks) == 1 {
+		return tracks[0].Name
+	}
+	return creator + " walks"
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,79 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCronInvalid(t *testing.T) {
+	specs := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"1-a * * * *",
+		"@every 10ms",
+		"@every soon",
+	}
+	for _, spec := range specs {
+		if _, err := ParseCron(spec); err == nil {
+			t.Errorf("ParseCron(%q) expected an error", spec)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	at := func(value string) time.Time {
+		parsed, err := time.Parse("2006-01-02 15:04:05", value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsed
+	}
+	cases := []struct {
+		name string
+		spec string
+		from string
+		want string
+	}{
+		{"step", "*/5 * * * *", "2024-01-01 10:02:30", "2024-01-01 10:05:00"},
+		{"strictly after", "0 * * * *", "2024-01-01 10:00:00", "2024-01-01 11:00:00"},
+		{"offset step", "5/15 * * * *", "2024-01-01 10:06:00", "2024-01-01 10:20:00"},
+		{"list", "0,30 * * * *", "2024-01-01 10:10:00", "2024-01-01 10:30:00"},
+		{"hour rollover", "*/20 8-10 * * *", "2024-01-01 10:41:00", "2024-01-02 08:00:00"},
+		{"year rollover", "30 23 * * *", "2024-12-31 23:45:00", "2025-01-01 23:30:00"},
+		{"month rollover", "0 0 1 * *", "2024-01-31 12:00:00", "2024-02-01 00:00:00"},
+		{"weekdays", "0 9 * * 1-5", "2024-01-05 10:00:00", "2024-01-08 09:00:00"},
+		{"sunday as 7", "0 0 * * 7", "2024-01-01 00:00:00", "2024-01-07 00:00:00"},
+		{"day of month or day of week", "0 0 13 * 5", "2024-01-01 00:00:00", "2024-01-05 00:00:00"},
+		{"restricted month", "0 0 1 6 *", "2024-07-01 00:00:00", "2025-06-01 00:00:00"},
+		{"leap day", "0 0 29 2 *", "2024-03-01 00:00:00", "2028-02-29 00:00:00"},
+		{"descriptor", "@daily", "2024-01-01 10:00:00", "2024-01-02 00:00:00"},
+		{"every", "@every 30s", "2024-01-01 10:00:10", "2024-01-01 10:00:30"},
+		{"never", "0 0 31 2 *", "2024-01-01 00:00:00", ""},
+	}
+	for _, c := range cases {
+		schedule, err := ParseCron(c.spec)
+		if err != nil {
+			t.Errorf("%s: ParseCron(%q) %v", c.name, c.spec, err)
+			continue
+		}
+		got := schedule.Next(at(c.from))
+		if c.want == "" {
+			if !got.IsZero() {
+				t.Errorf("%s: Next(%s) = %s, want none", c.name, c.from, got)
+			}
+			continue
+		}
+		if want := at(c.want); !got.Equal(want) {
+			t.Errorf("%s: Next(%s) = %s, want %s", c.name, c.from, got, want)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/logger"
+	"github.com/yiff028/comp90018-mobile-project/backend/pkg/util"
+)
+
+const (
+	defaultTimeout       = 5 * time.Minute
+	defaultRetryInterval = 10 * time.Second
+	// stopTimeout longest Stop waits for the running jobs to notice the cancellation
+	stopTimeout = 30 * time.Second
+)
+
+const (
+	RunStatusRunning = "running"
+	RunStatusSuccess = "success"
+	RunStatusFailed  = "failed"
+)
+
+// Job Retries extra attempts after a failure
+type Job struct {
+	Name          string
+	Schedule      Schedule
+	Timeout       time.Duration
+	Retries       int
+	RetryInterval time.Duration
+	Run           func(ctx context.Context) error
+}
+
+// Locker only one instance may run a job for a given slot
+type Locker interface {
+	Acquire(job string, slot time.Time, ttl time.Duration) (bool, error)
+}
+
+// Recorder keeps the run history
+type Recorder interface {
+	Start(ctx context.Context, run *Run) error
+	Finish(ctx context.Context, run *Run) error
+}
+
+// Run one execution of a job
+type Run struct {
+	ID           uint
+	Job          string
+	Instance     string
+	Slot         time.Time
+	StartedTime  time.Time
+	FinishedTime time.Time
+	Status       string
+	Attempts     int
+	Error        string
+}
+
+// Scheduler runs each job on its schedule until Stop
+type Scheduler struct {
+	Locker   Locker
+	Recorder Recorder
+	Instance string
+
+	jobs   []Job
+	stop   chan struct{}
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New
+func New(locker Locker, recorder Recorder) *Scheduler {
+	hostname, _ := os.Hostname()
+	return &Scheduler{
+		Locker:   locker,
+		Recorder: recorder,
+		Instance: fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+		stop:     make(chan struct{}),
+	}
+}
+
+// Add before Start
+func (s *Scheduler) Add(job Job) {
+	if job.Timeout <= 0 {
+		job.Timeout = defaultTimeout
+	}
+	if job.RetryInterval <= 0 {
+		job.RetryInterval = defaultRetryInterval
+	}
+	s.jobs = append(s.jobs, job)
+}
+
+// Start one goroutine per job, the jobs run on a context cancelled by Stop
+func (s *Scheduler) Start(ctx context.Context) {
+	ctx, s.cancel = context.WithCancel(ctx)
+	for _, job := range s.jobs {
+		s.wg.Add(1)
+		go s.loop(ctx, job)
+	}
+}
+
+// Stop no new runs are started, the running ones are cancelled and waited for up to stopTimeout
+func (s *Scheduler) Stop() {
+	close(s.stop)
+	if s.cancel != nil {
+		s.cancel()
+	}
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(stopTimeout):
+		logger.Warnf(context.Background(), "scheduler stop gave up waiting for running jobs after %s", stopTimeout)
+	}
+}
+
+func (s *Scheduler) loop(ctx context.Context, job Job) {
+	defer s.wg.Done()
+	for {
+		slot := job.Schedule.Next(time.Now())
+		if slot.IsZero() {
+			logger.Warnf(ctx, "job %s has no next run", job.Name)
+			return
+		}
+		timer := time.NewTimer(time.Until(slot))
+		select {
+		case <-s.stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		s.fire(ctx, job, slot)
+	}
+}
+
+// fire run the slot unless another instance already took it
+func (s *Scheduler) fire(ctx context.Context, job Job, slot time.Time) {
+	// the lock outlives the run so a slow instance cannot run the same slot again
+	ok, err := s.Locker.Acquire(job.Name, slot, job.Timeout*time.Duration(job.Retries+1)+time.Minute)
+	if err != nil {
+		logger.Errorf(ctx, "acquire lock of job %s fail %+v", job.Name, err)
+		return
+	}
+	if !ok {
+		return
+	}
+
+	run := Run{
+		Job:         job.Name,
+		Instance:    s.Instance,
+		Slot:        slot,
+		StartedTime: time.Now(),
+		Status:      RunStatusRunning,
+	}
+	if err := s.Recorder.Start(ctx, &run); err != nil {
+		logger.Errorf(ctx, "record start of job %s fail %+v", job.Name, err)
+	}
+
+	var lastErr error
+	err = util.Retry(ctx, job.Retries+1, job.RetryInterval, func() error {
+		run.Attempts++
+		lastErr = s.runOnce(ctx, job)
+		if lastErr != nil {
+			logger.Warnf(ctx, "job %s attempt %d fail %+v", job.Name, run.Attempts, lastErr)
+		}
+		return lastErr
+	})
+	if err != nil && lastErr != nil {
+		err = lastErr
+	}
+
+	run.FinishedTime = time.Now()
+	run.Status = RunStatusSuccess
+	if err != nil {
+		run.Status = RunStatusFailed
+		run.Error = err.Error()
+		logger.Errorf(ctx, "job %s fail after %d attempts %+v", job.Name, run.Attempts, err)
+	}
+	// recorded even when the run was cancelled by Stop
+	if err := s.Recorder.Finish(context.WithoutCancel(ctx), &run); err != nil {
+		logger.Errorf(ctx, "record finish of job %s fail %+v", job.Name, err)
+	}
+}
+
+// runOnce with the job timeout, a panic counts as a failure
+func (s *Scheduler) runOnce(ctx context.Context, job Job) (err error) {
+	jobCtx, cancel := context.WithTimeout(ctx, job.Timeout)
+	defer cancel()
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return job.Run(jobCtx)
+}
@@ -0,0 +1,78 @@
+package util
+
+import (
+	"fmt"
+	"math"
+)
+
+// BoundingBox in degrees
+type BoundingBox struct {
+	MinLongitude float64
+	MinLatitude  float64
+	MaxLongitude float64
+	MaxLatitude  float64
+}
+
+// NewBoundingBox smallest box containing the circle of radius metres around the point
+func NewBoundingBox(latitude, longitude, radius float64) BoundingBox {
+	dLat := radius / EarthRadius * 180 / math.Pi
+	dLon := 180.0
+	if cos := math.Cos(toRadians(latitude)); cos > 1e-6 {
+		dLon = math.Min(180, dLat/cos)
+	}
+	return BoundingBox{
+		MinLongitude: math.Max(-180, longitude-dLon),
+		MinLatitude:  math.Max(-90, latitude-dLat),
+		MaxLongitude: math.Min(180, longitude+dLon),
+		MaxLatitude:  math.Min(90, latitude+dLat),
+	}
+}
+
+// Expand grow the box by metres on every side
+func (b BoundingBox) Expand(metres float64) BoundingBox {
+	dLat := metres / EarthRadius * 180 / math.Pi
+	dLon := 180.0
+	if cos := math.Cos(toRadians(math.Max(math.Abs(b.MinLatitude), math.Abs(b.MaxLatitude)))); cos > 1e-6 {
+		dLon = math.Min(180, dLat/cos)
+	}
+	return BoundingBox{
+		MinLongitude: math.Max(-180, b.MinLongitude-dLon),
+		MinLatitude:  math.Max(-90, b.MinLatitude-dLat),
+		MaxLongitude: math.Min(180, b.MaxLongitude+dLon),
+		MaxLatitude:  math.Min(90, b.MaxLatitude+dLat),
+	}
+}
+
+// Valid
+func (b BoundingBox) Valid() bool {
+	return b.MinLongitude <= b.MaxLongitude && b.MinLatitude <= b.MaxLatitude &&
+		b.MinLongitude >= -180 && b.MaxLongitude <= 180 && b.MinLatitude >= -90 && b.MaxLatitude <= 90
+}
+
+// Center
+func (b BoundingBox) Center() GeoPoint {
+	return GeoPoint{
+		Longitude: (b.MinLongitude + b.MaxLongitude) / 2,
+		Latitude:  (b.MinLatitude + b.MaxLatitude) / 2,
+	}
+}
+
+// Contains
+func (b BoundingBox) Contains(latitude, longitude float64) bool {
+	return latitude >= b.MinLatitude && latitude <= b.MaxLatitude && longitude >= b.MinLongitude && longitude <= b.MaxLongitude
+}
+
+// PolygonWKT longitude latitude order
+func (b BoundingBox) PolygonWKT() string {
+	return fmt.Sprintf("POLYGON((%f %f, %f %f, %f %f, %f %f, %f %f))",
+		b.MinLongitude, b.MinLatitude,
+		b.MaxLongitude, b.MinLatitude,
+		b.MaxLongitude, b.MaxLatitude,
+		b.MinLongitude, b.MaxLatitude,
+		b.MinLongitude, b.MinLatitude)
+}
+
+// PointWKT longitude latitude order
+func PointWKT(latitude, longitude float64) string {
+	return fmt.Sprintf("POINT(%f %f)", longitude, latitude)
+}
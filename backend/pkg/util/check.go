@@ -27,9 +27,15 @@ func Retry(ctx context.Context, maxRetries int, interval time.Duration, fn func(
 			return nil // Success
 		}
 
-		// Log the retry attempt (optional)
+		// wait before the next attempt, unless the context ends first
 		if i < maxRetries-1 {
-			time.Sleep(interval)
+			timer := time.NewTimer(interval)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
 		}
 	}
 
@@ -235,3 +235,12 @@ func GenerateShortKey(name string) string {
 	hashNum := h.Sum64()
 	return base36Encode(hashNum, 8) //  Base36
 }
+
+// RandomToken url safe token of n random bytes
+func RandomToken(n int) (string, error) {
+	bytes, err := generateRandomBytes(n)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(bytes), nil
+}
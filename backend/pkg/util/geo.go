@@ -0,0 +1,71 @@
+package util
+
+import (
+	"math"
+	"time"
+)
+
+// EarthRadius in metres
+const EarthRadius = 6371000.0
+
+// GeoPoint
+type GeoPoint struct {
+	Longitude float64
+	Latitude  float64
+	Time      time.Time
+}
+
+func toRadians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+// Haversine great-circle distance in metres
+func Haversine(lat1, lon1, lat2, lon2 float64) float64 {
+	dLat := toRadians(lat2 - lat1)
+	dLon := toRadians(lon2 - lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRadians(lat1))*math.Cos(toRadians(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * EarthRadius * math.Asin(math.Sqrt(math.Min(1, a)))
+}
+
+// DistanceTo
+func (p GeoPoint) DistanceTo(q GeoPoint) float64 {
+	return Haversine(p.Latitude, p.Longitude, q.Latitude, q.Longitude)
+}
+
+// metresPerDegree length of one degree of latitude
+const metresPerDegree = 111320.0
+
+// SnapToGrid move a coordinate to the centre of its grid cell, cells are roughly gridMetres wide
+func SnapToGrid(latitude, longitude, gridMetres float64) (float64, float64) {
+	if gridMetres <= 0 {
+		return latitude, longitude
+	}
+	latStep := gridMetres / metresPerDegree
+	snappedLat := (math.Floor(latitude/latStep) + 0.5) * latStep
+	snappedLat = math.Max(-90, math.Min(90, snappedLat))
+
+	// cell width of the row, so cells do not shrink towards the poles
+	cos := math.Cos(toRadians(snappedLat))
+	if cos < 0.01 {
+		cos = 0.01
+	}
+	lonStep := gridMetres / (metresPerDegree * cos)
+	snappedLon := (math.Floor(longitude/lonStep) + 0.5) * lonStep
+	snappedLon = math.Max(-180, math.Min(180, snappedLon))
+	return snappedLat, snappedLon
+}
+
+// PointInPolygon ray casting on the plane, good enough for fences a few km wide
+// the polygon does not need to repeat its first vertex
+func PointInPolygon(latitude, longitude float64, polygon []GeoPoint) bool {
+	inside := false
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		a, b := polygon[i], polygon[j]
+		if (a.Latitude > latitude) != (b.Latitude > latitude) &&
+			longitude < (b.Longitude-a.Longitude)*(latitude-a.Latitude)/(b.Latitude-a.Latitude)+a.Longitude {
+			inside = !inside
+		}
+	}
+	return inside
+}
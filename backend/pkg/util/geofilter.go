@@ -0,0 +1,110 @@
+package util
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	// MaxWalkSpeed a dog on a walk never moves faster than this (m/s)
+	MaxWalkSpeed = 10.0
+	// JumpMinDistance steps shorter than this (metres) are never treated as a jump
+	JumpMinDistance = 100.0
+	// JumpMinMedianSpeed walking pace (m/s), the median a jump is compared to never drops below it so a stop does not reject the rest of the walk
+	JumpMinMedianSpeed = 1.5
+	// SmoothWindow number of raw points averaged into one stored point
+	SmoothWindow = 3
+	// SmoothMaxGap points further apart in time are not averaged together
+	SmoothMaxGap = 30 * time.Second
+	// FilterHistory number of recent accepted points the filter looks at
+	FilterHistory = 10
+)
+
+// reject reasons
+const (
+	RejectReasonSpeed = "speed"
+	RejectReasonJump  = "jump"
+)
+
+// GeoFilter drops points implying impossible speeds or jumps and smooths the rest with a moving average
+type GeoFilter struct {
+	// raw coordinates of the recent accepted points, oldest first
+	recent []GeoPoint
+}
+
+// NewGeoFilter recent raw points of the track, oldest first
+func NewGeoFilter(recent []GeoPoint) *GeoFilter {
+	f := &GeoFilter{}
+	for _, p := range recent {
+		f.push(p)
+	}
+	return f
+}
+
+func (f *GeoFilter) push(p GeoPoint) {
+	f.recent = append(f.recent, p)
+	if len(f.recent) > FilterHistory {
+		f.recent = f.recent[len(f.recent)-FilterHistory:]
+	}
+}
+
+// medianSpeed of the recent segments, never below JumpMinMedianSpeed, false if there is not enough history
+func (f *GeoFilter) medianSpeed() (float64, bool) {
+	speeds := []float64{}
+	for i := 1; i < len(f.recent); i++ {
+		seconds := f.recent[i].Time.Sub(f.recent[i-1].Time).Seconds()
+		if seconds > 0 {
+			speeds = append(speeds, f.recent[i-1].DistanceTo(f.recent[i])/seconds)
+		}
+	}
+	if len(speeds) < 3 {
+		return 0, false
+	}
+	sort.Float64s(speeds)
+	return math.Max(speeds[len(speeds)/2], JumpMinMedianSpeed), true
+}
+
+// Apply returns the smoothed point to store, or the reason the raw point was rejected
+func (f *GeoFilter) Apply(p GeoPoint) (GeoPoint, string) {
+	if len(f.recent) > 0 {
+		last := f.recent[len(f.recent)-1]
+		distance := last.DistanceTo(p)
+		seconds := p.Time.Sub(last.Time).Seconds()
+		if seconds <= 0 {
+			if distance > JumpMinDistance {
+				return p, RejectReasonJump
+			}
+		} else {
+			speed := distance / seconds
+			if speed > MaxWalkSpeed {
+				return p, RejectReasonSpeed
+			}
+			if distance > JumpMinDistance {
+				median, ok := f.medianSpeed()
+				if ok && speed > median && IsTooFarFromMedian(speed, median) {
+					return p, RejectReasonJump
+				}
+			}
+		}
+	}
+	f.push(p)
+
+	// trailing moving average over the points close enough in time
+	smoothed := GeoPoint{Time: p.Time}
+	count := 0
+	for i := len(f.recent) - 1; i >= 0 && count < SmoothWindow; i-- {
+		if p.Time.Sub(f.recent[i].Time) > SmoothMaxGap {
+			break
+		}
+		smoothed.Longitude += f.recent[i].Longitude
+		smoothed.Latitude += f.recent[i].Latitude
+		count++
+	}
+	if count == 0 {
+		return p, ""
+	}
+	smoothed.Longitude /= float64(count)
+	smoothed.Latitude /= float64(count)
+	return smoothed, ""
+}
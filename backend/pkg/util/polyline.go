@@ -0,0 +1,95 @@
+package util
+
+import (
+	"math"
+	"strings"
+)
+
+// project to a local plane in metres around origin, good enough for the extent of a walk
+func project(origin GeoPoint, p GeoPoint) (float64, float64) {
+	x := toRadians(p.Longitude-origin.Longitude) * math.Cos(toRadians(origin.Latitude)) * EarthRadius
+	y := toRadians(p.Latitude-origin.Latitude) * EarthRadius
+	return x, y
+}
+
+// perpendicular distance in metres from p to the segment a-b
+func segmentDistance(origin, a, b, p GeoPoint) float64 {
+	ax, ay := project(origin, a)
+	bx, by := project(origin, b)
+	px, py := project(origin, p)
+	dx, dy := bx-ax, by-ay
+	if dx == 0 && dy == 0 {
+		return math.Hypot(px-ax, py-ay)
+	}
+	t := ((px-ax)*dx + (py-ay)*dy) / (dx*dx + dy*dy)
+	t = math.Max(0, math.Min(1, t))
+	return math.Hypot(px-(ax+t*dx), py-(ay+t*dy))
+}
+
+// SimplifyIndexes Douglas-Peucker, returns the indexes of the points kept, tolerance in metres
+func SimplifyIndexes(points []GeoPoint, tolerance float64) []int {
+	if len(points) <= 2 || tolerance <= 0 {
+		indexes := make([]int, len(points))
+		for i := range points {
+			indexes[i] = i
+		}
+		return indexes
+	}
+
+	keep := make([]bool, len(points))
+	keep[0], keep[len(points)-1] = true, true
+	origin := points[0]
+	// explicit stack, long walks would recurse too deep
+	stack := [][2]int{{0, len(points) - 1}}
+	for len(stack) > 0 {
+		span := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		first, last := span[0], span[1]
+
+		maxDistance, index := 0.0, -1
+		for i := first + 1; i < last; i++ {
+			distance := segmentDistance(origin, points[first], points[last], points[i])
+			if distance > maxDistance {
+				maxDistance, index = distance, i
+			}
+		}
+		if index != -1 && maxDistance > tolerance {
+			keep[index] = true
+			stack = append(stack, [2]int{first, index}, [2]int{index, last})
+		}
+	}
+
+	indexes := []int{}
+	for i, ok := range keep {
+		if ok {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+// EncodePolyline Google encoded polyline with precision 5
+func EncodePolyline(points []GeoPoint) string {
+	var b strings.Builder
+	var prevLat, prevLon int64
+	for _, p := range points {
+		lat := int64(math.Round(p.Latitude * 1e5))
+		lon := int64(math.Round(p.Longitude * 1e5))
+		encodePolylineValue(&b, lat-prevLat)
+		encodePolylineValue(&b, lon-prevLon)
+		prevLat, prevLon = lat, lon
+	}
+	return b.String()
+}
+
+func encodePolylineValue(b *strings.Builder, value int64) {
+	v := value << 1
+	if value < 0 {
+		v = ^v
+	}
+	for v >= 0x20 {
+		b.WriteByte(byte((0x20 | (v & 0x1f)) + 63))
+		v >>= 5
+	}
+	b.WriteByte(byte(v + 63))
+}
@@ -0,0 +1,111 @@
+package util
+
+import "time"
+
+const (
+	// IdleSpeed below this (m/s) the dog is considered standing still
+	IdleSpeed = 0.3
+	// IdleGap a gap between two points longer than this is lost tracking, its distance still counts
+	IdleGap = 5 * time.Minute
+)
+
+// WalkStats
+type WalkStats struct {
+	PointCount     uint
+	Distance       float64 // metres
+	ActiveDuration float64 // seconds
+	IdleDuration   float64 // seconds
+	MaxSpeed       float64 // m/s
+	LastPointTime  *time.Time
+}
+
+// AvgSpeed average speed while moving, m/s
+func (s WalkStats) AvgSpeed() float64 {
+	if s.ActiveDuration <= 0 {
+		return 0
+	}
+	return s.Distance / s.ActiveDuration
+}
+
+// Pause time the walker paused the walk, End is zero while still paused
+type Pause struct {
+	Start time.Time
+	End   time.Time
+}
+
+// PauseList
+type PauseList []Pause
+
+// Overlaps whether a pause falls between from and to
+func (list PauseList) Overlaps(from time.Time, to time.Time) bool {
+	for _, pause := range list {
+		if pause.Start.Before(to) && (pause.End.IsZero() || pause.End.After(from)) {
+			return true
+		}
+	}
+	return false
+}
+
+// SegmentStats stats contributed by moving from prev to next
+// a segment spanning a pause adds no distance or time, wherever the phone went meanwhile
+func SegmentStats(prev GeoPoint, next GeoPoint, pauses PauseList) WalkStats {
+	stats := WalkStats{PointCount: 1, LastPointTime: GetTimePtr(next.Time)}
+	if pauses.Overlaps(prev.Time, next.Time) {
+		return stats
+	}
+	distance := prev.DistanceTo(next)
+	seconds := next.Time.Sub(prev.Time).Seconds()
+	if seconds <= 0 {
+		// same timestamp or out of order, keep the distance but no time
+		stats.Distance = distance
+		return stats
+	}
+	speed := distance / seconds
+	if next.Time.Sub(prev.Time) > IdleGap {
+		// tracking was lost for a while, the dog still covered the distance
+		stats.Distance = distance
+		if speed < IdleSpeed {
+			stats.IdleDuration = seconds
+		} else {
+			stats.ActiveDuration = seconds
+		}
+		return stats
+	}
+	if speed < IdleSpeed {
+		stats.IdleDuration = seconds
+		return stats
+	}
+	stats.Distance = distance
+	stats.ActiveDuration = seconds
+	stats.MaxSpeed = speed
+	return stats
+}
+
+// Add merge a following segment into the stats
+func (s *WalkStats) Add(seg WalkStats) {
+	s.PointCount += seg.PointCount
+	s.Distance += seg.Distance
+	s.ActiveDuration += seg.ActiveDuration
+	s.IdleDuration += seg.IdleDuration
+	if seg.MaxSpeed > s.MaxSpeed {
+		s.MaxSpeed = seg.MaxSpeed
+	}
+	// out of order points never move the last point time back
+	if seg.LastPointTime != nil && (s.LastPointTime == nil || seg.LastPointTime.After(*s.LastPointTime)) {
+		s.LastPointTime = seg.LastPointTime
+	}
+}
+
+// ComputeWalkStats points must be ordered by time, paused spans are skipped
+func ComputeWalkStats(points []GeoPoint, pauses PauseList) WalkStats {
+	stats := WalkStats{}
+	if len(points) == 0 {
+		return stats
+	}
+	stats.PointCount = 1
+	stats.LastPointTime = GetTimePtr(points[0].Time)
+	for i := 1; i < len(points); i++ {
+		stats.Add(SegmentStats(points[i-1], points[i], pauses))
+	}
+	return stats
+}